@@ -0,0 +1,162 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nathanlytang/rolodex/internal/logger"
+	"github.com/nathanlytang/rolodex/internal/ssh"
+	"golang.org/x/term"
+)
+
+// Most hosts rolodex check connects to at once
+const checkConcurrency = 8
+
+// Width of the rolodex check progress bar, in characters
+const checkProgressWidth = 30
+
+// Outcome of checking one host
+type checkResult struct {
+	host     Host
+	err      error
+	duration time.Duration
+}
+
+// Reports how a check went, e.g. "ok", "unreachable" or "auth failed"
+func (r checkResult) status() string {
+	var unknownErr *ssh.UnknownHostKeyError
+	switch {
+	case r.host.Disabled:
+		return "disabled"
+	case r.err == nil:
+		return "ok"
+	case errors.As(r.err, &unknownErr):
+		return "unknown host key"
+	}
+	switch ssh.ClassifyError(r.err) {
+	case ssh.ErrorDNS, ssh.ErrorUnreachable, ssh.ErrorTimeout:
+		return "unreachable"
+	case ssh.ErrorAuth:
+		return "auth failed"
+	case ssh.ErrorHostKeyChanged:
+		return "host key changed"
+	}
+	return "failed"
+}
+
+// Connects to and authenticates with h, then disconnects, sharing ssh.Connect with real sessions
+// Nothing is asked for: keyboard-interactive questions go unanswered, prompted passwords
+// aren't entered, and keys aren't added to the agent, so hosts needing them fail auth
+func checkHost(h *Host, configuration *Configuration) error {
+	authConfig, sessionConfig, err := connectionConfig(h, configuration, nil)
+	if err != nil {
+		return err
+	}
+	authConfig.Prompter = nil
+	authConfig.InteractiveAuth = false
+	authConfig.AddKeyToAgent = false
+	return ssh.Probe(h.Host, h.Port, h.User, authConfig, sessionConfig)
+}
+
+// Probes every host, checkConcurrency at a time, and prints a report
+// Progress is drawn on stderr when it's a terminal. Returns the number of hosts that failed
+func runCheck(configuration *Configuration, out io.Writer) int {
+	hosts := allTargets(configuration)
+	if len(hosts) == 0 {
+		fmt.Fprintln(out, "No hosts saved")
+		return 0
+	}
+	logger.Printf("Checking %d hosts", len(hosts))
+
+	showProgress := term.IsTerminal(int(os.Stderr.Fd()))
+	results := make([]checkResult, len(hosts))
+	var mu sync.Mutex
+	done := 0
+	if showProgress {
+		drawCheckProgress(0, len(hosts))
+	}
+
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, checkConcurrency)
+	for i := range hosts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			// Disabled hosts are listed but not connected to
+			if hosts[i].Disabled {
+				results[i] = checkResult{host: hosts[i]}
+			} else {
+				start := time.Now()
+				err := checkHost(&hosts[i], configuration)
+				results[i] = checkResult{host: hosts[i], err: err, duration: time.Since(start)}
+			}
+
+			mu.Lock()
+			done++
+			if showProgress {
+				drawCheckProgress(done, len(hosts))
+			}
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	if showProgress {
+		fmt.Fprint(os.Stderr, "\r\033[K")
+	}
+
+	return printCheckReport(out, results)
+}
+
+// Redraws the progress bar in place on stderr
+func drawCheckProgress(done, total int) {
+	filled := done * checkProgressWidth / total
+	bar := strings.Repeat("█", filled) + strings.Repeat("░", checkProgressWidth-filled)
+	fmt.Fprintf(os.Stderr, "\rChecking hosts %s %d/%d", bar, done, total)
+}
+
+// Prints one line per host in config order, then a summary
+// Returns the number of hosts that failed
+func printCheckReport(out io.Writer, results []checkResult) int {
+	nameWidth, statusWidth := len("Host"), len("Status")
+	for _, r := range results {
+		nameWidth = max(nameWidth, len(r.host.Name))
+		statusWidth = max(statusWidth, len(r.status()))
+	}
+
+	counts := make(map[string]int)
+	var order []string
+	failed := 0
+	fmt.Fprintf(out, "%-*s  %-*s  %s\n", nameWidth, "Host", statusWidth, "Status", "Details")
+	for _, r := range results {
+		status := r.status()
+		if counts[status] == 0 {
+			order = append(order, status)
+		}
+		counts[status]++
+
+		detail := fmt.Sprintf("%dms", r.duration.Milliseconds())
+		if r.host.Disabled {
+			detail = "not checked"
+		} else if r.err != nil {
+			failed++
+			// Multi-line errors, like a changed host key, are cut to their first line
+			detail, _, _ = strings.Cut(r.err.Error(), "\n")
+		}
+		fmt.Fprintf(out, "%-*s  %-*s  %s\n", nameWidth, r.host.Name, statusWidth, status, detail)
+	}
+
+	summary := make([]string, len(order))
+	for i, status := range order {
+		summary[i] = fmt.Sprintf("%d %s", counts[status], status)
+	}
+	fmt.Fprintf(out, "\n%d hosts checked: %s\n", len(results), strings.Join(summary, ", "))
+	return failed
+}
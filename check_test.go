@@ -0,0 +1,64 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nathanlytang/rolodex/internal/ssh"
+)
+
+func TestCheckResultStatus(t *testing.T) {
+	tests := []struct {
+		name   string
+		result checkResult
+		want   string
+	}{
+		{"ok", checkResult{}, "ok"},
+		{"disabled", checkResult{host: Host{Disabled: true}}, "disabled"},
+		{"dns", checkResult{err: &ssh.ConnectError{Kind: ssh.ErrorDNS, Address: "web:22", Err: errors.New("no such host")}}, "unreachable"},
+		{"refused", checkResult{err: &ssh.ConnectError{Kind: ssh.ErrorUnreachable, Address: "web:22", Err: errors.New("refused")}}, "unreachable"},
+		{"timeout", checkResult{err: &ssh.ConnectError{Kind: ssh.ErrorTimeout, Address: "web:22", Err: errors.New("i/o timeout")}}, "unreachable"},
+		{"auth", checkResult{err: errors.New("ssh: handshake failed: ssh: unable to authenticate")}, "auth failed"},
+		{"host key changed", checkResult{err: fmt.Errorf("connect: %w", &ssh.HostKeyChangedError{Address: "web:22"})}, "host key changed"},
+		{"unknown host key", checkResult{err: &ssh.UnknownHostKeyError{Address: "web:22"}}, "unknown host key"},
+		{"other", checkResult{err: errors.New("session closed")}, "failed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.result.status(); got != tt.want {
+				t.Errorf("status() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrintCheckReport(t *testing.T) {
+	results := []checkResult{
+		{host: Host{Name: "web"}, duration: 120 * time.Millisecond},
+		{host: Host{Name: "database"}, err: errors.New("ssh: unable to authenticate\nattempted methods [none]")},
+		{host: Host{Name: "old", Disabled: true}},
+		{host: Host{Name: "cache"}, duration: 80 * time.Millisecond},
+	}
+	var out strings.Builder
+	failed := printCheckReport(&out, results)
+	if failed != 1 {
+		t.Errorf("printCheckReport() = %d failed, want 1", failed)
+	}
+
+	want := strings.Join([]string{
+		"Host      Status       Details",
+		"web       ok           120ms",
+		"database  auth failed  ssh: unable to authenticate",
+		"old       disabled     not checked",
+		"cache     ok           80ms",
+		"",
+		"4 hosts checked: 2 ok, 1 auth failed, 1 disabled",
+		"",
+	}, "\n")
+	if got := out.String(); got != want {
+		t.Errorf("report =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/nathanlytang/rolodex/internal/logger"
+	"github.com/nathanlytang/rolodex/internal/ssh"
+	"golang.org/x/term"
+)
+
+const usage = `Usage:
+  rolodex                Open the host list
+  rolodex connect NAME   Connect to a saved host without opening the host list
+  rolodex list           Print saved host names, one per line
+  rolodex ssh-command NAME
+                         Print the equivalent ssh command for a saved host without connecting
+  rolodex check          Try logging in to every saved host and report which fail`
+
+// Runs a subcommand without the TUI and returns the process exit code
+func runCommand(args []string, configuration *Configuration, configPath string) int {
+	switch {
+	case args[0] == "list" && len(args) == 1:
+		for _, name := range allHostNames(configuration) {
+			fmt.Println(name)
+		}
+		return 0
+
+	case args[0] == "connect" && len(args) == 2:
+		if err := connectByName(args[1], configuration, configPath); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			if advice := ssh.ClassifyError(err).Advice(); advice != "" {
+				fmt.Fprintf(os.Stderr, "%s.\n", advice)
+			}
+			return 1
+		}
+		return 0
+
+	case args[0] == "ssh-command" && len(args) == 2:
+		if err := printSSHCommand(args[1], configuration); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		return 0
+
+	case args[0] == "check" && len(args) == 1:
+		if runCheck(configuration, os.Stdout) > 0 {
+			return 1
+		}
+		return 0
+
+	case args[0] == "help" || args[0] == "-h" || args[0] == "--help":
+		fmt.Println(usage)
+		return 0
+	}
+
+	fmt.Fprintln(os.Stderr, usage)
+	return 2
+}
+
+// Returns all hosts with templates expanded, those in folders first like the host list
+func allTargets(configuration *Configuration) []Host {
+	var hosts []Host
+	for _, f := range configuration.Folders {
+		hosts = append(hosts, expandHosts(f.Hosts)...)
+	}
+	return append(hosts, expandHosts(configuration.Hosts)...)
+}
+
+// Returns the names of all hosts, including each host a template expands into
+func allHostNames(configuration *Configuration) []string {
+	var names []string
+	for _, h := range allTargets(configuration) {
+		names = append(names, h.Name)
+	}
+	return names
+}
+
+// Returns the named host, which may come from a template
+func findTarget(name string, configuration *Configuration) (*Host, error) {
+	targets := allTargets(configuration)
+	i := slices.IndexFunc(targets, func(h Host) bool { return h.Name == name })
+	if i < 0 {
+		return nil, fmt.Errorf("no host named %q", name)
+	}
+	return &targets[i], nil
+}
+
+// Prints the ssh command equivalent to connecting to the named host, for scripts
+// Authentication ssh can't use, like the keyring, is noted on stderr
+func printSSHCommand(name string, configuration *Configuration) error {
+	h, err := findTarget(name, configuration)
+	if err != nil {
+		return err
+	}
+	command, err := sshCommand(*h, configuration)
+	if err != nil {
+		return err
+	}
+	if managed := managedAuth(*h); len(managed) > 0 {
+		fmt.Fprintf(os.Stderr, "Note: %s uses rolodex-managed authentication (%s), which ssh can't use\n", h.Name, strings.Join(managed, ", "))
+	}
+	fmt.Println(command)
+	return nil
+}
+
+// Connects to the named host in the current terminal until the session ends
+func connectByName(name string, configuration *Configuration, configPath string) error {
+	h, err := findTarget(name, configuration)
+	if err != nil {
+		return err
+	}
+	logger.Printf("Connecting to %s from the command line", h.Name)
+	return connectInTerminal(h, configuration, configPath)
+}
+
+// Connects to h in the current terminal until the session ends, asking for anything
+// the connection needs (password, unknown host key) on the command line
+func connectInTerminal(h *Host, configuration *Configuration, configPath string) error {
+	if h.Disabled {
+		return disabledHostError(h)
+	}
+	if h.IdentityFile != "" {
+		if err := ssh.CheckKeyPermissions(h.IdentityFile); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+	}
+
+	var password []byte
+	if h.PromptPassword {
+		fmt.Fprintf(os.Stderr, "Password for %s@%s: ", h.User, h.Host)
+		var err error
+		password, err = term.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return fmt.Errorf("failed to read password: %w", err)
+		}
+		defer clear(password)
+	}
+
+	authConfig, sessionConfig, err := connectionConfig(h, configuration, password)
+	if err != nil {
+		return err
+	}
+	start := time.Now()
+	err = ssh.StartSession(h.Host, h.Port, h.User, authConfig, sessionConfig, 0, 0)
+
+	// Ask to trust an unknown host key, then try once more
+	var unknownErr *ssh.UnknownHostKeyError
+	if errors.As(err, &unknownErr) {
+		if !confirmHostKey(unknownErr) {
+			return errors.New("host key rejected")
+		}
+		if err := ssh.AddKnownHost(unknownErr.KnownHostsFile, unknownErr.Address, unknownErr.Key); err != nil {
+			return fmt.Errorf("failed to trust host key: %w", err)
+		}
+		start = time.Now()
+		err = ssh.StartSession(h.Host, h.Port, h.User, authConfig, sessionConfig, 0, 0)
+	}
+
+	var disconnectErr *ssh.DisconnectError
+	if err == nil || errors.As(err, &disconnectErr) {
+		if touchErr := recordConnection(configPath, h.configName(), time.Now(), time.Since(start)); touchErr != nil {
+			logger.Errorf("Failed to record connection for %s: %v", h.Name, touchErr)
+		}
+	}
+	return err
+}
+
+// Shows an unknown host key and asks whether to trust it, like ssh does
+func confirmHostKey(e *ssh.UnknownHostKeyError) bool {
+	fmt.Fprintf(os.Stderr, "The authenticity of host %s can't be established.\n", e.Address)
+	fmt.Fprintf(os.Stderr, "%s key fingerprint is %s.\n", e.Key.Type(), e.Fingerprint())
+	fmt.Fprint(os.Stderr, "Trust this host key and connect? [y/N] ")
+
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/nathanlytang/rolodex/internal/ssh"
+)
+
+// Builds the equivalent OpenSSH command line for a host
+// Jump hosts, including default_jump_host, become -J. Flags that match ssh defaults are omitted
+// Returns an error if h's jump host can't be resolved
+func sshCommand(h Host, configuration *Configuration) (string, error) {
+	jumps, err := resolveJumpHosts(&h, configuration)
+	if err != nil {
+		return "", err
+	}
+	args := []string{"ssh"}
+
+	if h.Port != 0 && h.Port != 22 {
+		args = append(args, "-p", strconv.Itoa(h.Port))
+	}
+	if h.IdentityFile != "" {
+		args = append(args, "-i", shellQuote(h.IdentityFile))
+	}
+	if h.CertificateFile != "" {
+		args = append(args, "-o", shellQuote("CertificateFile="+h.CertificateFile))
+	}
+	if h.ForwardAgent {
+		args = append(args, "-A")
+	}
+	if h.ForwardX11 {
+		args = append(args, "-X")
+	}
+	if len(jumps) > 0 {
+		hops := make([]string, len(jumps))
+		for i, jump := range jumps {
+			hops[i] = jumpSpec(jump)
+		}
+		args = append(args, "-J", shellQuote(strings.Join(hops, ",")))
+	} else if command := proxyCommand(h.proxyURL(configuration.Proxy)); command != "" {
+		// ssh can't combine the two, so a proxy in front of jump hosts is left out
+		args = append(args, "-o", shellQuote("ProxyCommand="+command))
+	}
+	for _, f := range h.LocalForwards {
+		args = append(args, "-L", shellQuote(f))
+	}
+	for _, f := range h.RemoteForwards {
+		args = append(args, "-R", shellQuote(f))
+	}
+	for _, k := range slices.Sorted(maps.Keys(h.Options)) {
+		args = append(args, "-o", shellQuote(k+"="+h.Options[k]))
+	}
+
+	target := h.Host
+	if h.User != "" {
+		target = h.User + "@" + h.Host
+	}
+	args = append(args, shellQuote(target))
+
+	return strings.Join(args, " "), nil
+}
+
+// Formats a jump host as ssh -J expects, [user@]host[:port]
+func jumpSpec(jump ssh.JumpHost) string {
+	spec := jump.Host
+	if jump.Port != 22 || strings.Contains(jump.Host, ":") {
+		spec = ssh.Address(jump.Host, jump.Port)
+	}
+	if jump.User != "" {
+		spec = jump.User + "@" + spec
+	}
+	return spec
+}
+
+// Quotes an argument for POSIX shells if it contains special characters
+// A leading ~ is left unquoted so the shell still expands it
+func shellQuote(s string) string {
+	if s == "" {
+		return "''"
+	}
+	if !strings.ContainsAny(s, " \t\n'\"\\$`!*?;&|<>()[]{}#") {
+		return s
+	}
+	if strings.HasPrefix(s, "~/") {
+		return "~/" + shellQuote(s[2:])
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// Returns an OpenSSH ProxyCommand reaching the host through proxy with OpenBSD nc
+// Empty if proxy is unset, "none" or invalid. Proxy credentials aren't passed on
+func proxyCommand(proxy string) string {
+	if proxy == "" || proxy == "none" {
+		return ""
+	}
+	u, err := ssh.ParseProxy(proxy)
+	if err != nil {
+		return ""
+	}
+	protocol := "5"
+	if u.Scheme == "http" {
+		protocol = "connect"
+	}
+	return "nc -X " + protocol + " -x " + u.Host + " %h %p"
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSSHCommand(t *testing.T) {
+	tests := []struct {
+		name    string
+		host    Host
+		cfg     Configuration
+		want    string
+		wantErr string
+	}{
+		{
+			name: "defaults omitted",
+			host: Host{Name: "web", Host: "web.example.com", Port: 22},
+			want: "ssh web.example.com",
+		},
+		{
+			name: "user port and key",
+			host: Host{Name: "web", Host: "web", User: "deploy", Port: 2222, IdentityFile: "~/.ssh/my key"},
+			want: `ssh -p 2222 -i ~/'.ssh/my key' deploy@web`,
+		},
+		{
+			name: "forwarding and options sorted",
+			host: Host{
+				Name: "web", Host: "web", Port: 22, ForwardAgent: true, ForwardX11: true,
+				LocalForwards:  []string{"8080:localhost:80"},
+				RemoteForwards: []string{"9000:localhost:9000"},
+				Options:        map[string]string{"ServerAliveInterval": "30", "Compression": "yes"},
+			},
+			want: "ssh -A -X -L 8080:localhost:80 -R 9000:localhost:9000 -o Compression=yes -o ServerAliveInterval=30 web",
+		},
+		{
+			name: "jump hosts outermost first",
+			host: Host{Name: "db", Host: "db", Port: 22, JumpHostRef: "inner"},
+			cfg: Configuration{Hosts: []Host{
+				{Name: "inner", Host: "10.0.0.5", Port: 2222, JumpHostRef: "outer"},
+				{Name: "outer", Host: "bastion.example.com", User: "alice", Port: 22},
+			}},
+			want: "ssh -J alice@bastion.example.com,10.0.0.5:2222 db",
+		},
+		{
+			name: "IPv6 jump host keeps its port",
+			host: Host{Name: "db", Host: "db", Port: 22, JumpHostRef: "v6"},
+			cfg:  Configuration{Hosts: []Host{{Name: "v6", Host: "fe80::1", Port: 22}}},
+			want: "ssh -J '[fe80::1]:22' db",
+		},
+		{
+			name: "default jump host",
+			host: Host{Name: "db", Host: "db", Port: 22},
+			cfg: Configuration{
+				Hosts:    []Host{{Name: "bastion", Host: "bastion", Port: 22}},
+				Settings: Settings{DefaultJumpHost: "bastion"},
+			},
+			want: "ssh -J bastion db",
+		},
+		{
+			name: "default proxy",
+			host: Host{Name: "db", Host: "db", Port: 22},
+			cfg:  Configuration{Settings: Settings{Proxy: "socks5://proxy"}},
+			want: "ssh -o 'ProxyCommand=nc -X 5 -x proxy:1080 %h %p' db",
+		},
+		{
+			name: "host opts out of the proxy",
+			host: Host{Name: "db", Host: "db", Port: 22, Proxy: "none"},
+			cfg:  Configuration{Settings: Settings{Proxy: "socks5://proxy"}},
+			want: "ssh db",
+		},
+		{
+			name: "HTTP proxy",
+			host: Host{Name: "db", Host: "db", Port: 22, Proxy: "http://proxy:3128"},
+			want: "ssh -o 'ProxyCommand=nc -X connect -x proxy:3128 %h %p' db",
+		},
+		{
+			name: "jump host wins over proxy",
+			host: Host{Name: "db", Host: "db", Port: 22, JumpHostRef: "bastion", Proxy: "socks5://proxy"},
+			cfg:  Configuration{Hosts: []Host{{Name: "bastion", Host: "bastion", Port: 22}}},
+			want: "ssh -J bastion db",
+		},
+		{
+			name:    "unresolvable jump host",
+			host:    Host{Name: "db", Host: "db", Port: 22, JumpHostRef: "gone"},
+			wantErr: `jump host "gone" of db not found`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := sshCommand(tt.host, &tt.cfg)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("sshCommand() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("sshCommand(): %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("sshCommand() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"", "''"},
+		{"web.example.com", "web.example.com"},
+		{"my key", "'my key'"},
+		{"it's", `'it'\''s'`},
+		{"$HOME", "'$HOME'"},
+		{"~/.ssh/id_rsa", "~/.ssh/id_rsa"},
+		{"~/my keys/id", "~/'my keys/id'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			if got := shellQuote(tt.in); got != tt.want {
+				t.Errorf("shellQuote(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
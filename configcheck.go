@@ -0,0 +1,153 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/nathanlytang/rolodex/internal/ssh"
+)
+
+// A problem with one saved host found by validateConfiguration
+type hostConfigError struct {
+	folder string // Empty for top-level hosts
+	index  int    // Position within its folder, for hosts without a name
+	name   string
+	err    error
+}
+
+func (e *hostConfigError) Error() string {
+	host := fmt.Sprintf("host %q", e.name)
+	if e.name == "" {
+		host = fmt.Sprintf("host #%d", e.index+1)
+	}
+	if e.folder != "" {
+		host += fmt.Sprintf(" in folder %q", e.folder)
+	}
+	return host + ": " + e.err.Error()
+}
+
+func (e *hostConfigError) Unwrap() error {
+	return e.err
+}
+
+// Checks every saved host for mistakes that would only show up when connecting
+// Returns all problems found, nil if there are none. Problems with a host are *hostConfigError
+func validateConfiguration(cfg *Configuration) []error {
+	var errs []error
+
+	names := allHostNames(cfg)
+	if cfg.DefaultJumpHost != "" && !slices.Contains(names, cfg.DefaultJumpHost) {
+		errs = append(errs, fmt.Errorf("default_jump_host %q isn't a saved host", cfg.DefaultJumpHost))
+		// Report the bad default once rather than against every host using it
+		checked := *cfg
+		checked.DefaultJumpHost = ""
+		cfg = &checked
+	}
+
+	if cfg.Proxy != "" {
+		if _, err := ssh.ParseProxy(cfg.Proxy); err != nil {
+			errs = append(errs, fmt.Errorf("proxy: %w", err))
+		}
+	}
+
+	// Defaults only fill in the add form, but catching them here beats finding out there
+	if cfg.DefaultPort < 0 || cfg.DefaultPort > 65535 {
+		errs = append(errs, fmt.Errorf("default_port %d isn't between 1 and 65535", cfg.DefaultPort))
+	}
+	if cfg.DefaultIdentityFile != "" {
+		if err := checkReadable(cfg.DefaultIdentityFile); err != nil {
+			errs = append(errs, fmt.Errorf("default_identity_file %s can't be read: %w", cfg.DefaultIdentityFile, err))
+		}
+	}
+
+	check := func(folder string, hosts []Host) {
+		for i, h := range hosts {
+			for _, err := range validateHost(h, cfg) {
+				errs = append(errs, &hostConfigError{folder: folder, index: i, name: h.Name, err: err})
+			}
+		}
+	}
+	for _, f := range cfg.Folders {
+		check(f.Name, f.Hosts)
+	}
+	check("", cfg.Hosts)
+	return errs
+}
+
+// Returns the problems with one saved host, which may be a template
+func validateHost(h Host, cfg *Configuration) []error {
+	var errs []error
+	if h.Name == "" {
+		errs = append(errs, errors.New("name is empty"))
+	}
+	if h.Host == "" {
+		errs = append(errs, errors.New("host is empty"))
+	}
+	if h.Port < 1 || h.Port > 65535 {
+		errs = append(errs, fmt.Errorf("port %d isn't between 1 and 65535", h.Port))
+	}
+
+	for _, file := range []struct{ field, path string }{
+		{"identity_file", h.IdentityFile},
+		{"certificate_file", h.CertificateFile},
+	} {
+		if file.path == "" {
+			continue
+		}
+		if err := checkReadable(file.path); err != nil {
+			errs = append(errs, fmt.Errorf("%s %s can't be read: %w", file.field, file.path, err))
+		}
+	}
+
+	for _, f := range h.LocalForwards {
+		if _, _, err := ssh.ParseForward(f); err != nil {
+			errs = append(errs, fmt.Errorf("local forward: %w", err))
+		}
+	}
+	for _, f := range h.RemoteForwards {
+		if _, _, err := ssh.ParseForward(f); err != nil {
+			errs = append(errs, fmt.Errorf("remote forward: %w", err))
+		}
+	}
+
+	if h.Proxy != "" && h.Proxy != "none" {
+		if _, err := ssh.ParseProxy(h.Proxy); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	// Templates share their jump host, so checking the first host they expand into covers them all
+	if h.Name != "" {
+		if _, err := resolveJumpHosts(&expandHost(h)[0], cfg); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// Checks that a key or certificate path from config.json points at a file
+func checkReadable(file string) error {
+	path, err := ssh.ResolveIdentityFile(file)
+	if err == nil {
+		_, err = os.Stat(path)
+	}
+	var pathErr *os.PathError
+	if errors.As(err, &pathErr) {
+		err = pathErr.Err
+	}
+	return err
+}
+
+// Returns the names of saved hosts with problems, as written in config.json
+func invalidHostNames(errs []error) map[string]bool {
+	names := make(map[string]bool)
+	for _, err := range errs {
+		var hostErr *hostConfigError
+		if errors.As(err, &hostErr) {
+			names[hostErr.name] = true
+		}
+	}
+	return names
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateConfiguration(t *testing.T) {
+	key := filepath.Join(t.TempDir(), "id_ed25519")
+	if err := os.WriteFile(key, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	missing := filepath.Join(t.TempDir(), "missing")
+
+	tests := []struct {
+		name string
+		cfg  Configuration
+		want []string // Each problem expected, in order
+	}{
+		{
+			name: "valid",
+			cfg: Configuration{
+				Hosts: []Host{
+					{Name: "web", Host: "web.example.com", Port: 22, IdentityFile: key, JumpHostRef: "bastion"},
+					{Name: "bastion", Host: "bastion.example.com", Port: 22, Proxy: "socks5://proxy"},
+				},
+			},
+		},
+		{
+			name: "host fields",
+			cfg: Configuration{
+				Hosts: []Host{{Host: "", Port: 0, IdentityFile: missing, LocalForwards: []string{"nope"}, Proxy: "ftp://proxy"}},
+			},
+			want: []string{
+				"host #1: name is empty",
+				"host #1: host is empty",
+				"host #1: port 0 isn't between 1 and 65535",
+				"host #1: identity_file " + missing + " can't be read: no such file or directory",
+				"host #1: local forward: ",
+				"host #1: invalid proxy URL: expected socks5:// or http://, not ftp://",
+			},
+		},
+		{
+			name: "host in a folder",
+			cfg: Configuration{
+				Folders: []Folder{{Name: "prod", Hosts: []Host{{Name: "db", Host: "db", Port: 70000}}}},
+			},
+			want: []string{`host "db" in folder "prod": port 70000 isn't between 1 and 65535`},
+		},
+		{
+			name: "jump host loop",
+			cfg: Configuration{
+				Hosts: []Host{
+					{Name: "a", Host: "a", Port: 22, JumpHostRef: "b"},
+					{Name: "b", Host: "b", Port: 22, JumpHostRef: "a"},
+				},
+			},
+			want: []string{
+				`host "a": jump host references form a cycle: a -> b -> a`,
+				`host "b": jump host references form a cycle: b -> a -> b`,
+			},
+		},
+		{
+			name: "missing default jump host reported once",
+			cfg: Configuration{
+				Hosts:    []Host{{Name: "web", Host: "web", Port: 22}, {Name: "db", Host: "db", Port: 22}},
+				Settings: Settings{DefaultJumpHost: "gone"},
+			},
+			want: []string{`default_jump_host "gone" isn't a saved host`},
+		},
+		{
+			name: "settings",
+			cfg: Configuration{
+				Settings: Settings{Proxy: "socks5://", DefaultPort: 65536, DefaultIdentityFile: missing},
+			},
+			want: []string{
+				"proxy: invalid proxy URL: no proxy host",
+				"default_port 65536 isn't between 1 and 65535",
+				"default_identity_file " + missing + " can't be read",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := validateConfiguration(&tt.cfg)
+			if len(errs) != len(tt.want) {
+				t.Fatalf("validateConfiguration() = %q, want %d problems", errs, len(tt.want))
+			}
+			for i, err := range errs {
+				if !strings.HasPrefix(err.Error(), tt.want[i]) {
+					t.Errorf("problem %d = %q, want prefix %q", i, err, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestInvalidHostNames(t *testing.T) {
+	cfg := Configuration{
+		Hosts: []Host{
+			{Name: "good", Host: "good", Port: 22},
+			{Name: "bad", Host: "", Port: 22},
+		},
+		Folders:  []Folder{{Name: "prod", Hosts: []Host{{Name: "worse", Host: "worse", Port: -1}}}},
+		Settings: Settings{DefaultPort: -1},
+	}
+	errs := validateConfiguration(&cfg)
+
+	var hostErr *hostConfigError
+	if !errors.As(errs[len(errs)-1], &hostErr) {
+		t.Fatalf("host problem %q isn't a *hostConfigError", errs[len(errs)-1])
+	}
+	names := invalidHostNames(errs)
+	if len(names) != 2 || !names["bad"] || !names["worse"] {
+		t.Errorf("invalidHostNames() = %v, want bad and worse", names)
+	}
+}
@@ -0,0 +1,279 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/list"
+	lg "github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// Host fields matched by the list filter when filter_fields isn't configured
+var defaultFilterFields = []string{"name", "host", "user", "tags"}
+
+// A single host field that participates in filtering
+type filterSegment struct {
+	field string
+	value string
+	start int // Rune offset of value within the filter value
+}
+
+// Returns the configured host fields with their rune offsets in the filter value
+func (i Item) filterSegments() []filterSegment {
+	var segments []filterSegment
+	offset := 0
+	for _, field := range i.filterFields {
+		var value string
+		switch field {
+		case "name":
+			value = i.host.Name
+		case "host":
+			value = i.host.Host
+		case "user":
+			value = i.host.User
+		case "tags":
+			value = i.tagsText()
+		default:
+			continue
+		}
+		if value == "" {
+			continue
+		}
+		segments = append(segments, filterSegment{field: field, value: value, start: offset})
+		offset += len([]rune(value)) + 1 // +1 for the separating space
+	}
+	return segments
+}
+
+// Joins the configured host fields so the list filter can match any of them
+func (i Item) filterValue() string {
+	var values []string
+	for _, s := range i.filterSegments() {
+		values = append(values, s.value)
+	}
+	return strings.Join(values, " ")
+}
+
+// Returns the rune indices within a segment's value that the filter matched
+func (s filterSegment) matches(matchedRunes []int) []int {
+	end := s.start + len([]rune(s.value))
+	var local []int
+	for _, r := range matchedRunes {
+		if r >= s.start && r < end {
+			local = append(local, r-s.start)
+		}
+	}
+	return local
+}
+
+// List delegate that highlights filter matches in whichever host field they hit
+// The default delegate only highlights the title, which is wrong once the filter
+// value spans several fields
+type hostDelegate struct {
+	list.DefaultDelegate
+}
+
+func newHostDelegate() hostDelegate {
+	d := list.NewDefaultDelegate()
+	d.Styles.SelectedTitle = d.Styles.SelectedTitle.Foreground(theme.Highlight).BorderForeground(theme.Highlight)
+	d.Styles.SelectedDesc = d.Styles.SelectedDesc.BorderForeground(theme.Highlight)
+
+	// Without a configured theme, unselected items keep the list's colors
+	// that adapt to the terminal background
+	if theme.Name != "" {
+		d.Styles.NormalTitle = d.Styles.NormalTitle.Foreground(theme.Text)
+		d.Styles.NormalDesc = d.Styles.NormalDesc.Foreground(theme.Muted)
+		d.Styles.DimmedTitle = d.Styles.DimmedTitle.Foreground(theme.Muted)
+		d.Styles.DimmedDesc = d.Styles.DimmedDesc.Foreground(theme.Muted)
+	}
+	return hostDelegate{DefaultDelegate: d}
+}
+
+func (d hostDelegate) Render(w io.Writer, m list.Model, index int, item list.Item) {
+	// Color host names by the last reachability check
+	if it, ok := item.(Item); ok && it.health != healthUnknown {
+		color := theme.Success
+		if it.health == healthUnreachable {
+			color = theme.Error
+		}
+		d.Styles.NormalTitle = d.Styles.NormalTitle.Foreground(color)
+		d.Styles.SelectedTitle = d.Styles.SelectedTitle.Foreground(color)
+	}
+	if it, ok := item.(Item); ok && it.host.Disabled {
+		greyOut(&d.Styles)
+	}
+
+	isFiltered := m.FilterState() == list.Filtering || m.FilterState() == list.FilterApplied
+	if !isFiltered || m.Width() <= 0 {
+		d.DefaultDelegate.Render(w, m, index, item)
+		return
+	}
+
+	s := &d.Styles
+	isSelected := index == m.Index()
+	emptyFilter := m.FilterState() == list.Filtering && m.FilterValue() == ""
+
+	titleStyle, descStyle := s.NormalTitle, s.NormalDesc
+	if emptyFilter {
+		titleStyle, descStyle = s.DimmedTitle, s.DimmedDesc
+	} else if isSelected && m.FilterState() != list.Filtering {
+		titleStyle, descStyle = s.SelectedTitle, s.SelectedDesc
+	}
+
+	var matchedRunes []int
+	if index < len(m.VisibleItems()) {
+		matchedRunes = m.MatchesForItem(index)
+	}
+
+	// Highlight each field's matches; fields without a home in the
+	// title or description are appended to the description when they match
+	highlight := func(value string, matches []int, base lg.Style) string {
+		unmatched := base.Inline(true)
+		matched := unmatched.Inherit(s.FilterMatch)
+		return lg.StyleRunes(value, matches, matched, unmatched)
+	}
+
+	textwidth := m.Width() - s.NormalTitle.GetPaddingLeft() - s.NormalTitle.GetPaddingRight()
+
+	// Folder headers filter on their name but render it after the arrow
+	if f, ok := item.(FolderItem); ok {
+		prefix := strings.TrimSuffix(f.Title(), f.folder.Name)
+		title := titleStyle.Render(prefix + highlight(ansi.Truncate(f.folder.Name, textwidth, "…"), matchedRunes, titleStyle))
+		desc := descStyle.Render(f.Description())
+		if d.ShowDescription {
+			fmt.Fprintf(w, "%s\n%s", title, desc)
+			return
+		}
+		fmt.Fprintf(w, "%s", title)
+		return
+	}
+
+	it, ok := item.(Item)
+	if !ok {
+		d.DefaultDelegate.Render(w, m, index, item)
+		return
+	}
+
+	matches := make(map[string][]int)
+	var extra []string
+	for _, seg := range it.filterSegments() {
+		matches[seg.field] = seg.matches(matchedRunes)
+		switch seg.field {
+		case "name", "host", "tags":
+		default:
+			// Fields not shown in the list are only displayed when they match
+			if len(matches[seg.field]) > 0 {
+				extra = append(extra, highlight(seg.field+": ", nil, descStyle)+highlight(seg.value, matches[seg.field], descStyle))
+			}
+		}
+	}
+
+	sep := highlight(" · ", nil, descStyle)
+	title := highlight(it.markPrefix(), nil, titleStyle) + highlight(ansi.Truncate(it.host.Name, textwidth, "…"), matches["name"], titleStyle) + highlight(it.healthMarker()+it.disabledMarker(), nil, titleStyle)
+	desc := highlight(it.host.Host, matches["host"], descStyle)
+	if tags := it.tagsText(); tags != "" {
+		desc += sep + highlight(tags, matches["tags"], descStyle)
+	}
+	if last := it.lastConnected(); last != "" {
+		desc += highlight(last, nil, descStyle)
+	}
+	if len(extra) > 0 {
+		desc += sep + strings.Join(extra, sep)
+	}
+
+	title = titleStyle.Render(it.indent() + title)
+	desc = descStyle.Render(it.indent() + desc)
+
+	if d.ShowDescription {
+		fmt.Fprintf(w, "%s\n%s", title, desc)
+		return
+	}
+	fmt.Fprintf(w, "%s", title)
+}
+
+// Single-line list delegate showing "Name — user@host:port" for each host,
+// fitting about twice as many hosts on screen as the two-line default
+type compactDelegate struct {
+	hostDelegate
+}
+
+func newCompactDelegate() compactDelegate {
+	d := newHostDelegate()
+	d.ShowDescription = false
+	d.SetHeight(1)
+	d.SetSpacing(0)
+	return compactDelegate{hostDelegate: d}
+}
+
+func (d compactDelegate) Render(w io.Writer, m list.Model, index int, item list.Item) {
+	it, ok := item.(Item)
+	if !ok || m.Width() <= 0 {
+		d.hostDelegate.Render(w, m, index, item)
+		return
+	}
+
+	s := &d.Styles
+	// Color host names by the last reachability check
+	if it.health != healthUnknown {
+		color := theme.Success
+		if it.health == healthUnreachable {
+			color = theme.Error
+		}
+		s.NormalTitle = s.NormalTitle.Foreground(color)
+		s.SelectedTitle = s.SelectedTitle.Foreground(color)
+	}
+	if it.host.Disabled {
+		greyOut(s)
+	}
+
+	isFiltered := m.FilterState() == list.Filtering || m.FilterState() == list.FilterApplied
+	titleStyle, detailStyle := s.NormalTitle, s.NormalDesc
+	if m.FilterState() == list.Filtering && m.FilterValue() == "" {
+		titleStyle, detailStyle = s.DimmedTitle, s.DimmedDesc
+	} else if index == m.Index() && m.FilterState() != list.Filtering {
+		titleStyle, detailStyle = s.SelectedTitle, s.SelectedDesc.UnsetBorderStyle().UnsetPadding()
+	}
+
+	// Highlight filter matches in the name and host
+	matches := make(map[string][]int)
+	if isFiltered && index < len(m.VisibleItems()) {
+		matchedRunes := m.MatchesForItem(index)
+		for _, seg := range it.filterSegments() {
+			matches[seg.field] = seg.matches(matchedRunes)
+		}
+	}
+	highlight := func(value string, matches []int, base lg.Style) string {
+		unmatched := base.Inline(true)
+		matched := unmatched.Inherit(s.FilterMatch)
+		return lg.StyleRunes(value, matches, matched, unmatched)
+	}
+
+	textwidth := m.Width() - s.NormalTitle.GetPaddingLeft() - s.NormalTitle.GetPaddingRight()
+	name := it.indent() + it.markPrefix() + it.host.Name + it.healthMarker() + it.disabledMarker()
+	address := it.host.User + "@" + it.host.Host + fmt.Sprintf(":%d", it.host.Port)
+	if lg.Width(name) >= textwidth {
+		fmt.Fprint(w, titleStyle.Render(ansi.Truncate(name, textwidth, "…")))
+		return
+	}
+
+	prefix := it.indent() + it.markPrefix()
+	line := highlight(prefix, nil, titleStyle) + highlight(it.host.Name, matches["name"], titleStyle) + highlight(it.healthMarker()+it.disabledMarker(), nil, titleStyle)
+	rest := textwidth - lg.Width(name)
+	detail := " — " + address
+	if lg.Width(detail) > rest {
+		detail = ansi.Truncate(detail, rest, "…")
+		line += highlight(detail, nil, detailStyle.Inline(true))
+	} else {
+		// The host starts after the separator and user@
+		hostMatches := matches["host"]
+		offset := len([]rune(" — " + it.host.User + "@"))
+		shifted := make([]int, len(hostMatches))
+		for i, r := range hostMatches {
+			shifted[i] = r + offset
+		}
+		line += highlight(detail, shifted, detailStyle)
+	}
+	fmt.Fprint(w, titleStyle.Render(line))
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Returns a marker shown after the names of disabled hosts
+func (i Item) disabledMarker() string {
+	if i.host.Disabled {
+		return " (disabled)"
+	}
+	return ""
+}
+
+// Greys out a disabled host's title and description, selected or not
+func greyOut(s *list.DefaultItemStyles) {
+	s.NormalTitle = s.NormalTitle.Foreground(theme.Muted)
+	s.NormalDesc = s.NormalDesc.Foreground(theme.Muted)
+	s.SelectedTitle = s.SelectedTitle.Foreground(theme.Muted)
+	s.SelectedDesc = s.SelectedDesc.Foreground(theme.Muted)
+}
+
+// Returned when connecting to a host marked disabled in config.json
+func disabledHostError(h *Host) error {
+	return fmt.Errorf("%s is disabled, press %s in the host list to enable it", h.Name, toggleDisabled.Help().Key)
+}
+
+// Disables the selected host, or enables it if it's disabled, saving the change in place
+// A template's hosts are all toggled together
+func (m Model) toggleHostDisabled() (tea.Model, tea.Cmd) {
+	it, ok := m.list.SelectedItem().(Item)
+	if !ok {
+		return m, nil
+	}
+	h := m.hostAt(it.loc)
+	disabled := !h.Disabled
+
+	change := "disabling " + h.Name
+	if !disabled {
+		change = "enabling " + h.Name
+	}
+	snapshot := takeSnapshot(m.configPath, change)
+	if err := setHostDisabledInConfig(m.configPath, it.loc, disabled); err != nil {
+		m.err = fmt.Errorf("failed to update host: %w", err)
+		m.showErr = true
+		return m, nil
+	}
+	m.undo = snapshot
+
+	config, err := readConfig(m.configPath)
+	if err != nil {
+		m.err = fmt.Errorf("failed to reload config: %w", err)
+		m.showErr = true
+		return m, nil
+	}
+	m.hosts = config.Hosts
+	m.folders = config.Folders
+	index := m.list.Index()
+	cmd := m.list.SetItems(m.buildItems())
+	m.list.Select(index)
+
+	status := "Disabled " + h.Name
+	if !disabled {
+		status = "Enabled " + h.Name
+	}
+	return m, tea.Batch(cmd, m.list.NewStatusMessage(status))
+}
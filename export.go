@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/nathanlytang/rolodex/internal/ssh"
+)
+
+// First line of exported files, used to avoid overwriting files rolodex didn't write
+const exportHeader = "# Generated by rolodex. Changes are lost on the next export."
+
+// Where exported ssh_config files are written unless another path is chosen
+const defaultExportPath = "~/.ssh/rolodex_config"
+
+var errExportOverwrite = errors.New("file exists and wasn't written by rolodex")
+
+// Writes hosts as ssh_config Host blocks so plain ssh and scp can use them
+// Authentication rolodex handles itself (keyring, stored passwords) has no
+// ssh_config equivalent and is noted in a comment instead
+func ExportSSHConfig(hosts []Host, w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, exportHeader)
+
+	for _, h := range hosts {
+		fmt.Fprintln(bw)
+		if managed := managedAuth(h); len(managed) > 0 {
+			fmt.Fprintf(bw, "# %s uses rolodex-managed authentication (%s), which ssh can't use\n", h.Name, strings.Join(managed, ", "))
+		}
+
+		fmt.Fprintf(bw, "Host %s\n", sshConfigAlias(h.Name))
+		fmt.Fprintf(bw, "    HostName %s\n", h.Host)
+		if h.Port != 0 {
+			fmt.Fprintf(bw, "    Port %d\n", h.Port)
+		}
+		if h.User != "" {
+			fmt.Fprintf(bw, "    User %s\n", h.User)
+		}
+		if h.IdentityFile != "" {
+			fmt.Fprintf(bw, "    IdentityFile %s\n", sshConfigQuote(h.IdentityFile))
+		}
+		if h.CertificateFile != "" {
+			fmt.Fprintf(bw, "    CertificateFile %s\n", sshConfigQuote(h.CertificateFile))
+		}
+		if h.ForwardAgent {
+			fmt.Fprintln(bw, "    ForwardAgent yes")
+		}
+		if h.ForwardX11 {
+			fmt.Fprintln(bw, "    ForwardX11 yes")
+		}
+		if command := proxyCommand(h.Proxy); command != "" {
+			fmt.Fprintf(bw, "    ProxyCommand %s\n", command)
+		}
+		for _, f := range h.LocalForwards {
+			if listen, target, err := ssh.ParseForward(f); err == nil {
+				fmt.Fprintf(bw, "    LocalForward %s %s\n", listen, target)
+			}
+		}
+		for _, f := range h.RemoteForwards {
+			if listen, target, err := ssh.ParseForward(f); err == nil {
+				fmt.Fprintf(bw, "    RemoteForward %s %s\n", listen, target)
+			}
+		}
+		for _, k := range slices.Sorted(maps.Keys(h.Options)) {
+			fmt.Fprintf(bw, "    %s %s\n", k, h.Options[k])
+		}
+	}
+	return bw.Flush()
+}
+
+// Returns the auth methods of h that only rolodex can use
+func managedAuth(h Host) []string {
+	var managed []string
+	if h.KeyringService != "" || h.KeyringAccount != "" {
+		managed = append(managed, "keyring")
+	}
+	if h.Password != "" {
+		managed = append(managed, "stored password")
+	}
+	if h.PasswordCommand != "" {
+		managed = append(managed, "password command")
+	}
+	if h.IdentityPassphrase != "" {
+		managed = append(managed, "stored key passphrase")
+	}
+	if h.AgentKey != "" {
+		managed = append(managed, "agent key selection")
+	}
+	return managed
+}
+
+// Makes a host name usable as an ssh_config Host alias
+// Whitespace and pattern characters are replaced since ssh would treat them as separators or wildcards
+func sshConfigAlias(name string) string {
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(" \t*?!,\"", r) {
+			return '-'
+		}
+		return r
+	}, name)
+}
+
+// Quotes an ssh_config value containing spaces
+func sshConfigQuote(s string) string {
+	if strings.ContainsAny(s, " \t") {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+// Exports hosts to path, expanding a leading ~
+// Refuses to replace a file that rolodex didn't write, such as ~/.ssh/config itself
+func exportSSHConfigFile(path string, hosts []Host) (string, error) {
+	if strings.HasPrefix(path, "~") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to get home directory: %w", err)
+		}
+		path = filepath.Join(home, path[1:])
+	}
+
+	if existing, err := os.Open(path); err == nil {
+		first, _ := bufio.NewReader(existing).ReadString('\n')
+		existing.Close()
+		if strings.TrimSpace(first) != exportHeader {
+			return "", fmt.Errorf("%w: %s", errExportOverwrite, path)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return "", fmt.Errorf("failed to create directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return "", fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	if err := ExportSSHConfig(hosts, f); err != nil {
+		f.Close()
+		return "", fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := f.Close(); err != nil {
+		return "", fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return path, nil
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/bubbles/list"
+)
+
+// How the host list filter matches what's typed after /
+type filterMode string
+
+const (
+	filterFuzzy     filterMode = "fuzzy"     // Typed characters in order, gaps allowed, best matches first
+	filterSubstring filterMode = "substring" // Typed text as-is ignoring case, in list order
+)
+
+// Parses a filter mode from config, falling back to fuzzy
+func parseFilterMode(s string) filterMode {
+	if filterMode(s) == filterSubstring {
+		return filterSubstring
+	}
+	return filterFuzzy
+}
+
+// Returns the list filter implementing the mode
+func (f filterMode) filterFunc() list.FilterFunc {
+	if f == filterSubstring {
+		return substringFilter
+	}
+	// The list's default filter ranks fuzzy matches with sahilm/fuzzy
+	return list.DefaultFilter
+}
+
+// Keeps the targets containing term, ignoring case, in their original order
+// Matched indexes are rune offsets so the delegate can highlight them
+func substringFilter(term string, targets []string) []list.Rank {
+	term = strings.ToLower(term)
+	var ranks []list.Rank
+	for i, target := range targets {
+		lower := strings.ToLower(target)
+		at := strings.Index(lower, term)
+		if at < 0 {
+			continue
+		}
+		// Lowercasing can change byte lengths, so count runes in the lowered string
+		start := utf8.RuneCountInString(lower[:at])
+		matched := make([]int, utf8.RuneCountInString(term))
+		for j := range matched {
+			matched[j] = start + j
+		}
+		ranks = append(ranks, list.Rank{Index: i, MatchedIndexes: matched})
+	}
+	return ranks
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/charmbracelet/bubbles/list"
+)
+
+func TestSubstringFilter(t *testing.T) {
+	targets := []string{"web-prod", "db-prod", "Web Staging", "İstanbul web", "cache"}
+	tests := []struct {
+		term string
+		want []list.Rank
+	}{
+		{"cache", []list.Rank{{Index: 4, MatchedIndexes: []int{0, 1, 2, 3, 4}}}},
+		{"prod", []list.Rank{
+			{Index: 0, MatchedIndexes: []int{4, 5, 6, 7}},
+			{Index: 1, MatchedIndexes: []int{3, 4, 5, 6}},
+		}},
+		// Kept in list order and matched ignoring case. İ lowercases to a shorter i,
+		// which must not shift the highlighted runes after it
+		{"WEB", []list.Rank{
+			{Index: 0, MatchedIndexes: []int{0, 1, 2}},
+			{Index: 2, MatchedIndexes: []int{0, 1, 2}},
+			{Index: 3, MatchedIndexes: []int{9, 10, 11}},
+		}},
+		// Characters in order but apart only match fuzzily
+		{"wp", nil},
+		{"none", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.term, func(t *testing.T) {
+			if got := substringFilter(tt.term, targets); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("substringFilter(%q) = %v, want %v", tt.term, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseFilterMode(t *testing.T) {
+	for in, want := range map[string]filterMode{
+		"":          filterFuzzy,
+		"fuzzy":     filterFuzzy,
+		"substring": filterSubstring,
+		"regex":     filterFuzzy,
+	} {
+		if got := parseFilterMode(in); got != want {
+			t.Errorf("parseFilterMode(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/atotto/clipboard"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/nathanlytang/rolodex/internal/logger"
+	"github.com/nathanlytang/rolodex/internal/ssh"
+)
+
+// Result of fetching a host's key fingerprint, delivered back to Update
+type fingerprintMsg struct {
+	host *Host
+	info ssh.HostKeyInfo
+	err  error
+}
+
+// Returns a command that reads h's host key with a handshake that stops before authentication
+func fetchFingerprint(h *Host, configPath string) tea.Cmd {
+	return func() tea.Msg {
+		config, err := readConfig(configPath)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fingerprintMsg{host: h, err: err}
+		}
+		_, sessionConfig, err := connectionConfig(h, &config, nil)
+		if err != nil {
+			return fingerprintMsg{host: h, err: err}
+		}
+
+		address := ssh.Address(h.Host, h.Port)
+		info, err := ssh.FetchHostKey(address, config.KnownHostsFile, sessionConfig.ConnectTimeout, sessionConfig.HostKeyAlgorithms)
+		return fingerprintMsg{host: h, info: info, err: err}
+	}
+}
+
+// Copies a fetched fingerprint and shows it with whether known_hosts trusts it
+func (m Model) finishFingerprint(msg fingerprintMsg) (tea.Model, tea.Cmd) {
+	if msg.err != nil {
+		logger.Errorf("Failed to fetch host key for %s: %v", msg.host.Name, msg.err)
+		return m, m.list.NewStatusMessage("Couldn't fetch host key for " + msg.host.Name + ": " + msg.err.Error())
+	}
+
+	var unknownErr *ssh.UnknownHostKeyError
+	status := "known"
+	if errors.As(msg.info.KnownHosts, &unknownErr) {
+		status = "not in known_hosts"
+	} else if msg.info.KnownHosts != nil {
+		// A changed key is worth more than a status line
+		m.err = fmt.Errorf("%s presented %s %s, which doesn't match known_hosts: %w", msg.host.Name, msg.info.Type, msg.info.Fingerprint, msg.info.KnownHosts)
+		m.showErr = true
+		return m, nil
+	}
+
+	shown := fmt.Sprintf("%s %s (%s)", msg.info.Type, msg.info.Fingerprint, status)
+	if err := clipboard.WriteAll(msg.info.Fingerprint); err != nil {
+		logger.Errorf("Failed to copy to clipboard: %v", err)
+		return m, m.list.NewStatusMessage(shown + ", clipboard unavailable")
+	}
+	return m, m.list.NewStatusMessage("Copied " + shown)
+}
@@ -1,34 +1,63 @@
 package main
 
 import (
+	"cmp"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/bubbles/help"
 	"github.com/charmbracelet/bubbles/textinput"
 	lg "github.com/charmbracelet/lipgloss"
+	"github.com/nathanlytang/rolodex/internal/logger"
 )
 
 type formModel struct {
 	inputs       []textinput.Model
 	focusIndex   int
 	submitting   bool
-	scrollOffset int // Track scroll position for large forms
+	scrollOffset int           // Track scroll position for large forms
+	editLoc      *hostLocation // Location of the host being edited, nil when adding
+	base         Host          // Host being edited, keeps fields the form doesn't expose
+	keyPicker    *keyPicker    // Open identity file picker, nil when closed
+	probe        *probeState   // Last connection test, nil if none has run
+	keyInfo      *keyInfoState // Key at the identity file path, nil until it has been read
+	keyInfoID    int           // Bumped on each identity file change so only the last one is read
+	completions  []string      // Path completions shown below the focused input
+	err          error         // Validation error shown inside the form
 }
 
+// Inputs that hold a local file path and complete it on tab
+var pathInputs = []int{identityFileInput}
+
 const (
 	nameInput = iota
 	hostInput
 	portInput
 	userInput
+	folderInput
+	tagsInput
+	notesInput
+	jumpHostRefInput
 	sshAgentInput
+	agentKeyInput
 	identityFileInput
 	identityPassphraseInput
 	keyringServiceInput
 	keyringAccountInput
 	passwordInput
+	passwordCommandInput
+	promptPasswordInput
+	storeInKeyringInput
+	interactiveAuthInput
+	localForwardsInput
+	remoteForwardsInput
+	envInput
 )
 
 var inputLabels = []string{
@@ -36,12 +65,24 @@ var inputLabels = []string{
 	"Host/IP",
 	"Port",
 	"User",
+	"Folder",
+	"Tags (comma-separated)",
+	"Notes",
+	"Jump Host (name of a saved host)",
 	"Use SSH Agent (true/false)",
+	"Agent Key Fingerprint",
 	"Identity File Path",
 	"Identity Passphrase",
 	"Keyring Service",
 	"Keyring Account",
 	"Password",
+	"Password Command (e.g. pass show server)",
+	"Prompt for Password on Connect (true/false)",
+	"Store Password in Keyring (true/false)",
+	"Answer Prompts Myself, e.g. 2FA/OTP codes (true/false)",
+	"Local Forwards (comma-separated, e.g. 8080:localhost:80)",
+	"Remote Forwards (comma-separated, e.g. 9000:localhost:3000)",
+	"Environment Variables (comma-separated, e.g. LANG=en_US.UTF-8)",
 }
 
 // Renders the help view and subtracts its height from available height
@@ -75,63 +116,502 @@ func (m Model) calculateVisibleFormContent(
 	helpRendered string,
 	getFormLines func(lines []string, availHeight int) []string,
 ) string {
+	// Keep at least one line of content so very short windows still show something
+	availHeight = max(availHeight, 1)
 	lines := strings.Split(b, "\n")
 	visibleLines := getFormLines(lines, availHeight)
 	content := lg.NewStyle().Height(availHeight).Render(strings.Join(visibleLines, "\n"))
 	return docStyle.Render(lg.JoinVertical(lg.Left, title, content, helpRendered))
 }
 
-// Saves a new host to the config file
-func saveHostToConfig(configPath string, newHost Host) error {
+// Returns the availHeight lines starting at offset, clamped so the last page stays full
+func scrollLines(lines []string, offset, availHeight int) []string {
+	if len(lines) <= availHeight {
+		return lines
+	}
+	start := min(max(offset, 0), len(lines)-availHeight)
+	return lines[start : start+availHeight]
+}
+
+// Identifies where a host lives in the config file
+type hostLocation struct {
+	folder int // Index into Configuration.Folders, -1 for top-level hosts
+	index  int // Index into the host slice of that folder
+}
+
+// Reads and parses the config file
+func readConfig(configPath string) (Configuration, error) {
+	var config Configuration
+
 	data, err := os.ReadFile(configPath)
 	if err != nil {
-		return fmt.Errorf("failed to read config: %w", err)
+		return config, fmt.Errorf("failed to read config: %w", err)
 	}
 
-	var config Configuration
 	if err := json.Unmarshal(data, &config); err != nil {
-		return fmt.Errorf("failed to parse config: %w", err)
+		return config, fmt.Errorf("failed to parse config: %w", err)
 	}
 
-	config.Hosts = append(config.Hosts, newHost)
+	return config, nil
+}
 
+// Writes the config file as indented JSON
+// Writes to a temp file and renames it over the original so an interrupted write
+// can't truncate the config, keeping the previous version as a .bak copy
+func writeConfig(configPath string, config Configuration) error {
 	prettyJSON, err := json.MarshalIndent(config, "", "\t")
 	if err != nil {
 		return fmt.Errorf("failed to marshal config: %w", err)
 	}
 
-	if err := os.WriteFile(configPath, prettyJSON, 0644); err != nil {
+	tmp, err := os.CreateTemp(filepath.Dir(configPath), filepath.Base(configPath)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temp config: %w", err)
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath) // no-op once renamed
+
+	if _, err := tmp.Write(prettyJSON); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync config: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
 		return fmt.Errorf("failed to write config: %w", err)
 	}
+	// The config can hold passwords, so keep whatever mode the user gave it
+	mode := configFileMode(configPath)
+	if err := os.Chmod(tmpPath, mode); err != nil {
+		return fmt.Errorf("failed to set config permissions: %w", err)
+	}
+
+	// Back up the current config before replacing it
+	if previous, err := os.ReadFile(configPath); err == nil {
+		backupPath := configPath + ".bak"
+		if err := os.WriteFile(backupPath, previous, mode); err != nil {
+			return fmt.Errorf("failed to back up config: %w", err)
+		}
+		// WriteFile leaves an existing backup's mode alone
+		if err := os.Chmod(backupPath, mode); err != nil {
+			return fmt.Errorf("failed to set backup permissions: %w", err)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to read config for backup: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, configPath); err != nil {
+		return fmt.Errorf("failed to replace config: %w", err)
+	}
+	recordConfigWrite(configPath)
 
 	return nil
 }
 
-// Deletes a host from the config file
-func deleteHostFromConfig(configPath string, hostIndex int) error {
-	data, err := os.ReadFile(configPath)
+// Returns the permissions of the config file, or 0600 if it doesn't exist yet
+func configFileMode(configPath string) os.FileMode {
+	info, err := os.Stat(configPath)
 	if err != nil {
-		return fmt.Errorf("failed to read config: %w", err)
+		return 0600
 	}
+	return info.Mode().Perm()
+}
 
-	var config Configuration
-	if err := json.Unmarshal(data, &config); err != nil {
-		return fmt.Errorf("failed to parse config: %w", err)
+// How long to wait for another instance to release the config lock
+const configLockTimeout = 5 * time.Second
+
+// Lock files older than this are assumed to be left behind by a crashed instance
+const configLockStale = 30 * time.Second
+
+// Takes an exclusive lock on the config file so concurrent instances don't clobber each other
+// Returns a function that releases the lock
+func lockConfig(configPath string) (unlock func(), err error) {
+	lockPath := configPath + ".lock"
+	deadline := time.Now().Add(configLockTimeout)
+
+	for {
+		f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(lockPath) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("failed to lock config: %w", err)
+		}
+
+		if info, statErr := os.Stat(lockPath); statErr == nil && time.Since(info.ModTime()) > configLockStale {
+			logger.Printf("Removing stale config lock %s", lockPath)
+			os.Remove(lockPath)
+			continue
+		}
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("config is locked by another instance (remove %s if this is wrong)", lockPath)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// Returns the host slice for a folder index (-1 for top-level hosts)
+func hostsIn(config *Configuration, folder int) (*[]Host, error) {
+	if folder == -1 {
+		return &config.Hosts, nil
+	}
+	if folder < 0 || folder >= len(config.Folders) {
+		return nil, fmt.Errorf("invalid folder index")
+	}
+	return &config.Folders[folder].Hosts, nil
+}
+
+// Returns the index of the named folder, creating it if it doesn't exist
+// An empty name refers to the top-level host list (-1)
+func findOrCreateFolder(config *Configuration, name string) int {
+	if name == "" {
+		return -1
+	}
+	for i, f := range config.Folders {
+		if f.Name == name {
+			return i
+		}
+	}
+	config.Folders = append(config.Folders, Folder{Name: name})
+	return len(config.Folders) - 1
+}
+
+// Returns the set of host names across top-level hosts and folders
+func hostNames(hosts []Host, folders []Folder) map[string]bool {
+	names := make(map[string]bool)
+	for _, h := range hosts {
+		names[h.Name] = true
+	}
+	for _, f := range folders {
+		for _, h := range f.Hosts {
+			names[h.Name] = true
+		}
+	}
+	return names
+}
+
+// Returned when saving a host whose name is already taken
+var errDuplicateName = errors.New("a host with this name already exists")
+
+// Returns an error if another host already uses the name
+// The host at skip (if any) is ignored so an edited host can keep its name
+func checkUniqueName(config *Configuration, name string, skip *hostLocation) error {
+	check := func(hosts []Host, folder int) error {
+		for i, h := range hosts {
+			if skip != nil && skip.folder == folder && skip.index == i {
+				continue
+			}
+			if h.Name == name {
+				return fmt.Errorf("%w: %q", errDuplicateName, name)
+			}
+		}
+		return nil
+	}
+
+	if err := check(config.Hosts, -1); err != nil {
+		return err
+	}
+	for fi, f := range config.Folders {
+		if err := check(f.Hosts, fi); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Saves a new host to the config file under the named folder
+// Creates the config file if it doesn't exist yet
+func saveHostToConfig(configPath string, folderName string, newHost Host) error {
+	unlock, err := lockConfig(configPath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	config, err := readConfig(configPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	if err := checkUniqueName(&config, newHost.Name, nil); err != nil {
+		return err
+	}
+
+	hosts, err := hostsIn(&config, findOrCreateFolder(&config, folderName))
+	if err != nil {
+		return err
+	}
+	*hosts = append(*hosts, newHost)
+
+	return writeConfig(configPath, config)
+}
+
+// Saves several new hosts to the named folder in a single write
+// Nothing is saved if any name is already taken
+func saveHostsToConfig(configPath string, folderName string, newHosts []Host) error {
+	unlock, err := lockConfig(configPath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	config, err := readConfig(configPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	for i, h := range newHosts {
+		if err := checkUniqueName(&config, h.Name, nil); err != nil {
+			return err
+		}
+		if slices.ContainsFunc(newHosts[:i], func(o Host) bool { return o.Name == h.Name }) {
+			return fmt.Errorf("%w: %q", errDuplicateName, h.Name)
+		}
+	}
+
+	hosts, err := hostsIn(&config, findOrCreateFolder(&config, folderName))
+	if err != nil {
+		return err
+	}
+	*hosts = append(*hosts, newHosts...)
+
+	return writeConfig(configPath, config)
+}
+
+// Replaces an existing host in the config file, moving it if the folder changed
+// Returns the new location of the host
+func updateHostInConfig(configPath string, loc hostLocation, folderName string, updatedHost Host) (hostLocation, error) {
+	unlock, err := lockConfig(configPath)
+	if err != nil {
+		return loc, err
+	}
+	defer unlock()
+
+	config, err := readConfig(configPath)
+	if err != nil {
+		return loc, err
 	}
 
-	if hostIndex < 0 || hostIndex >= len(config.Hosts) {
+	// Creating the target folder can reallocate config.Folders, so it's found before taking a pointer into it
+	target := findOrCreateFolder(&config, folderName)
+	hosts, err := hostsIn(&config, loc.folder)
+	if err != nil {
+		return loc, err
+	}
+	if loc.index < 0 || loc.index >= len(*hosts) {
+		return loc, fmt.Errorf("invalid host index")
+	}
+
+	if err := checkUniqueName(&config, updatedHost.Name, &loc); err != nil {
+		return loc, err
+	}
+
+	newLoc := loc
+	if target == loc.folder {
+		(*hosts)[loc.index] = updatedHost
+	} else {
+		*hosts = append((*hosts)[:loc.index], (*hosts)[loc.index+1:]...)
+		targetHosts, err := hostsIn(&config, target)
+		if err != nil {
+			return loc, err
+		}
+		*targetHosts = append(*targetHosts, updatedHost)
+		newLoc = hostLocation{folder: target, index: len(*targetHosts) - 1}
+	}
+
+	if err := writeConfig(configPath, config); err != nil {
+		return loc, err
+	}
+
+	return newLoc, nil
+}
+
+// Records a successful connection on the named host, ending at t after lasting duration
+func recordConnection(configPath string, name string, t time.Time, duration time.Duration) error {
+	unlock, err := lockConfig(configPath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	config, err := readConfig(configPath)
+	if err != nil {
+		return err
+	}
+
+	h := findHostByName(&config, name)
+	if h == nil {
+		return fmt.Errorf("host %q not found", name)
+	}
+	h.LastConnected = t
+	h.ConnectCount++
+	h.TotalDuration += int(duration.Round(time.Second) / time.Second)
+
+	return writeConfig(configPath, config)
+}
+
+// Moves the host at index from to index to within the same folder, shifting those between
+func reorderHostInConfig(configPath string, folder, from, to int) error {
+	unlock, err := lockConfig(configPath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	config, err := readConfig(configPath)
+	if err != nil {
+		return err
+	}
+
+	hosts, err := hostsIn(&config, folder)
+	if err != nil {
+		return err
+	}
+	if from < 0 || from >= len(*hosts) || to < 0 || to >= len(*hosts) {
 		return fmt.Errorf("invalid host index")
 	}
-	config.Hosts = append(config.Hosts[:hostIndex], config.Hosts[hostIndex+1:]...)
+	h := (*hosts)[from]
+	*hosts = slices.Insert(slices.Delete(*hosts, from, from+1), to, h)
 
-	prettyJSON, err := json.MarshalIndent(config, "", "\t")
+	return writeConfig(configPath, config)
+}
+
+// Sets whether the host at loc is disabled, leaving everything else about it alone
+func setHostDisabledInConfig(configPath string, loc hostLocation, disabled bool) error {
+	unlock, err := lockConfig(configPath)
 	if err != nil {
-		return fmt.Errorf("failed to marshal config: %w", err)
+		return err
 	}
+	defer unlock()
 
-	if err := os.WriteFile(configPath, prettyJSON, 0644); err != nil {
-		return fmt.Errorf("failed to write config: %w", err)
+	config, err := readConfig(configPath)
+	if err != nil {
+		return err
+	}
+
+	hosts, err := hostsIn(&config, loc.folder)
+	if err != nil {
+		return err
+	}
+	if loc.index < 0 || loc.index >= len(*hosts) {
+		return fmt.Errorf("invalid host index")
 	}
+	(*hosts)[loc.index].Disabled = disabled
 
+	return writeConfig(configPath, config)
+}
+
+// Returns a pointer to the named host in the config, or nil if it doesn't exist
+func findHostByName(config *Configuration, name string) *Host {
+	for i := range config.Hosts {
+		if config.Hosts[i].Name == name {
+			return &config.Hosts[i]
+		}
+	}
+	for fi := range config.Folders {
+		for i := range config.Folders[fi].Hosts {
+			if config.Folders[fi].Hosts[i].Name == name {
+				return &config.Folders[fi].Hosts[i]
+			}
+		}
+	}
 	return nil
 }
+
+// Saves the host list sort mode to the config file
+func saveSortMode(configPath string, mode sortMode) error {
+	unlock, err := lockConfig(configPath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	config, err := readConfig(configPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	config.SortMode = string(mode)
+
+	return writeConfig(configPath, config)
+}
+
+// Saves the name of the host selected when the list was left
+func saveLastSelected(configPath string, name string) error {
+	unlock, err := lockConfig(configPath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	config, err := readConfig(configPath)
+	if err != nil {
+		return err
+	}
+	config.LastSelected = name
+
+	return writeConfig(configPath, config)
+}
+
+// Saves the global settings to the config file, leaving hosts and folders untouched
+func saveSettings(configPath string, settings Settings) error {
+	unlock, err := lockConfig(configPath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	config, err := readConfig(configPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	config.Settings = settings
+
+	return writeConfig(configPath, config)
+}
+
+// Deletes a host from the config file
+func deleteHostFromConfig(configPath string, loc hostLocation) error {
+	return deleteHostsFromConfig(configPath, []hostLocation{loc})
+}
+
+// Removes several hosts in one write
+// Hosts are removed from the highest index down so earlier removals don't shift later ones
+func deleteHostsFromConfig(configPath string, locs []hostLocation) error {
+	unlock, err := lockConfig(configPath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	config, err := readConfig(configPath)
+	if err != nil {
+		return err
+	}
+
+	locs = slices.Clone(locs)
+	slices.SortFunc(locs, func(a, b hostLocation) int {
+		return cmp.Or(cmp.Compare(a.folder, b.folder), cmp.Compare(b.index, a.index))
+	})
+	locs = slices.Compact(locs)
+
+	// Check every location before changing anything
+	for _, loc := range locs {
+		hosts, err := hostsIn(&config, loc.folder)
+		if err != nil {
+			return err
+		}
+		if loc.index < 0 || loc.index >= len(*hosts) {
+			return fmt.Errorf("invalid host index")
+		}
+	}
+	for _, loc := range locs {
+		hosts, _ := hostsIn(&config, loc.folder)
+		*hosts = slices.Delete(*hosts, loc.index, loc.index+1)
+	}
+
+	return writeConfig(configPath, config)
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	lg "github.com/charmbracelet/lipgloss"
+)
+
+// Returned when the user declines a server banner that needs acknowledgement
+var errBannerDeclined = errors.New("server banner not acknowledged, disconnected")
+
+// Key map for the banner acknowledgement prompt
+type bannerKeyMap struct {
+	Accept  key.Binding
+	Decline key.Binding
+}
+
+func (k bannerKeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.Accept, k.Decline}
+}
+
+func (k bannerKeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{
+		{k.Accept, k.Decline},
+	}
+}
+
+var bannerKeys = bannerKeyMap{
+	Accept: key.NewBinding(
+		key.WithKeys("y", "enter"),
+		key.WithHelp("y/⏎", "acknowledge and connect"),
+	),
+	Decline: key.NewBinding(
+		key.WithKeys("n", "esc", "ctrl+c"),
+		key.WithHelp("n/esc", "disconnect"),
+	),
+}
+
+// Standalone Bubble Tea model that shows a server banner until it's acknowledged
+// Like interactiveModel, it runs after the host list has exited
+type bannerModel struct {
+	host     string
+	banner   string
+	accepted bool
+}
+
+func (m bannerModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m bannerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+
+	switch keyMsg.String() {
+	case "y", "enter":
+		m.accepted = true
+		return m, tea.Quit
+	case "n", "esc", "ctrl+c":
+		return m, tea.Quit
+	}
+	return m, nil
+}
+
+func (m bannerModel) View() string {
+	titleStyle := lg.NewStyle().
+		Bold(true).
+		Foreground(theme.TitleText).
+		Background(theme.TitleBackground).
+		Padding(0, 1).
+		Margin(0, 0, 0, 2)
+
+	bannerStyle := lg.NewStyle().
+		Foreground(theme.Text).
+		Border(lg.RoundedBorder()).
+		BorderForeground(theme.Border).
+		Padding(0, 1).
+		Margin(0, 0, 0, 2)
+
+	helpStyle := lg.NewStyle().
+		Padding(1, 0, 0, 2)
+
+	b := titleStyle.Render("Message from "+m.host) + "\n\n"
+	b += bannerStyle.Render(m.banner) + "\n"
+
+	return docStyle.Render(b + helpStyle.Render(help.New().View(bannerKeys)))
+}
+
+// Removes carriage returns and control characters a server could use to rewrite the terminal
+func sanitizeBanner(banner string) string {
+	banner = strings.ToValidUTF8(banner, "?")
+	banner = strings.Map(func(r rune) rune {
+		if r == '\r' {
+			return -1
+		}
+		if r < ' ' && r != '\n' && r != '\t' || r == 0x7f {
+			return '?'
+		}
+		return r
+	}, banner)
+	return strings.TrimRight(banner, "\n")
+}
+
+// Returns the handler for h's server banner
+// Banners are printed above the shell like ssh does, or shown in a prompt that must be
+// acknowledged before connecting when the host or the global setting asks for it
+func bannerHandler(h *Host, configuration *Configuration) func(string) error {
+	acknowledge := h.AcknowledgeBanner || configuration.AcknowledgeBanners
+	return func(banner string) error {
+		banner = sanitizeBanner(banner)
+		if !acknowledge {
+			fmt.Fprintln(os.Stderr, banner)
+			return nil
+		}
+
+		finalModel, err := tea.NewProgram(bannerModel{host: h.Name, banner: banner}).Run()
+		if err != nil {
+			return err
+		}
+		if m, ok := finalModel.(bannerModel); !ok || !m.accepted {
+			return errBannerDeclined
+		}
+		return nil
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	lg "github.com/charmbracelet/lipgloss"
+)
+
+// Key map for the startup summary of config problems
+type configIssuesKeyMap struct {
+	Scroll   key.Binding
+	Continue key.Binding
+	Quit     key.Binding
+}
+
+func (k configIssuesKeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.Scroll, k.Continue, k.Quit}
+}
+
+func (k configIssuesKeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{
+		{k.Scroll, k.Continue, k.Quit},
+	}
+}
+
+var configIssuesKeys = configIssuesKeyMap{
+	Scroll: key.NewBinding(
+		key.WithKeys("up", "down"),
+		key.WithHelp("↑/↓", "scroll"),
+	),
+	Continue: key.NewBinding(
+		key.WithKeys("enter", "c"),
+		key.WithHelp("⏎", "continue with the valid hosts"),
+	),
+	Quit: key.NewBinding(
+		key.WithKeys("q", "esc"),
+		key.WithHelp("q", "quit"),
+	),
+}
+
+func (m Model) updateConfigIssues(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "up":
+		if m.configIssuesOffset > 0 {
+			m.configIssuesOffset--
+		}
+	case "down":
+		if m.configIssuesOffset < len(m.configIssues)-1 {
+			m.configIssuesOffset++
+		}
+	case "enter", "c":
+		// Hosts with problems stay hidden until rolodex is restarted
+		m.skippedHosts = invalidHostNames(m.configIssues)
+		m.configIssues = nil
+		m.configIssuesOffset = 0
+		m.view = listView
+		return m, m.list.SetItems(m.buildItems())
+	case "q", "esc":
+		return Quit(m)
+	}
+	return m, nil
+}
+
+func (m Model) renderConfigIssues() string {
+	titleStyle := lg.NewStyle().
+		Bold(true).
+		Foreground(theme.TitleText).
+		Background(theme.TitleBackground).
+		Padding(0, 1).
+		Margin(0, 0, 0, 2)
+
+	warnStyle := lg.NewStyle().
+		Foreground(theme.Warning).
+		Margin(0, 0, 0, 2).
+		Width(max(m.width-8, 20))
+
+	issueStyle := lg.NewStyle().
+		Foreground(theme.Error).
+		Margin(0, 0, 0, 2).
+		Width(max(m.width-8, 20))
+
+	helpRendered, availHeight := m.renderFormHelp(configIssuesKeys)
+
+	title := titleStyle.Render("Config Problems") + "\n\n"
+	availHeight -= lg.Height(title)
+
+	summary := fmt.Sprintf("Found %d problems in %s.", len(m.configIssues), m.configPath)
+	if len(m.configIssues) == 1 {
+		summary = fmt.Sprintf("Found a problem in %s.", m.configPath)
+	}
+	if len(invalidHostNames(m.configIssues)) > 0 {
+		summary += " Continuing hides the hosts affected until the next start."
+	}
+	b := warnStyle.Render(summary) + "\n\n"
+	for _, err := range m.configIssues[min(m.configIssuesOffset, len(m.configIssues)-1):] {
+		b += issueStyle.Render("• "+err.Error()) + "\n"
+	}
+
+	return m.calculateVisibleFormContent(availHeight, b, title, helpRendered, m.getVisibleDeleteLines)
+}
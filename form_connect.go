@@ -0,0 +1,110 @@
+package main
+
+import (
+	"slices"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	lg "github.com/charmbracelet/lipgloss"
+)
+
+// Key map for connect confirmation view
+type connectKeyMap struct {
+	Confirm key.Binding
+	Cancel  key.Binding
+}
+
+func (k connectKeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.Confirm, k.Cancel}
+}
+
+func (k connectKeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{
+		{k.Confirm, k.Cancel},
+	}
+}
+
+var connectKeys = connectKeyMap{
+	Confirm: key.NewBinding(
+		key.WithKeys("y", "Y"),
+		key.WithHelp("y", "connect"),
+	),
+	Cancel: key.NewBinding(
+		key.WithKeys("n", "N", "esc"),
+		key.WithHelp("n/esc", "cancel"),
+	),
+}
+
+// Reports whether connecting to h should be confirmed first, either because
+// the host asks for it or because it carries one of the configured confirm tags
+func (m Model) needsConnectConfirm(h Host) bool {
+	if h.Confirm {
+		return true
+	}
+	for _, tag := range h.Tags {
+		if slices.Contains(m.confirmTags, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m Model) updateConnectConfirm(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "y", "Y":
+		// Confirm and connect
+		h := m.hostToConfirm
+		m.hostToConfirm = nil
+		if h == nil {
+			m.view = listView
+			return m, nil
+		}
+		return m.connect(h)
+
+	case "n", "N", "esc":
+		// Cancel and return to list
+		m.view = listView
+		m.hostToConfirm = nil
+		return m, nil
+	}
+
+	return m, nil
+}
+
+func (m Model) renderConnectConfirm() string {
+	titleStyle := lg.NewStyle().
+		Bold(true).
+		Foreground(theme.TitleText).
+		Background(theme.TitleBackground).
+		Padding(0, 1).
+		Margin(0, 0, 0, 2)
+
+	hostDescriptionStyle := lg.NewStyle().
+		Foreground(theme.Text).
+		Padding(0, 1)
+
+	hostStyle := lg.NewStyle().
+		Foreground(theme.Highlight).
+		Bold(true).
+		Margin(0, 2)
+
+	infoStyle := lg.NewStyle().
+		Foreground(theme.Error).
+		Padding(0, 2)
+
+	helpRendered, availHeight := m.renderFormHelp(connectKeys)
+
+	var title string
+	title = titleStyle.Render("Confirm Connection") + "\n\n"
+	availHeight -= lg.Height(title)
+	var b string
+
+	if m.hostToConfirm != nil {
+		b += infoStyle.Render("Are you sure you want to connect to this host?") + "\n\n"
+		b += hostStyle.Render("Name") + hostDescriptionStyle.Render(m.hostToConfirm.Name) + "\n"
+		b += hostStyle.Render("Host") + hostDescriptionStyle.Render(m.hostToConfirm.Host) + "\n"
+		b += hostStyle.Render("User") + hostDescriptionStyle.Render(m.hostToConfirm.User) + "\n\n"
+	}
+
+	return m.calculateVisibleFormContent(availHeight, b, title, helpRendered, m.getVisibleDeleteLines)
+}
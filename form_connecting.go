@@ -0,0 +1,174 @@
+package main
+
+import (
+	"errors"
+	"os"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/spinner"
+	tea "github.com/charmbracelet/bubbletea"
+	lg "github.com/charmbracelet/lipgloss"
+	"github.com/nathanlytang/rolodex/internal/ssh"
+)
+
+// Returned when the server asks keyboard-interactive questions while connecting inside the TUI
+// The questions can only be asked once the terminal is handed over, so the connection is retried there
+var errNeedsTerminal = errors.New("server asked questions that need the terminal")
+
+// Key map for the connecting view
+type connectingKeyMap struct {
+	Cancel key.Binding
+}
+
+func (k connectingKeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.Cancel}
+}
+
+func (k connectingKeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{
+		{k.Cancel},
+	}
+}
+
+var connectingKeys = connectingKeyMap{
+	Cancel: key.NewBinding(
+		key.WithKeys("esc"),
+		key.WithHelp("esc", "cancel"),
+	),
+}
+
+// Result of connecting in the background, delivered back to Update
+type connectedMsg struct {
+	id            int // Matches Model.connectID unless the attempt was cancelled
+	host          *Host
+	client        *ssh.Client
+	sessionConfig ssh.SessionConfig
+	err           error
+}
+
+// Returns a command that dials and authenticates to h without taking over the terminal
+func dialHost(id int, h *Host, configPath string, password []byte) tea.Cmd {
+	return func() tea.Msg {
+		config, err := readConfig(configPath)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return connectedMsg{id: id, host: h, err: err}
+		}
+		authConfig, sessionConfig, err := connectionConfig(h, &config, password)
+		if err != nil {
+			return connectedMsg{id: id, host: h, err: err}
+		}
+
+		asked := false
+		authConfig.Prompter = func(name, instruction string, questions []string, echos []bool) ([]string, error) {
+			asked = true
+			return nil, errNeedsTerminal
+		}
+
+		client, err := ssh.Connect(h.Host, h.Port, h.User, authConfig, sessionConfig)
+		if err != nil && asked {
+			err = errNeedsTerminal
+		}
+		return connectedMsg{id: id, host: h, client: client, sessionConfig: sessionConfig, err: err}
+	}
+}
+
+// Connects to h in the background while showing a spinner, then quits the TUI to run the shell
+// File browsing, reconnects, and hosts that always ask keyboard-interactive questions
+// connect in the terminal instead
+func (m Model) startConnect(h *Host, password []byte) (tea.Model, tea.Cmd) {
+	m.connectPassword = password
+	if m.browse || m.reconnect || h.InteractiveAuth {
+		m.connectHost = h
+		return Quit(m)
+	}
+
+	m.spinner = spinner.New(
+		spinner.WithSpinner(spinner.Dot),
+		spinner.WithStyle(lg.NewStyle().Foreground(theme.Accent)),
+	)
+	m.connectID++
+	m.connectingHost = h
+	m.view = connectingView
+	return m, tea.Batch(m.spinner.Tick, dialHost(m.connectID, h, m.configPath, password))
+}
+
+// Hands a finished connection to main, or shows why it failed
+func (m Model) finishConnect(msg connectedMsg) (tea.Model, tea.Cmd) {
+	if msg.id != m.connectID || m.view != connectingView {
+		// Cancelled while connecting
+		if msg.client != nil {
+			msg.client.Close()
+		}
+		return m, nil
+	}
+	m.connectingHost = nil
+
+	var unknownErr *ssh.UnknownHostKeyError
+	switch {
+	case errors.Is(msg.err, errNeedsTerminal):
+		m.connectHost = msg.host
+		return Quit(m)
+
+	case errors.As(msg.err, &unknownErr):
+		m.clearConnectPassword()
+		m.unknownHostKey = unknownErr
+		m.unknownHostKeyHost = msg.host
+		m.view = hostKeyConfirmView
+		return m, nil
+
+	case msg.err != nil:
+		m.clearConnectPassword()
+		m.err = msg.err
+		m.showErr = true
+		m.view = listView
+		return m, nil
+	}
+
+	m.connectHost = msg.host
+	m.connectClient = msg.client
+	m.connectSessionConfig = msg.sessionConfig
+	return Quit(m)
+}
+
+// Forgets a prompted password once it's no longer needed
+func (m *Model) clearConnectPassword() {
+	clear(m.connectPassword)
+	m.connectPassword = nil
+}
+
+func (m Model) updateConnecting(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if msg.String() == "esc" {
+		// The dial can't be interrupted, so its result is discarded when it arrives
+		m.connectID++
+		m.connectingHost = nil
+		m.clearConnectPassword()
+		m.view = listView
+		return m, m.list.NewStatusMessage("Connection cancelled")
+	}
+	return m, nil
+}
+
+func (m Model) renderConnecting() string {
+	titleStyle := lg.NewStyle().
+		Bold(true).
+		Foreground(theme.TitleText).
+		Background(theme.TitleBackground).
+		Padding(0, 1).
+		Margin(0, 0, 0, 2)
+
+	textStyle := lg.NewStyle().
+		Foreground(theme.Text)
+
+	helpRendered, availHeight := m.renderFormHelp(connectingKeys)
+
+	var title string
+	title = titleStyle.Render("Connecting") + "\n\n"
+	availHeight -= lg.Height(title)
+	var b string
+
+	if h := m.connectingHost; h != nil {
+		b += "  " + m.spinner.View() + textStyle.Render("Connecting to "+h.Name+" ("+h.User+"@"+h.Host+")...") + "\n"
+	}
+
+	return m.calculateVisibleFormContent(availHeight, b, title, helpRendered, m.getVisibleDeleteLines)
+}
@@ -1,31 +1,39 @@
 package main
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
-	"os"
+	"maps"
+	"net"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
+	"unicode"
 
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	lg "github.com/charmbracelet/lipgloss"
-	"golang.org/x/term"
+	"github.com/nathanlytang/rolodex/internal/logger"
+	"github.com/nathanlytang/rolodex/internal/ssh"
 )
 
 type formKeyMap struct {
 	Navigate key.Binding
 	Submit   key.Binding
 	Cancel   key.Binding
+	PickKey  key.Binding
+	Test     key.Binding
 }
 
 func (k formKeyMap) ShortHelp() []key.Binding {
-	return []key.Binding{k.Navigate, k.Submit, k.Cancel}
+	return []key.Binding{k.Navigate, k.Submit, k.Cancel, k.PickKey, k.Test}
 }
 
 func (k formKeyMap) FullHelp() [][]key.Binding {
 	return [][]key.Binding{
-		{k.Navigate, k.Submit, k.Cancel},
+		{k.Navigate, k.Submit, k.Cancel, k.PickKey, k.Test},
 	}
 }
 
@@ -42,22 +50,33 @@ var formKeys = formKeyMap{
 		key.WithKeys("esc"),
 		key.WithHelp("esc", "cancel"),
 	),
+	PickKey: key.NewBinding(
+		key.WithKeys("ctrl+o"),
+		key.WithHelp("ctrl+o", "pick key"),
+	),
+	Test: key.NewBinding(
+		key.WithKeys("ctrl+t"),
+		key.WithHelp("ctrl+t", "test connection"),
+	),
 }
 
 func newFormModel() formModel {
-	inputs := make([]textinput.Model, 10)
+	inputs := make([]textinput.Model, len(inputLabels))
 
 	for i := range inputs {
 		t := textinput.New()
 		t.Prompt = "> "
-		t.PromptStyle = lg.NewStyle().Foreground(lg.Color("#7D56F4")).Margin(0, 0, 0, 2)
+		t.PromptStyle = lg.NewStyle().Foreground(theme.Accent).Margin(0, 0, 0, 2)
 		t.CharLimit = 256
 
 		switch i {
 		case nameInput:
 			t.Focus()
+		case notesInput:
+			t.CharLimit = 1024
 		case portInput:
 			t.CharLimit = 5
+			t.Validate = validatePort
 		case identityPassphraseInput:
 			t.EchoMode = textinput.EchoPassword
 		case passwordInput:
@@ -73,27 +92,115 @@ func newFormModel() formModel {
 	}
 }
 
+// Creates a form pre-populated with an existing host for editing
+func newEditFormModel(h Host, loc hostLocation, folderName string) formModel {
+	f := newFormModel()
+	f.editLoc = &loc
+	f.base = h
+
+	f.inputs[nameInput].SetValue(h.Name)
+	f.inputs[hostInput].SetValue(h.Host)
+	// A host without a port leaves the field blank, which saves as 22
+	if h.Port > 0 {
+		f.inputs[portInput].SetValue(strconv.Itoa(h.Port))
+	}
+	f.inputs[userInput].SetValue(h.User)
+	f.inputs[folderInput].SetValue(folderName)
+	f.inputs[tagsInput].SetValue(strings.Join(h.Tags, ", "))
+	f.inputs[notesInput].SetValue(h.Notes)
+	f.inputs[jumpHostRefInput].SetValue(h.JumpHostRef)
+	f.inputs[sshAgentInput].SetValue(strconv.FormatBool(h.SSHAgent))
+	f.inputs[agentKeyInput].SetValue(h.AgentKey)
+	f.inputs[identityFileInput].SetValue(h.IdentityFile)
+	f.inputs[identityPassphraseInput].SetValue(h.IdentityPassphrase)
+	f.inputs[keyringServiceInput].SetValue(h.KeyringService)
+	f.inputs[keyringAccountInput].SetValue(h.KeyringAccount)
+	f.inputs[passwordInput].SetValue(h.Password)
+	f.inputs[passwordCommandInput].SetValue(h.PasswordCommand)
+	f.inputs[promptPasswordInput].SetValue(strconv.FormatBool(h.PromptPassword))
+	f.inputs[interactiveAuthInput].SetValue(strconv.FormatBool(h.InteractiveAuth))
+	f.inputs[localForwardsInput].SetValue(strings.Join(h.LocalForwards, ", "))
+	f.inputs[remoteForwardsInput].SetValue(strings.Join(h.RemoteForwards, ", "))
+	f.inputs[envInput].SetValue(formatEnvList(h.Env))
+
+	return f
+}
+
+// Creates an add form pre-populated with the defaults from settings, blank ones left empty
+// The values go through the inputs' own validation, so a bad default shows up as an input error
+func newAddFormModel(user string, port int, identityFile string) formModel {
+	f := newFormModel()
+	f.inputs[userInput].SetValue(user)
+	if port > 0 {
+		f.inputs[portInput].SetValue(strconv.Itoa(port))
+	}
+	f.inputs[identityFileInput].SetValue(identityFile)
+	return f
+}
+
+// Creates an add form pre-populated with a copy of an existing host
+// The name is incremented so the copy doesn't clash with existing hosts
+func newDuplicateFormModel(h Host, folderName string, existing map[string]bool) formModel {
+	f := newEditFormModel(h, hostLocation{}, folderName)
+	f.editLoc = nil
+	f.base.LastConnected = time.Time{}
+	f.base.ConnectCount = 0
+	f.base.TotalDuration = 0
+	f.inputs[nameInput].SetValue(nextHostName(h.Name, existing))
+	return f
+}
+
+// Returns name with a trailing number incremented (or " 2" appended) until it is unused
+func nextHostName(name string, existing map[string]bool) string {
+	base := strings.TrimRightFunc(name, unicode.IsDigit)
+	digits := name[len(base):]
+	n := 1
+	if digits != "" {
+		n, _ = strconv.Atoi(digits)
+	} else {
+		base += " "
+	}
+
+	// Keep zero padding, e.g. web-01 -> web-02
+	for {
+		n++
+		candidate := fmt.Sprintf("%s%0*d", base, len(digits), n)
+		if !existing[candidate] {
+			return candidate
+		}
+	}
+}
+
 func validateAndCreateHost(f formModel) (Host, error) {
 	// Validate required fields
 	if f.inputs[nameInput].Value() == "" {
-		return Host{}, fmt.Errorf("name is required")
+		return Host{}, &inputError{nameInput, errors.New("name is required")}
 	}
 	if f.inputs[hostInput].Value() == "" {
-		return Host{}, fmt.Errorf("host/IP is required")
+		return Host{}, &inputError{hostInput, errors.New("host/IP is required")}
 	}
 	if f.inputs[userInput].Value() == "" {
-		return Host{}, fmt.Errorf("user is required")
+		return Host{}, &inputError{userInput, errors.New("user is required")}
 	}
 
+	// A bracketed IPv6 address may carry its own port, e.g. [2001:db8::1]:2222
+	host, hostPort := splitHostInput(f.inputs[hostInput].Value())
+
 	// Parse port
 	portStr := f.inputs[portInput].Value()
+	if hostPort != "" {
+		if portStr != "" && portStr != hostPort {
+			return Host{}, &inputError{hostInput, fmt.Errorf("port %s in the host doesn't match the port field", hostPort)}
+		}
+		portStr = hostPort
+	}
 	if portStr == "" {
 		portStr = "22" // Default port
 	}
-	port, err := strconv.Atoi(portStr)
-	if err != nil || port < 1 || port > 65535 {
-		return Host{}, fmt.Errorf("invalid port number")
+	if err := validatePort(portStr); err != nil {
+		return Host{}, &inputError{portInput, err}
 	}
+	port, _ := strconv.Atoi(portStr)
 
 	// Parse SSH Agent
 	sshAgent := false
@@ -101,31 +208,247 @@ func validateAndCreateHost(f formModel) (Host, error) {
 		sshAgent = true
 	}
 
-	return Host{
-		Name:               f.inputs[nameInput].Value(),
-		Host:               f.inputs[hostInput].Value(),
-		Port:               port,
-		User:               f.inputs[userInput].Value(),
-		SSHAgent:           sshAgent,
-		IdentityFile:       f.inputs[identityFileInput].Value(),
-		IdentityPassphrase: f.inputs[identityPassphraseInput].Value(),
-		KeyringService:     f.inputs[keyringServiceInput].Value(),
-		KeyringAccount:     f.inputs[keyringAccountInput].Value(),
-		Password:           f.inputs[passwordInput].Value(),
-	}, nil
+	// Parse port forwards
+	localForwards, err := parseForwardList(f.inputs[localForwardsInput].Value())
+	if err != nil {
+		return Host{}, &inputError{localForwardsInput, err}
+	}
+	remoteForwards, err := parseForwardList(f.inputs[remoteForwardsInput].Value())
+	if err != nil {
+		return Host{}, &inputError{remoteForwardsInput, err}
+	}
+
+	// Longer cycles are caught at connect time, when every host is known
+	if jump := strings.TrimSpace(f.inputs[jumpHostRefInput].Value()); jump != "" && jump == f.inputs[nameInput].Value() {
+		return Host{}, &inputError{jumpHostRefInput, errors.New("a host can't be its own jump host")}
+	}
+
+	// Parse environment variables
+	env, err := parseEnvList(f.inputs[envInput].Value())
+	if err != nil {
+		return Host{}, &inputError{envInput, err}
+	}
+
+	// Start from the original host so fields without form inputs are kept when editing
+	h := f.base
+	h.Name = f.inputs[nameInput].Value()
+	h.Host = host
+	h.Port = port
+	h.User = f.inputs[userInput].Value()
+	h.SSHAgent = sshAgent
+	h.AgentKey = strings.TrimSpace(f.inputs[agentKeyInput].Value())
+	h.IdentityFile = f.inputs[identityFileInput].Value()
+	h.IdentityPassphrase = f.inputs[identityPassphraseInput].Value()
+	h.KeyringService = f.inputs[keyringServiceInput].Value()
+	h.KeyringAccount = f.inputs[keyringAccountInput].Value()
+	h.Password = f.inputs[passwordInput].Value()
+	h.PasswordCommand = strings.TrimSpace(f.inputs[passwordCommandInput].Value())
+	h.PromptPassword = f.inputs[promptPasswordInput].Value() == "true"
+	h.InteractiveAuth = f.inputs[interactiveAuthInput].Value() == "true"
+	h.Tags = parseTags(f.inputs[tagsInput].Value())
+	h.Notes = strings.TrimSpace(f.inputs[notesInput].Value())
+	h.JumpHostRef = strings.TrimSpace(f.inputs[jumpHostRefInput].Value())
+	h.LocalForwards = localForwards
+	h.RemoteForwards = remoteForwards
+	h.Env = env
+
+	// Storing the password fills in whichever keyring field is blank, otherwise both are needed to find it
+	if f.inputs[storeInKeyringInput].Value() == "true" && h.Password != "" {
+		setKeyringDefaults(&h)
+	}
+	if h.KeyringService == "" && h.KeyringAccount != "" {
+		return Host{}, &inputError{keyringServiceInput, errIncompleteKeyring}
+	}
+	if h.KeyringService != "" && h.KeyringAccount == "" {
+		return Host{}, &inputError{keyringAccountInput, errIncompleteKeyring}
+	}
+
+	if !hasAuthMethod(h) {
+		return Host{}, &inputError{sshAgentInput, errNoAuthMethod}
+	}
+	return h, nil
+}
+
+// Validation error tied to the form input that caused it
+type inputError struct {
+	input int
+	err   error
+}
+
+func (e *inputError) Error() string {
+	return e.err.Error()
+}
+
+func (e *inputError) Unwrap() error {
+	return e.err
+}
+
+// Returned when a host is saved without any way to authenticate
+var errNoAuthMethod = errors.New("configure at least one authentication method")
+
+// Returned when only one of the keyring service and account is set
+var errIncompleteKeyring = errors.New("set both the keyring service and account, or neither")
+
+// Returns true if the host has at least one authentication method configured
+// The keyring only counts with both a service and an account, since it needs both to find the password
+func hasAuthMethod(h Host) bool {
+	return h.SSHAgent || h.IdentityFile != "" || (h.KeyringService != "" && h.KeyringAccount != "") ||
+		h.Password != "" || h.PasswordCommand != "" || h.PromptPassword || h.InteractiveAuth
+}
+
+// Default keyring service used when the form leaves it blank
+const defaultKeyringService = "rolodex"
+
+// Fills in a blank keyring service and account with "rolodex" and user@name
+func setKeyringDefaults(h *Host) {
+	if h.KeyringService == "" {
+		h.KeyringService = defaultKeyringService
+	}
+	if h.KeyringAccount == "" {
+		h.KeyringAccount = h.User + "@" + h.Name
+	}
+}
+
+// Stores the host's password in the OS keyring and replaces it with keyring references
+// Service and account default to "rolodex" and user@name when left blank
+func storePasswordInKeyring(h *Host) error {
+	if h.Password == "" {
+		return fmt.Errorf("no password to store")
+	}
+	setKeyringDefaults(h)
+
+	if err := ssh.StoreInKeyring(h.KeyringService, h.KeyringAccount, h.Password); err != nil {
+		return err
+	}
+
+	logger.Printf("Stored password for %s in keyring as %s/%s", h.Name, h.KeyringService, h.KeyringAccount)
+	h.Password = ""
+	return nil
+}
+
+// Splits a comma-separated list of forward specs and validates each one
+func parseForwardList(value string) ([]string, error) {
+	var forwards []string
+	for _, spec := range strings.Split(value, ",") {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+		if _, _, err := ssh.ParseForward(spec); err != nil {
+			return nil, err
+		}
+		forwards = append(forwards, spec)
+	}
+	return forwards, nil
+}
+
+// Splits a host input into the address and any port given with a bracketed IPv6 address
+// Brackets are removed so [2001:db8::1] and 2001:db8::1 are stored the same way
+func splitHostInput(value string) (host, port string) {
+	value = strings.TrimSpace(value)
+	if !strings.HasPrefix(value, "[") {
+		return value, ""
+	}
+	if h, p, err := net.SplitHostPort(value); err == nil {
+		return h, p
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(value, "["), "]"), ""
+}
+
+// Checks the port input as it's typed, empty means the default port
+func validatePort(value string) error {
+	if value == "" {
+		return nil
+	}
+	port, err := strconv.Atoi(value)
+	if err != nil {
+		return errors.New("port must be a number")
+	}
+	if port < 1 || port > 65535 {
+		return errors.New("port must be between 1 and 65535")
+	}
+	return nil
+}
+
+// Parses comma-separated KEY=VALUE pairs, returning nil if there are none
+func parseEnvList(value string) (map[string]string, error) {
+	var env map[string]string
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, val, ok := strings.Cut(pair, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" || strings.ContainsAny(name, " \t") {
+			return nil, fmt.Errorf("invalid environment variable %q, expected KEY=VALUE", pair)
+		}
+		if env == nil {
+			env = make(map[string]string)
+		}
+		env[name] = strings.TrimSpace(val)
+	}
+	return env, nil
+}
+
+// Formats environment variables as comma-separated KEY=VALUE pairs sorted by name
+func formatEnvList(env map[string]string) string {
+	var pairs []string
+	for _, name := range slices.Sorted(maps.Keys(env)) {
+		pairs = append(pairs, name+"="+env[name])
+	}
+	return strings.Join(pairs, ", ")
 }
 
 func (m Model) updateForm(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.form.keyPicker != nil {
+		return m.updateKeyPicker(msg)
+	}
+
 	switch msg.String() {
+	case "ctrl+o":
+		// Open the key picker from the agent key or identity file input
+		switch m.form.focusIndex {
+		case agentKeyInput:
+			m.form.keyPicker = newAgentKeyPicker()
+		case identityFileInput:
+			m.form.keyPicker = newIdentityPicker()
+		}
+		return m, nil
+
+	case "ctrl+t":
+		// Check the host is reachable without saving or authenticating
+		return m.startProbe()
+
 	case "esc":
 		// Cancel and return to list
 		m.view = listView
 		return m, nil
 
 	case "tab", "shift+tab", "up", "down":
-		// Navigate between inputs
 		s := msg.String()
 
+		// Tab completes a path input that has something to complete, otherwise it moves on
+		if s == "tab" && slices.Contains(pathInputs, m.form.focusIndex) {
+			input := &m.form.inputs[m.form.focusIndex]
+			if value := input.Value(); value != "" {
+				completed, candidates := completePath(value)
+				if completed != value || len(candidates) > 0 {
+					input.SetValue(completed)
+					input.CursorEnd()
+					m.form.completions = candidates
+					var cmd tea.Cmd
+					if m.form.focusIndex == identityFileInput {
+						cmd = m.form.identityFileChanged()
+					}
+					return m, cmd
+				}
+			}
+		}
+
+		// Navigate between inputs
+		m.form.completions = nil
+
 		if s == "up" || s == "shift+tab" {
 			m.form.focusIndex--
 		} else {
@@ -156,22 +479,38 @@ func (m Model) updateForm(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		// Submit form
 		newHost, err := validateAndCreateHost(m.form)
 		if err != nil {
-			m.err = err
-			m.showErr = true
-			m.view = listView
-			return m, nil
+			return m.showFormError(err)
 		}
 
-		// Save to config
-		if err := saveHostToConfig(m.configPath, newHost); err != nil {
-			m.err = fmt.Errorf("failed to save host: %w", err)
-			m.showErr = true
-			m.view = listView
-			return m, nil
+		// Move the password into the OS keyring instead of config.json
+		if m.form.inputs[storeInKeyringInput].Value() == "true" {
+			if err := storePasswordInKeyring(&newHost); err != nil {
+				return m.showFormError(&inputError{storeInKeyringInput, fmt.Errorf("failed to store password in keyring: %w", err)})
+			}
 		}
 
+		// Save to config, replacing the original host when editing
+		folderName := strings.TrimSpace(m.form.inputs[folderInput].Value())
+		var selectLoc *hostLocation
+		change := "adding " + newHost.Name
+		if m.form.editLoc != nil {
+			change = "editing " + m.form.base.Name
+		}
+		snapshot := takeSnapshot(m.configPath, change)
+		if m.form.editLoc != nil {
+			newLoc, err := updateHostInConfig(m.configPath, *m.form.editLoc, folderName, newHost)
+			if err != nil {
+				return m.showFormError(fmt.Errorf("failed to update host: %w", err))
+			}
+			selectLoc = &newLoc
+		} else if err := saveHostToConfig(m.configPath, folderName, newHost); err != nil {
+			return m.showFormError(fmt.Errorf("failed to save host: %w", err))
+		}
+		m.form.err = nil
+		m.undo = snapshot
+
 		// Reload config
-		data, err := os.ReadFile(m.configPath)
+		config, err := readConfig(m.configPath)
 		if err != nil {
 			m.err = fmt.Errorf("failed to reload config: %w", err)
 			m.showErr = true
@@ -179,71 +518,112 @@ func (m Model) updateForm(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			return m, nil
 		}
 
-		var config Configuration
-		if err := json.Unmarshal(data, &config); err != nil {
-			m.err = fmt.Errorf("failed to parse reloaded config: %w", err)
-			m.showErr = true
-			m.view = listView
-			return m, nil
-		}
-
 		// Update model with new hosts and return to list
+		// Saving can move hosts between folders, so marks no longer apply
 		m.hosts = config.Hosts
-		m.list = buildList(m.hosts)
+		m.folders = config.Folders
+		m.marked = nil
+		m.list = m.buildList()
+		if selectLoc != nil {
+			selectHost(&m.list, *selectLoc)
+		}
 		m.view = listView
-		// Trigger window size update to refresh list
-		return m, func() tea.Msg {
-			w, h, _ := term.GetSize(int(os.Stdout.Fd()))
-			return tea.WindowSizeMsg{Width: w, Height: h}
+		return m, nil
+	}
+
+	// Only digits can be typed into the port
+	if m.form.focusIndex == portInput && msg.Type == tea.KeyRunes {
+		if strings.ContainsFunc(string(msg.Runes), func(r rune) bool { return r < '0' || r > '9' }) {
+			return m, nil
 		}
 	}
 
 	// Update the focused input
+	m.form.completions = nil
 	var cmd tea.Cmd
+	before := m.form.inputs[m.form.focusIndex].Value()
 	m.form.inputs[m.form.focusIndex], cmd = m.form.inputs[m.form.focusIndex].Update(msg)
+
+	// A test result no longer applies once the host or port changes
+	if m.form.focusIndex == hostInput || m.form.focusIndex == portInput {
+		if m.form.inputs[m.form.focusIndex].Value() != before {
+			m.form.probe = nil
+		}
+	}
+	if m.form.focusIndex == identityFileInput && m.form.inputs[identityFileInput].Value() != before {
+		cmd = tea.Batch(cmd, m.form.identityFileChanged())
+	}
 	return m, cmd
 }
 
 func (m Model) renderForm() string {
+	helpRendered, availHeight := m.renderFormHelp(formKeys)
+
+	// Title is always visible at the top
+	title := m.formTitle()
+
+	// Subtract title height from available height for content
+	availHeight -= lg.Height(title)
+
+	b, _ := m.formContent()
+	return m.calculateVisibleFormContent(availHeight, b, title, helpRendered, m.getVisibleFormLines)
+}
+
+// Returns the rendered form title, including the blank lines below it
+func (m Model) formTitle() string {
 	titleStyle := lg.NewStyle().
 		Bold(true).
-		Foreground(lg.Color("#DDDDDD")).
-		Background(lg.Color("62")).
+		Foreground(theme.TitleText).
+		Background(theme.TitleBackground).
 		Padding(0, 1).
 		Margin(0, 0, 0, 2)
 
+	if m.form.editLoc != nil {
+		return titleStyle.Render("Edit Host Configuration") + "\n\n"
+	}
+	return titleStyle.Render("Add New Host Configuration") + "\n\n"
+}
+
+// Builds the scrollable form content below the title
+// Returns the content and the line each input's label starts on
+func (m Model) formContent() (string, []int) {
 	labelStyle := lg.NewStyle().
-		Foreground(lg.Color("#DDDDDD")).
+		Foreground(theme.Text).
 		Bold(true).
 		Width(40).
 		Margin(0, 0, 0, 2)
 
 	requiredStyle := lg.NewStyle().
-		Foreground(lg.Color("#ED5679"))
+		Foreground(theme.Error)
 
 	optionalStyle := lg.NewStyle().
-		Foreground(lg.Color("#888888"))
-
-	helpRendered, availHeight := m.renderFormHelp(formKeys)
-
-	// Title is always visible at the top
-	var title string
-	title = titleStyle.Render("Add New Host Configuration") + "\n\n"
+		Foreground(theme.Muted)
 
-	// Subtract title height from available height for content
-	availHeight -= lg.Height(title)
+	formErrStyle := lg.NewStyle().
+		Foreground(theme.Error).
+		Margin(0, 0, 0, 2)
 
 	// Build form content
 	var b string
+	inputLines := make([]int, len(m.form.inputs))
+
+	// Errors that don't belong to an input are shown above the form
+	if m.form.err != nil && m.form.errInput() == -1 {
+		b += formErrStyle.Render(m.form.err.Error()) + "\n\n"
+	}
 
 	// Authentication section header
 	authHeaderStyle := lg.NewStyle().
-		Foreground(lg.Color("#00FFFF")).
+		Foreground(theme.Header).
 		Bold(true).
 		Margin(0, 0, 0, 2)
 
+	completionStyle := lg.NewStyle().
+		Foreground(theme.Muted).
+		Margin(0, 0, 0, 4)
+
 	authTypeStyle := lg.NewStyle().
-		Foreground(lg.Color("#888888")).
+		Foreground(theme.Muted).
 		Italic(true).
 		Margin(1, 0, 1, 2)
 
@@ -251,6 +631,15 @@ func (m Model) renderForm() string {
 		// Add section headers
 		if i == sshAgentInput {
 			b += authHeaderStyle.Render("Authentication (minimum one auth method required):") + "\n"
+			if m.form.errInput() == sshAgentInput {
+				b += formErrStyle.Render(m.form.err.Error()) + "\n"
+			}
+		}
+		if i == localForwardsInput {
+			b += "\n" + authHeaderStyle.Render("Port Forwarding:") + "\n"
+		}
+		if i == envInput {
+			b += "\n" + authHeaderStyle.Render("Environment:") + "\n"
 		}
 
 		// Add auth type labels with separators
@@ -263,85 +652,121 @@ func (m Model) renderForm() string {
 			b += authTypeStyle.Render("Keyring Authentication") + "\n"
 		case passwordInput:
 			b += authTypeStyle.Render("Password Authentication") + "\n"
+		case interactiveAuthInput:
+			b += authTypeStyle.Render("Keyboard-Interactive Authentication") + "\n"
 		}
 
-		label := inputLabels[i]
+		label := focusMarker(i == m.form.focusIndex) + inputLabels[i]
 		isRequired := i < userInput+1 // First 4 fields are required
 
 		var labelText string
 		if isRequired {
 			labelText = labelStyle.Render(label) + " " + requiredStyle.Render("*")
 		} else {
-			if i == identityPassphraseInput {
+			if i == agentKeyInput || i == identityPassphraseInput || i == folderInput || i == tagsInput || i == notesInput || i == jumpHostRefInput || i >= localForwardsInput {
 				labelText = labelStyle.Render(label) + " " + optionalStyle.Render("(optional)")
 			} else {
 				labelText = labelStyle.Render(label)
 			}
 		}
 
+		inputLines[i] = strings.Count(b, "\n")
 		b += labelText + "\n"
-		b += input.View() + "\n\n"
+		b += input.View() + "\n"
+		if i == m.form.errInput() && i != sshAgentInput {
+			b += formErrStyle.Render(m.form.err.Error()) + "\n"
+		} else if input.Err != nil {
+			b += formErrStyle.Render("✗ "+input.Err.Error()) + "\n"
+		}
+		if i == portInput && m.form.probe != nil {
+			b += m.form.probe.View()
+		}
+		if i == identityFileInput && m.form.keyInfo != nil {
+			b += m.form.keyInfo.View()
+		}
+		if i == m.form.focusIndex && len(m.form.completions) > 0 {
+			b += completionStyle.Render(formatPathCandidates(m.form.completions)) + "\n"
+		}
+		if m.form.keyPicker != nil && i == m.form.keyPicker.input {
+			b += m.form.keyPicker.View()
+		}
+		b += "\n"
 	}
 
-	return m.calculateVisibleFormContent(availHeight, b, title, helpRendered, m.getVisibleFormLines)
+	return b, inputLines
 }
 
-// Determines the scroll offset to keep the focused input visible
-func (m Model) calculateScrollOffset() int {
-	// Calculate the line position of the focused input
-	linesPerInput := 4
-
-	// Add extra lines for section headers
-	extraLines := 0
-	if m.form.focusIndex >= sshAgentInput {
-		extraLines += 2 // Auth header
-	}
-	if m.form.focusIndex >= identityFileInput {
-		extraLines += 2 // Identity auth type
+// Keeps the form open with its inputs intact and shows the error inside it
+// Errors tied to an input move focus to that input
+func (m Model) showFormError(err error) (tea.Model, tea.Cmd) {
+	if errors.Is(err, errDuplicateName) {
+		err = &inputError{nameInput, err}
 	}
-	if m.form.focusIndex >= keyringServiceInput {
-		extraLines += 2 // Keyring auth type
-	}
-	if m.form.focusIndex >= passwordInput {
-		extraLines += 2 // Password auth type
-	}
-
-	focusedLine := m.form.focusIndex*linesPerInput + extraLines
+	m.form.err = err
 
-	// Get available height
-	_, availHeight := m.renderFormHelp(formKeys)
+	var inputErr *inputError
+	if !errors.As(err, &inputErr) {
+		m.form.scrollOffset = 0
+		return m, nil
+	}
 
-	// Calculate scroll offset to keep focused input in view
-	// Keep some padding (show a few lines above the focused input)
-	padding := 3
+	cmd := m.form.focusInput(inputErr.input)
+	m.form.scrollOffset = m.calculateScrollOffset()
+	return m, cmd
+}
 
-	if focusedLine < m.form.scrollOffset+padding {
-		// Scroll up
-		return max(0, focusedLine-padding)
+// Moves focus to input i, blurring the others
+func (f *formModel) focusInput(i int) tea.Cmd {
+	f.focusIndex = i
+	for j := range f.inputs {
+		if j != i {
+			f.inputs[j].Blur()
+		}
 	}
+	return f.inputs[i].Focus()
+}
 
-	if focusedLine > m.form.scrollOffset+availHeight-padding {
-		// Scroll down
-		return focusedLine - availHeight + padding
+// Returns the input the form error belongs to, or -1 if it isn't tied to one
+func (f formModel) errInput() int {
+	var inputErr *inputError
+	if errors.As(f.err, &inputErr) {
+		return inputErr.input
 	}
+	return -1
+}
 
-	return m.form.scrollOffset
+// Returns the scroll offset that keeps the focused input in view
+// Line positions come from rendering the form, so section headers, errors and
+// anything else shown between inputs are accounted for
+func (m Model) calculateScrollOffset() int {
+	content, inputLines := m.formContent()
+	_, availHeight := m.renderFormHelp(formKeys)
+	availHeight -= lg.Height(m.formTitle())
+	return formScrollOffset(inputLines, len(strings.Split(content, "\n")), m.form.focusIndex, m.form.scrollOffset, availHeight)
 }
 
-// Returns the visible portion of form lines based on scroll offset
-func (m Model) getVisibleFormLines(lines []string, availHeight int) []string {
-	if len(lines) <= availHeight {
-		// Form fits on screen, no scrolling needed
-		return lines
+// Returns the offset to scroll to so the focused input, from its label down to the next
+// input's label, fits in availHeight lines with a few lines of context around it when
+// there's room. The offset only changes when the input would be out of view
+func formScrollOffset(inputLines []int, totalLines, focus, offset, availHeight int) int {
+	start := inputLines[focus]
+	end := totalLines
+	if focus+1 < len(inputLines) {
+		end = inputLines[focus+1]
 	}
+	padding := max(0, min(3, (availHeight-(end-start))/2))
 
-	start := m.form.scrollOffset
-	end := min(start+availHeight, len(lines))
-
-	if start >= len(lines) {
-		start = max(0, len(lines)-availHeight)
-		end = len(lines)
+	if start-padding < offset {
+		// Scroll up
+		offset = start - padding
+	} else if end+padding > offset+availHeight {
+		// Scroll down, but never past the input's label
+		offset = min(end+padding-availHeight, start)
 	}
+	return min(max(offset, 0), max(totalLines-availHeight, 0))
+}
 
-	return lines[start:end]
+// Returns the visible portion of form lines based on scroll offset
+func (m Model) getVisibleFormLines(lines []string, availHeight int) []string {
+	return scrollLines(lines, m.form.scrollOffset, availHeight)
 }
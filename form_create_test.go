@@ -0,0 +1,400 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFormScrollOffsetKeepsFocusVisible(t *testing.T) {
+	m := Model{form: newFormModel()}
+	content, inputLines := m.formContent()
+	totalLines := len(strings.Split(content, "\n"))
+
+	for _, height := range []int{5, 10, 20, 40} {
+		// Tab forward through the form and back again, carrying the offset like the form does
+		order := make([]int, 0, 2*len(inputLines))
+		for i := range inputLines {
+			order = append(order, i)
+		}
+		for i := len(inputLines) - 1; i >= 0; i-- {
+			order = append(order, i)
+		}
+
+		offset := 0
+		for _, focus := range order {
+			t.Run(fmt.Sprintf("height %d field %d", height, focus), func(t *testing.T) {
+				offset = formScrollOffset(inputLines, totalLines, focus, offset, height)
+
+				start := inputLines[focus]
+				end := totalLines
+				if focus+1 < len(inputLines) {
+					end = inputLines[focus+1]
+				}
+				if offset < 0 || offset > max(totalLines-height, 0) {
+					t.Fatalf("offset %d out of range for %d lines", offset, totalLines)
+				}
+				if start < offset || start >= offset+height {
+					t.Errorf("label on line %d not within lines %d-%d", start, offset, offset+height-1)
+				}
+				// The input itself sits on the line after its label
+				if height > 1 && start+1 >= offset+height {
+					t.Errorf("input on line %d not within lines %d-%d", start+1, offset, offset+height-1)
+				}
+				if end-start <= height && end > offset+height {
+					t.Errorf("field spanning lines %d-%d cut off at line %d", start, end-1, offset+height-1)
+				}
+			})
+		}
+	}
+}
+
+func TestFormScrollOffsetOnlyMovesWhenNeeded(t *testing.T) {
+	inputLines := []int{0, 3, 6, 9, 12, 15}
+	tests := []struct {
+		name                       string
+		focus, offset, availHeight int
+		want                       int
+	}{
+		{"already visible", 2, 2, 10, 2},
+		{"scrolls down to next field", 4, 0, 10, 8},
+		{"scrolls up with context", 1, 10, 10, 0},
+		{"everything fits", 5, 0, 40, 0},
+		{"never past the label", 3, 0, 2, 9},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formScrollOffset(inputLines, 18, tt.focus, tt.offset, tt.availHeight); got != tt.want {
+				t.Errorf("formScrollOffset(focus %d, offset %d, height %d) = %d, want %d", tt.focus, tt.offset, tt.availHeight, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitHostInput(t *testing.T) {
+	tests := []struct {
+		input, host, port string
+	}{
+		{"web.example.com", "web.example.com", ""},
+		{"::1", "::1", ""},
+		{"[::1]", "::1", ""},
+		{"[fe80::1]:2222", "fe80::1", "2222"},
+		{" [2001:db8::1]:22 ", "2001:db8::1", "22"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			host, port := splitHostInput(tt.input)
+			if host != tt.host || port != tt.port {
+				t.Errorf("splitHostInput(%q) = %q, %q, want %q, %q", tt.input, host, port, tt.host, tt.port)
+			}
+		})
+	}
+}
+
+func TestValidateAndCreateHostIPv6(t *testing.T) {
+	tests := []struct {
+		name, host, port string
+		wantHost         string
+		wantPort         int
+		wantErrInput     int // -1 when the host is valid
+	}{
+		{"bare literal", "::1", "", "::1", 22, -1},
+		{"bracketed literal", "[::1]", "", "::1", 22, -1},
+		{"port in the host", "[fe80::1]:2222", "", "fe80::1", 2222, -1},
+		{"same port in both", "[fe80::1]:2222", "2222", "fe80::1", 2222, -1},
+		{"conflicting ports", "[fe80::1]:2222", "22", "", 0, hostInput},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFormModel()
+			f.inputs[nameInput].SetValue("v6")
+			f.inputs[hostInput].SetValue(tt.host)
+			f.inputs[portInput].SetValue(tt.port)
+			f.inputs[userInput].SetValue("root")
+			f.inputs[sshAgentInput].SetValue("true")
+
+			h, err := validateAndCreateHost(f)
+			if tt.wantErrInput >= 0 {
+				var inputErr *inputError
+				if !errors.As(err, &inputErr) || inputErr.input != tt.wantErrInput {
+					t.Fatalf("validateAndCreateHost() error = %v, want an error on input %d", err, tt.wantErrInput)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("validateAndCreateHost() error = %v", err)
+			}
+			if h.Host != tt.wantHost || h.Port != tt.wantPort {
+				t.Errorf("got host %q port %d, want %q port %d", h.Host, h.Port, tt.wantHost, tt.wantPort)
+			}
+		})
+	}
+}
+
+func TestParseEnvList(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    map[string]string
+		wantErr bool
+	}{
+		{value: "", want: nil},
+		{value: " , ", want: nil},
+		{value: "TZ=UTC", want: map[string]string{"TZ": "UTC"}},
+		{value: " TZ = UTC , APP_ENV=prod,", want: map[string]string{"TZ": "UTC", "APP_ENV": "prod"}},
+		{value: "EMPTY=", want: map[string]string{"EMPTY": ""}},
+		{value: "URL=a=b", want: map[string]string{"URL": "a=b"}},
+		{value: "TZ=UTC,TZ=CET", want: map[string]string{"TZ": "CET"}},
+		{value: "TZ", wantErr: true},
+		{value: "=UTC", wantErr: true},
+		{value: "MY VAR=1", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseEnvList(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseEnvList(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseEnvList(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatEnvListRoundTrips(t *testing.T) {
+	env := map[string]string{"TZ": "UTC", "APP_ENV": "prod", "EMPTY": ""}
+	formatted := formatEnvList(env)
+	if want := "APP_ENV=prod, EMPTY=, TZ=UTC"; formatted != want {
+		t.Errorf("formatEnvList() = %q, want %q", formatted, want)
+	}
+	parsed, err := parseEnvList(formatted)
+	if err != nil {
+		t.Fatalf("parseEnvList(%q): %v", formatted, err)
+	}
+	if !reflect.DeepEqual(parsed, env) {
+		t.Errorf("parseEnvList(%q) = %v, want %v", formatted, parsed, env)
+	}
+}
+
+func TestNextHostName(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing []string
+		want     string
+	}{
+		{"web", []string{"web"}, "web 2"},
+		{"web", []string{"web", "web 2", "web 3"}, "web 4"},
+		{"web 2", []string{"web", "web 2"}, "web 3"},
+		{"web9", []string{"web9"}, "web10"},
+		{"web-01", []string{"web-01"}, "web-02"},
+		{"web-01", []string{"web-01", "web-02"}, "web-03"},
+		{"web-99", []string{"web-99"}, "web-100"},
+		{"10.0.0.1", []string{"10.0.0.1"}, "10.0.0.2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			existing := make(map[string]bool)
+			for _, name := range tt.existing {
+				existing[name] = true
+			}
+			if got := nextHostName(tt.name, existing); got != tt.want {
+				t.Errorf("nextHostName(%q) = %q, want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAddFormDefaults(t *testing.T) {
+	tests := []struct {
+		name         string
+		user         string
+		port         int
+		identityFile string
+		want         Host
+		wantInput    int // Input the error is tied to, -1 for none
+	}{
+		{
+			name:      "no defaults",
+			wantInput: userInput, // User is required and nothing filled it in
+		},
+		{
+			name:         "all defaults",
+			user:         "deploy",
+			port:         2222,
+			identityFile: "~/.ssh/id_ed25519",
+			want:         Host{User: "deploy", Port: 2222, IdentityFile: "~/.ssh/id_ed25519"},
+			wantInput:    -1,
+		},
+		{
+			name:      "unset port falls back to 22",
+			user:      "deploy",
+			want:      Host{User: "deploy", Port: 22},
+			wantInput: -1,
+		},
+		{
+			name:      "bad default port",
+			user:      "deploy",
+			port:      70000,
+			wantInput: portInput,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newAddFormModel(tt.user, tt.port, tt.identityFile)
+			f.inputs[nameInput].SetValue("web")
+			f.inputs[hostInput].SetValue("web.example.com")
+			f.inputs[passwordInput].SetValue("secret")
+
+			h, err := validateAndCreateHost(f)
+			if tt.wantInput >= 0 {
+				var inputErr *inputError
+				if !errors.As(err, &inputErr) || inputErr.input != tt.wantInput {
+					t.Fatalf("validateAndCreateHost() error = %v, want one on input %d", err, tt.wantInput)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("validateAndCreateHost(): %v", err)
+			}
+			if h.User != tt.want.User || h.Port != tt.want.Port || h.IdentityFile != tt.want.IdentityFile {
+				t.Errorf("host user %q port %d identity file %q, want %q %d %q",
+					h.User, h.Port, h.IdentityFile, tt.want.User, tt.want.Port, tt.want.IdentityFile)
+			}
+		})
+	}
+}
+
+func TestInteractiveAuthInput(t *testing.T) {
+	for _, interactive := range []bool{false, true} {
+		t.Run(fmt.Sprint(interactive), func(t *testing.T) {
+			original := Host{Name: "web", Host: "web", Port: 22, User: "alice", Password: "secret", InteractiveAuth: interactive}
+			h, err := validateAndCreateHost(newEditFormModel(original, hostLocation{}, ""))
+			if err != nil {
+				t.Fatalf("validateAndCreateHost(): %v", err)
+			}
+			if h.InteractiveAuth != interactive {
+				t.Errorf("InteractiveAuth = %v after editing, want %v", h.InteractiveAuth, interactive)
+			}
+		})
+	}
+
+	// Answering prompts is enough to authenticate, e.g. for hosts that only ask for an OTP
+	f := newFormModel()
+	f.inputs[nameInput].SetValue("web")
+	f.inputs[hostInput].SetValue("web")
+	f.inputs[userInput].SetValue("alice")
+	f.inputs[interactiveAuthInput].SetValue("true")
+	if _, err := validateAndCreateHost(f); err != nil {
+		t.Errorf("validateAndCreateHost() with only interactive auth: %v", err)
+	}
+}
+
+func TestKeyringInputs(t *testing.T) {
+	tests := []struct {
+		name           string
+		service        string
+		account        string
+		password       string
+		storeInKeyring bool
+		wantService    string
+		wantAccount    string
+		wantInput      int // Input the error is tied to, -1 for none
+	}{
+		{name: "both set", service: "work", account: "alice@web", wantService: "work", wantAccount: "alice@web", wantInput: -1},
+		{name: "only service", service: "work", wantInput: keyringAccountInput},
+		{name: "only account", account: "alice@web", wantInput: keyringServiceInput},
+		// A password alone is a way to log in, but the half-set keyring still can't find one
+		{name: "only service with a password", service: "work", password: "secret", wantInput: keyringAccountInput},
+		{
+			name: "stored with only a service", service: "work", password: "secret", storeInKeyring: true,
+			wantService: "work", wantAccount: "alice@web", wantInput: -1,
+		},
+		{
+			name: "stored with neither", password: "secret", storeInKeyring: true,
+			wantService: defaultKeyringService, wantAccount: "alice@web", wantInput: -1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFormModel()
+			f.inputs[nameInput].SetValue("web")
+			f.inputs[hostInput].SetValue("web.example.com")
+			f.inputs[userInput].SetValue("alice")
+			f.inputs[keyringServiceInput].SetValue(tt.service)
+			f.inputs[keyringAccountInput].SetValue(tt.account)
+			f.inputs[passwordInput].SetValue(tt.password)
+			f.inputs[storeInKeyringInput].SetValue(fmt.Sprint(tt.storeInKeyring))
+
+			h, err := validateAndCreateHost(f)
+			if tt.wantInput >= 0 {
+				var inputErr *inputError
+				if !errors.As(err, &inputErr) || inputErr.input != tt.wantInput {
+					t.Fatalf("validateAndCreateHost() error = %v, want one on input %d", err, tt.wantInput)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("validateAndCreateHost(): %v", err)
+			}
+			if h.KeyringService != tt.wantService || h.KeyringAccount != tt.wantAccount {
+				t.Errorf("keyring = %q/%q, want %q/%q", h.KeyringService, h.KeyringAccount, tt.wantService, tt.wantAccount)
+			}
+		})
+	}
+}
+
+func TestHasAuthMethod(t *testing.T) {
+	tests := []struct {
+		name string
+		host Host
+		want bool
+	}{
+		{"nothing", Host{}, false},
+		{"keyring", Host{KeyringService: "rolodex", KeyringAccount: "alice@web"}, true},
+		{"keyring service only", Host{KeyringService: "rolodex"}, false},
+		{"keyring account only", Host{KeyringAccount: "alice@web"}, false},
+		{"agent", Host{SSHAgent: true}, true},
+		{"identity file", Host{IdentityFile: "~/.ssh/id_ed25519"}, true},
+		{"password command", Host{PasswordCommand: "pass web"}, true},
+		{"prompt", Host{PromptPassword: true}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hasAuthMethod(tt.host); got != tt.want {
+				t.Errorf("hasAuthMethod(%+v) = %v, want %v", tt.host, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEditFormPort(t *testing.T) {
+	tests := []struct {
+		name      string
+		port      int
+		wantField string
+		wantPort  int
+	}{
+		{"set", 2222, "2222", 2222},
+		{"unset", 0, "", 22},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original := Host{Name: "web", Host: "web", Port: tt.port, User: "alice", SSHAgent: true}
+			f := newEditFormModel(original, hostLocation{}, "")
+			if got := f.inputs[portInput].Value(); got != tt.wantField {
+				t.Errorf("port field = %q, want %q", got, tt.wantField)
+			}
+
+			// Saving without touching the field works
+			h, err := validateAndCreateHost(f)
+			if err != nil {
+				t.Fatalf("validateAndCreateHost(): %v", err)
+			}
+			if h.Port != tt.wantPort {
+				t.Errorf("saved port = %d, want %d", h.Port, tt.wantPort)
+			}
+		})
+	}
+}
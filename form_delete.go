@@ -1,29 +1,28 @@
 package main
 
 import (
-	"encoding/json"
 	"fmt"
-	"os"
+	"strings"
 
 	"github.com/charmbracelet/bubbles/key"
 	tea "github.com/charmbracelet/bubbletea"
 	lg "github.com/charmbracelet/lipgloss"
-	"golang.org/x/term"
 )
 
 // Key map for delete confirmation view
 type deleteKeyMap struct {
 	Confirm key.Binding
 	Cancel  key.Binding
+	Scroll  key.Binding
 }
 
 func (k deleteKeyMap) ShortHelp() []key.Binding {
-	return []key.Binding{k.Confirm, k.Cancel}
+	return []key.Binding{k.Confirm, k.Cancel, k.Scroll}
 }
 
 func (k deleteKeyMap) FullHelp() [][]key.Binding {
 	return [][]key.Binding{
-		{k.Confirm, k.Cancel},
+		{k.Confirm, k.Cancel, k.Scroll},
 	}
 }
 
@@ -36,106 +35,168 @@ var deleteKeys = deleteKeyMap{
 		key.WithKeys("n", "N", "esc"),
 		key.WithHelp("n/esc", "cancel"),
 	),
+	Scroll: key.NewBinding(
+		key.WithKeys("up", "down"),
+		key.WithHelp("↑/↓", "scroll"),
+	),
 }
 
 func (m Model) updateDeleteConfirm(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	// Leaving the view resets scrolling, other views share getVisibleDeleteLines
 	switch msg.String() {
-	case "y", "Y":
-		// Confirm deletion
-		if err := deleteHostFromConfig(m.configPath, m.hostToDeleteIndex); err != nil {
-			m.err = fmt.Errorf("failed to delete host: %w", err)
-			m.showErr = true
-			m.view = listView
-			m.hostToDelete = nil
-			return m, nil
-		}
+	case "up", "down":
+	default:
+		m.deleteScrollOffset = 0
+	}
 
-		// Reload config
-		data, err := os.ReadFile(m.configPath)
-		if err != nil {
-			m.err = fmt.Errorf("failed to reload config: %w", err)
-			m.showErr = true
-			m.view = listView
-			m.hostToDelete = nil
-			return m, nil
-		}
+	switch msg.String() {
+	case "up":
+		m.deleteScrollOffset = max(0, m.deleteScrollOffset-1)
+		return m, nil
 
-		var config Configuration
-		if err := json.Unmarshal(data, &config); err != nil {
-			m.err = fmt.Errorf("failed to parse reloaded config: %w", err)
-			m.showErr = true
-			m.view = listView
-			m.hostToDelete = nil
-			return m, nil
-		}
+	case "down":
+		m.deleteScrollOffset = min(m.deleteScrollOffset+1, strings.Count(m.deleteConfirmBody(), "\n"))
+		return m, nil
 
-		// Update model with new hosts and return to list
-		m.hosts = config.Hosts
-		m.list = buildList(m.hosts)
-		m.view = listView
-		m.hostToDelete = nil
-		// Trigger window size update to refresh list
-		return m, func() tea.Msg {
-			w, h, _ := term.GetSize(int(os.Stdout.Fd()))
-			return tea.WindowSizeMsg{Width: w, Height: h}
+	case "y", "Y":
+		// Confirm deletion
+		locs := m.hostsToDelete
+		if len(locs) == 0 {
+			locs = []hostLocation{m.hostToDeleteLoc}
 		}
+		m, ok := m.deleteHosts(locs)
+		if ok && len(locs) > 1 {
+			return m, m.list.NewStatusMessage(fmt.Sprintf("Deleted %d hosts", len(locs)))
+		}
+		return m, nil
 
 	case "n", "N", "esc":
-		// Cancel deletion
+		// Cancel deletion, keeping any marks
 		m.view = listView
 		m.hostToDelete = nil
+		m.hostsToDelete = nil
 		return m, nil
 	}
 
 	return m, nil
 }
 
+// Deletes the hosts at locs, taking an undo snapshot first, and returns to the list
+// Returns whether they were deleted, failures are shown in the error view
+func (m Model) deleteHosts(locs []hostLocation) (Model, bool) {
+	change := fmt.Sprintf("deleting %d hosts", len(locs))
+	if len(locs) == 1 {
+		change = "deleting " + m.hostAt(locs[0]).Name
+	}
+	snapshot := takeSnapshot(m.configPath, change)
+	err := deleteHostsFromConfig(m.configPath, locs)
+
+	// Locations shift after any delete, so marks no longer apply
+	m.marked = nil
+	m.hostsToDelete = nil
+	m.hostToDelete = nil
+	m.view = listView
+	if err != nil {
+		m.err = fmt.Errorf("failed to delete host: %w", err)
+		m.showErr = true
+		return m, false
+	}
+
+	m.undo = snapshot
+
+	// Reload config
+	config, err := readConfig(m.configPath)
+	if err != nil {
+		m.err = fmt.Errorf("failed to reload config: %w", err)
+		m.showErr = true
+		return m, false
+	}
+
+	// Update model with new hosts and return to list
+	m.hosts = config.Hosts
+	m.folders = config.Folders
+	m.list = m.buildList()
+	return m, true
+}
+
+// Deletes the marked hosts, or the selected host if none are marked, without asking
+// Used when confirm_delete is off, undo is the way back
+func (m Model) deleteWithoutConfirm() (tea.Model, tea.Cmd) {
+	locs := m.markedLocations()
+	if len(locs) == 0 {
+		it, ok := m.list.SelectedItem().(Item)
+		if !ok {
+			return m, nil
+		}
+		locs = []hostLocation{it.loc}
+	}
+	name := fmt.Sprintf("%d hosts", len(locs))
+	if len(locs) == 1 {
+		name = m.hostAt(locs[0]).Name
+	}
+	m, ok := m.deleteHosts(locs)
+	if !ok {
+		return m, nil
+	}
+	return m, m.list.NewStatusMessage(fmt.Sprintf("Deleted %s, press %s to undo", name, undoChange.Help().Key))
+}
+
 func (m Model) renderDeleteConfirm() string {
 	titleStyle := lg.NewStyle().
 		Bold(true).
-		Foreground(lg.Color("#DDDDDD")).
-		Background(lg.Color("62")).
+		Foreground(theme.TitleText).
+		Background(theme.TitleBackground).
 		Padding(0, 1).
 		Margin(0, 0, 0, 2)
 
+	helpRendered, availHeight := m.renderFormHelp(deleteKeys)
+
+	var title string
+	if len(m.hostsToDelete) > 0 {
+		title = titleStyle.Render("Delete Hosts") + "\n\n"
+	} else {
+		title = titleStyle.Render("Delete Host") + "\n\n"
+	}
+	availHeight -= lg.Height(title)
+
+	return m.calculateVisibleFormContent(availHeight, m.deleteConfirmBody(), title, helpRendered, m.getVisibleDeleteLines)
+}
+
+// Returns the scrollable part of the delete view
+func (m Model) deleteConfirmBody() string {
 	hostDescriptionStyle := lg.NewStyle().
-		Foreground(lg.Color("#DDDDDD")).
+		Foreground(theme.Text).
 		Padding(0, 1)
 
 	hostStyle := lg.NewStyle().
-		Foreground(lg.Color("#EE6FF8")).
+		Foreground(theme.Highlight).
 		Bold(true).
 		Margin(0, 2)
 
 	infoStyle := lg.NewStyle().
-		Foreground(lg.Color("#ED5679")).
+		Foreground(theme.Error).
 		Padding(0, 2)
 
-	helpRendered, availHeight := m.renderFormHelp(deleteKeys)
-
-	var title string
-	title = titleStyle.Render("Delete Host") + "\n\n"
-	availHeight -= lg.Height(title)
 	var b string
-
-	if m.hostToDelete != nil {
+	if len(m.hostsToDelete) > 0 {
+		b += infoStyle.Render(fmt.Sprintf("Are you sure you want to delete these %d hosts?", len(m.hostsToDelete))) + "\n\n"
+		for _, loc := range m.hostsToDelete {
+			h := m.hostAt(loc)
+			b += hostStyle.Render(h.Name) + hostDescriptionStyle.Render(h.User+"@"+h.Host) + "\n"
+		}
+		b += "\n" + infoStyle.Render("Press u in the host list to undo.") + "\n\n"
+	} else if m.hostToDelete != nil {
 		b += infoStyle.Render("Are you sure you want to delete this host?") + "\n\n"
 		b += hostStyle.Render("Name") + hostDescriptionStyle.Render(m.hostToDelete.Name) + "\n"
 		b += hostStyle.Render("Host") + hostDescriptionStyle.Render(m.hostToDelete.Host) + "\n"
 		b += hostStyle.Render("User") + hostDescriptionStyle.Render(m.hostToDelete.User) + "\n\n"
-		b += infoStyle.Render("This action cannot be undone.") + "\n\n"
+		b += infoStyle.Render("Press u in the host list to undo.") + "\n\n"
 	}
-
-	return m.calculateVisibleFormContent(availHeight, b, title, helpRendered, m.getVisibleDeleteLines)
+	return b
 }
 
 // Returns the visible portion of delete form lines
-// TODO: Does not resize properly, but the delete form is small so not a big deal for now
+// The confirmation prompt comes first, so it stays visible until the user scrolls down
 func (m Model) getVisibleDeleteLines(lines []string, availHeight int) []string {
-	if len(lines) <= availHeight {
-		return lines
-	}
-
-	end := min(availHeight, len(lines))
-	return lines[0:end]
+	return scrollLines(lines, m.deleteScrollOffset, availHeight)
 }
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestRenderDeleteConfirmShortWindow(t *testing.T) {
+	single := Model{width: 80, hostToDelete: &Host{Name: "web", Host: "web.example.com", User: "deploy"}}
+	several := Model{
+		width:         80,
+		hosts:         []Host{{Name: "web", Host: "web.example.com", User: "deploy"}, {Name: "db", Host: "db.example.com", User: "deploy"}},
+		hostsToDelete: []hostLocation{{folder: -1, index: 0}, {folder: -1, index: 1}},
+	}
+
+	tests := []struct {
+		name   string
+		m      Model
+		prompt string
+	}{
+		{"one host", single, "Are you sure you want to delete this host?"},
+		{"several hosts", several, "Are you sure you want to delete these 2 hosts?"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name+" at height 5", func(t *testing.T) {
+			m := tt.m
+			m.height = 5
+			if view := m.renderDeleteConfirm(); !strings.Contains(view, tt.prompt) {
+				t.Errorf("prompt %q missing from:\n%s", tt.prompt, view)
+			}
+		})
+		// Windows too short for the help used to slice with a negative height
+		for height := 0; height < 5; height++ {
+			t.Run(fmt.Sprintf("%s at height %d", tt.name, height), func(t *testing.T) {
+				m := tt.m
+				m.height = height
+				m.renderDeleteConfirm()
+			})
+		}
+	}
+}
+
+func TestDeleteWithoutConfirm(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	configuration := Configuration{
+		Hosts:    []Host{{Name: "web", Host: "web", Port: 22}, {Name: "db", Host: "db", Port: 22}},
+		Settings: Settings{ConfirmDelete: new(bool)},
+	}
+	if err := writeConfig(configPath, configuration); err != nil {
+		t.Fatal(err)
+	}
+	m := initialModel(&configuration, configPath)
+	selectHostNamed(&m.list, "db")
+
+	model, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("d")})
+	m = model.(Model)
+	if m.view != listView {
+		t.Fatalf("view = %v after d, want the list without a confirmation", m.view)
+	}
+	config, err := readConfig(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(config.Hosts) != 1 || config.Hosts[0].Name != "web" {
+		t.Fatalf("hosts after deleting db = %v, want only web", config.Hosts)
+	}
+
+	// Deleting at once relies on undo to bring the host back
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("u")})
+	if config, err = readConfig(configPath); err != nil {
+		t.Fatal(err)
+	}
+	if len(config.Hosts) != 2 {
+		t.Errorf("hosts after undo = %v, want web and db back", config.Hosts)
+	}
+}
+
+func TestConfirmDeleteSetting(t *testing.T) {
+	field := settingFieldByLabel(t, "Confirm Delete")
+	for _, value := range []string{"false", "true"} {
+		t.Run(value, func(t *testing.T) {
+			var s Settings
+			if err := field.set(&s, value); err != nil {
+				t.Fatal(err)
+			}
+			if got := field.get(s); got != value {
+				t.Errorf("get() = %q after setting %q", got, value)
+			}
+			// Asking is the default, so only turning it off is written out
+			data, err := json.Marshal(s)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if written := strings.Contains(string(data), "confirm_delete"); written != (value == "false") {
+				t.Errorf("config written as %s after setting %q", data, value)
+			}
+		})
+	}
+}
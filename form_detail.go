@@ -0,0 +1,350 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	lg "github.com/charmbracelet/lipgloss"
+	"github.com/nathanlytang/rolodex/internal/ssh"
+)
+
+// Key map for the host detail view
+type detailKeyMap struct {
+	Scroll  key.Binding
+	Refresh key.Binding
+	Back    key.Binding
+}
+
+func (k detailKeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.Scroll, k.Refresh, k.Back}
+}
+
+func (k detailKeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{
+		{k.Scroll, k.Refresh, k.Back},
+	}
+}
+
+var detailKeys = detailKeyMap{
+	Scroll: key.NewBinding(
+		key.WithKeys("up", "down"),
+		key.WithHelp("↑/↓", "scroll"),
+	),
+	Refresh: key.NewBinding(
+		key.WithKeys("r"),
+		key.WithHelp("r", "check reachability"),
+	),
+	Back: key.NewBinding(
+		key.WithKeys("esc", "q", "i"),
+		key.WithHelp("esc", "back to list"),
+	),
+}
+
+// Shown instead of passwords and passphrases
+const maskedSecret = "••••••••"
+
+// Result of the detail view's reachability check, delivered back to Update
+type detailPingMsg struct {
+	id      int // Matches Model.detailPing.id unless the check was since restarted
+	latency time.Duration
+	err     error
+}
+
+// State of the detail view's reachability check
+type detailPingState struct {
+	id      int
+	address string
+	running bool
+	latency time.Duration
+	err     error
+}
+
+// Returns a command that times a TCP connection to address without authenticating
+func pingHost(id int, address string) tea.Cmd {
+	return func() tea.Msg {
+		start := time.Now()
+		err := ssh.CheckReachable(address, healthCheckTimeout)
+		return detailPingMsg{id: id, latency: time.Since(start), err: err}
+	}
+}
+
+// Shows every configured field of a host without opening the edit form
+// and checks whether its SSH port is reachable
+func (m Model) openDetail(h Host) (tea.Model, tea.Cmd) {
+	m.detailHost = &h
+	m.detailOffset = 0
+	m.detailPing = nil
+	m.view = detailView
+	return m.startDetailPing()
+}
+
+// Starts a reachability check of the host in the detail view
+// Hosts behind a jump host or proxy aren't checked since they can't be dialled directly
+func (m Model) startDetailPing() (tea.Model, tea.Cmd) {
+	h := m.detailHost
+	if h == nil || h.jumpHost(m.defaultJumpHost) != "" || h.proxyURL(m.defaultProxy) != "" {
+		return m, nil
+	}
+	id := 1
+	if m.detailPing != nil {
+		id = m.detailPing.id + 1
+	}
+	address := ssh.Address(h.Host, h.Port)
+	m.detailPing = &detailPingState{id: id, address: address, running: true}
+	return m, pingHost(id, address)
+}
+
+// Records a reachability check result if it's still the latest one
+func (m Model) finishDetailPing(msg detailPingMsg) (tea.Model, tea.Cmd) {
+	p := m.detailPing
+	if m.view != detailView || p == nil || p.id != msg.id {
+		return m, nil
+	}
+	p.running = false
+	p.latency = msg.latency
+	p.err = msg.err
+	return m, nil
+}
+
+// Renders the reachability status shown under the detail view title
+func (p *detailPingState) View() string {
+	pendingStyle := lg.NewStyle().
+		Foreground(theme.Muted).
+		Margin(0, 0, 0, 2)
+
+	okStyle := lg.NewStyle().
+		Foreground(theme.Success).
+		Margin(0, 0, 0, 2)
+
+	failStyle := lg.NewStyle().
+		Foreground(theme.Error).
+		Margin(0, 0, 0, 2)
+
+	switch {
+	case p.running:
+		return pendingStyle.Render("Checking "+p.address+"...") + "\n"
+	case p.err != nil:
+		return failStyle.Render("✗ unreachable: "+p.err.Error()) + "\n"
+	default:
+		return okStyle.Render(fmt.Sprintf("✓ reachable (%dms)", p.latency.Milliseconds())) + "\n"
+	}
+}
+
+func (m Model) updateDetail(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, detailKeys.Refresh):
+		return m.startDetailPing()
+	case msg.String() == "up":
+		if m.detailOffset > 0 {
+			m.detailOffset--
+		}
+	case msg.String() == "down":
+		m.detailOffset = min(m.detailOffset+1, strings.Count(m.detailBody(), "\n"))
+	case key.Matches(msg, detailKeys.Back):
+		m.detailHost = nil
+		m.detailPing = nil
+		m.view = listView
+	}
+	return m, nil
+}
+
+// A labelled line of the detail view
+type detailRow struct {
+	label string
+	value string
+}
+
+// A titled group of rows in the detail view, untitled for the first
+type detailSection struct {
+	header string
+	rows   []detailRow
+}
+
+// Returns the host's configured fields grouped into sections, leaving out unset ones
+func detailSections(h Host, defaultJumpHost, defaultProxy string) []detailSection {
+	var general, auth, session []detailRow
+	add := func(rows *[]detailRow, label, value string) {
+		if value != "" {
+			*rows = append(*rows, detailRow{label, value})
+		}
+	}
+	yes := func(b bool) string {
+		if b {
+			return "yes"
+		}
+		return ""
+	}
+	masked := func(secret string) string {
+		if secret == "" {
+			return ""
+		}
+		return maskedSecret
+	}
+
+	add(&general, "Name", h.Name)
+	add(&general, "Disabled", yes(h.Disabled))
+	add(&general, "Host", h.Host)
+	add(&general, "Fallback Addresses", strings.Join(h.Addresses, ", "))
+	if jump := h.jumpHost(defaultJumpHost); jump != "" && h.JumpHostRef == "" {
+		add(&general, "Jump Host", jump+" (default)")
+	} else {
+		add(&general, "Jump Host", jump)
+	}
+	if proxy := h.proxyURL(defaultProxy); proxy != "" && h.Proxy == "" {
+		add(&general, "Proxy", redactProxy(proxy)+" (default)")
+	} else {
+		add(&general, "Proxy", redactProxy(proxy))
+	}
+	add(&general, "Port", strconv.Itoa(h.Port))
+	add(&general, "User", h.User)
+	if len(h.Tags) > 0 {
+		add(&general, "Tags", Item{host: h}.tagsText())
+	}
+	if !h.LastConnected.IsZero() {
+		add(&general, "Last Connected", h.LastConnected.Local().Format("2006-01-02 15:04")+" ("+relativeTime(h.LastConnected)+")")
+	}
+	if h.ConnectCount > 0 {
+		add(&general, "Connections", fmt.Sprintf("%d (%s total)", h.ConnectCount, formatDuration(h.TotalDuration)))
+	}
+
+	add(&auth, "SSH Agent", yes(h.SSHAgent))
+	add(&auth, "Agent Key", h.AgentKey)
+	if h.IdentityFile != "" {
+		path := h.IdentityFile
+		if resolved, err := ssh.ResolveIdentityFile(h.IdentityFile); err == nil && resolved != h.IdentityFile {
+			path += " → " + resolved
+		}
+		add(&auth, "Identity File", path)
+	}
+	add(&auth, "Passphrase", masked(h.IdentityPassphrase))
+	add(&auth, "Certificate", h.CertificateFile)
+	add(&auth, "Add Key to Agent", yes(h.AddKeysToAgent))
+	if h.KeyringService != "" || h.KeyringAccount != "" {
+		add(&auth, "Keyring", h.KeyringService+"/"+h.KeyringAccount)
+	}
+	add(&auth, "Password", masked(h.Password))
+	add(&auth, "Password Command", h.PasswordCommand)
+	add(&auth, "Prompt Password", yes(h.PromptPassword))
+	add(&auth, "Interactive Auth", yes(h.InteractiveAuth))
+	if len(auth) == 0 {
+		add(&auth, "Methods", "none configured")
+	}
+	add(&auth, "Single Auth Method", yes(h.SingleAuthMethod))
+
+	if h.ConnectTimeout > 0 {
+		add(&session, "Connect Timeout", fmt.Sprintf("%ds", h.ConnectTimeout))
+	}
+	if h.KeepAliveInterval != nil {
+		add(&session, "Keepalive", fmt.Sprintf("%ds", *h.KeepAliveInterval))
+	}
+	add(&session, "Local Forwards", strings.Join(h.LocalForwards, ", "))
+	add(&session, "Remote Forwards", strings.Join(h.RemoteForwards, ", "))
+	add(&session, "Forward Agent", yes(h.ForwardAgent))
+	add(&session, "Forward X11", yes(h.ForwardX11))
+	add(&session, "Confirm", yes(h.Confirm))
+	add(&session, "Acknowledge Banner", yes(h.AcknowledgeBanner))
+	add(&session, "Quiet", yes(h.Quiet))
+	add(&session, "Host Key Algorithms", strings.Join(h.HostKeyAlgorithms, ", "))
+	add(&session, "Ciphers", strings.Join(h.Ciphers, ", "))
+	add(&session, "Environment", formatEnvList(h.Env))
+	for _, name := range slices.Sorted(maps.Keys(h.Options)) {
+		add(&session, name, h.Options[name])
+	}
+
+	return []detailSection{
+		{"", general},
+		{"Authentication", auth},
+		{"Session", session},
+	}
+}
+
+func (m Model) renderDetail() string {
+	titleStyle := lg.NewStyle().
+		Bold(true).
+		Foreground(theme.TitleText).
+		Background(theme.TitleBackground).
+		Padding(0, 1).
+		Margin(0, 0, 0, 2)
+
+	helpRendered, availHeight := m.renderFormHelp(detailKeys)
+
+	var title string
+	title = titleStyle.Render("Host Info") + "\n\n"
+	availHeight -= lg.Height(title)
+
+	return m.calculateVisibleFormContent(availHeight, m.detailBody(), title, helpRendered, m.getVisibleDetailLines)
+}
+
+// Returns the scrollable part of the detail view
+func (m Model) detailBody() string {
+	headerStyle := lg.NewStyle().
+		Foreground(theme.Header).
+		Bold(true).
+		Margin(0, 0, 0, 2)
+
+	hostStyle := lg.NewStyle().
+		Foreground(theme.Highlight).
+		Bold(true).
+		Margin(0, 2)
+
+	hostDescriptionStyle := lg.NewStyle().
+		Foreground(theme.Text).
+		Padding(0, 1)
+
+	noteStyle := lg.NewStyle().
+		Foreground(theme.Text).
+		Margin(0, 2).
+		Width(max(m.width-8, 20))
+
+	var b string
+
+	if h := m.detailHost; h != nil {
+		switch {
+		case m.detailPing != nil:
+			b += m.detailPing.View() + "\n"
+		case h.jumpHost(m.defaultJumpHost) != "":
+			b += hostDescriptionStyle.Render("Reached through "+h.jumpHost(m.defaultJumpHost)+", not checked") + "\n\n"
+		case h.proxyURL(m.defaultProxy) != "":
+			b += hostDescriptionStyle.Render("Reached through a proxy, not checked") + "\n\n"
+		}
+
+		sections := detailSections(*h, m.defaultJumpHost, m.defaultProxy)
+		labelWidth := 0
+		for _, s := range sections {
+			for _, r := range s.rows {
+				labelWidth = max(labelWidth, lg.Width(r.label))
+			}
+		}
+		labelStyle := hostStyle.Width(labelWidth + 4)
+
+		for _, s := range sections {
+			if len(s.rows) == 0 {
+				continue
+			}
+			if s.header != "" {
+				b += headerStyle.Render(s.header+":") + "\n"
+			}
+			for _, r := range s.rows {
+				b += labelStyle.Render(r.label) + hostDescriptionStyle.Render(r.value) + "\n"
+			}
+			b += "\n"
+		}
+
+		if h.Notes != "" {
+			b += headerStyle.Render("Notes:") + "\n"
+			b += noteStyle.Render(h.Notes) + "\n"
+		}
+	}
+	return b
+}
+
+// Returns the visible portion of the detail view
+func (m Model) getVisibleDetailLines(lines []string, availHeight int) []string {
+	return scrollLines(lines, m.detailOffset, availHeight)
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	lg "github.com/charmbracelet/lipgloss"
+)
+
+// Key map for the ssh_config export view
+type exportKeyMap struct {
+	Export key.Binding
+	Cancel key.Binding
+}
+
+func (k exportKeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.Export, k.Cancel}
+}
+
+func (k exportKeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{
+		{k.Export, k.Cancel},
+	}
+}
+
+var exportKeys = exportKeyMap{
+	Export: key.NewBinding(
+		key.WithKeys("enter"),
+		key.WithHelp("⏎", "export"),
+	),
+	Cancel: key.NewBinding(
+		key.WithKeys("esc"),
+		key.WithHelp("esc", "cancel"),
+	),
+}
+
+// Shows the export view with the default output path
+func (m Model) openExport() (tea.Model, tea.Cmd) {
+	t := textinput.New()
+	t.Prompt = "> "
+	t.PromptStyle = lg.NewStyle().Foreground(theme.Accent).Margin(0, 0, 0, 2)
+	t.CharLimit = 256
+	t.SetValue(defaultExportPath)
+	t.Focus()
+
+	m.exportPath = t
+	m.exportErr = nil
+	m.view = exportView
+	return m, textinput.Blink
+}
+
+func (m Model) updateExport(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "enter":
+		path, err := exportSSHConfigFile(m.exportPath.Value(), expandHosts(m.allHosts()))
+		if err != nil {
+			m.exportErr = err
+			return m, nil
+		}
+		m.view = listView
+		return m, m.list.NewStatusMessage("Exported hosts to " + path)
+
+	case "esc":
+		m.view = listView
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.exportPath, cmd = m.exportPath.Update(msg)
+	return m, cmd
+}
+
+func (m Model) renderExport() string {
+	titleStyle := lg.NewStyle().
+		Bold(true).
+		Foreground(theme.TitleText).
+		Background(theme.TitleBackground).
+		Padding(0, 1).
+		Margin(0, 0, 0, 2)
+
+	labelStyle := lg.NewStyle().
+		Foreground(theme.Text).
+		Bold(true).
+		Margin(0, 0, 0, 2)
+
+	hintStyle := lg.NewStyle().
+		Foreground(theme.Muted).
+		Margin(0, 0, 0, 2)
+
+	errStyle := lg.NewStyle().
+		Foreground(theme.Error).
+		Margin(0, 0, 0, 2)
+
+	helpRendered, availHeight := m.renderFormHelp(exportKeys)
+
+	var title string
+	title = titleStyle.Render("Export to ssh_config") + "\n\n"
+	availHeight -= lg.Height(title)
+	var b string
+
+	b += labelStyle.Render("Output file") + "\n"
+	b += m.exportPath.View() + "\n"
+	if m.exportErr != nil {
+		b += errStyle.Render(m.exportErr.Error()) + "\n"
+	}
+	b += "\n" + hintStyle.Render("Add \"Include rolodex_config\" to ~/.ssh/config to use these hosts with ssh and scp.") + "\n"
+
+	return m.calculateVisibleFormContent(availHeight, b, title, helpRendered, m.getVisibleDeleteLines)
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	lg "github.com/charmbracelet/lipgloss"
+	"github.com/nathanlytang/rolodex/internal/ssh"
+)
+
+// Key map for unknown host key confirmation view
+type hostKeyKeyMap struct {
+	Accept key.Binding
+	Reject key.Binding
+}
+
+func (k hostKeyKeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.Accept, k.Reject}
+}
+
+func (k hostKeyKeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{
+		{k.Accept, k.Reject},
+	}
+}
+
+var hostKeyKeys = hostKeyKeyMap{
+	Accept: key.NewBinding(
+		key.WithKeys("y", "Y"),
+		key.WithHelp("y", "trust and connect"),
+	),
+	Reject: key.NewBinding(
+		key.WithKeys("n", "N", "esc"),
+		key.WithHelp("n/esc", "reject"),
+	),
+}
+
+func (m Model) updateHostKeyConfirm(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "y", "Y":
+		// Trust the key and reconnect
+		if m.unknownHostKey != nil && m.unknownHostKeyHost != nil {
+			e := m.unknownHostKey
+			if err := ssh.AddKnownHost(e.KnownHostsFile, e.Address, e.Key); err != nil {
+				m.err = fmt.Errorf("failed to trust host key: %w", err)
+				m.showErr = true
+				m.view = listView
+				m.unknownHostKey = nil
+				m.unknownHostKeyHost = nil
+				return m, nil
+			}
+			h := m.unknownHostKeyHost
+			m.unknownHostKey = nil
+			m.unknownHostKeyHost = nil
+			return m.connect(h)
+		}
+		m.view = listView
+		return m, nil
+
+	case "n", "N", "esc":
+		// Reject the key and return to list
+		m.view = listView
+		m.unknownHostKey = nil
+		m.unknownHostKeyHost = nil
+		return m, nil
+	}
+
+	return m, nil
+}
+
+func (m Model) renderHostKeyConfirm() string {
+	titleStyle := lg.NewStyle().
+		Bold(true).
+		Foreground(theme.TitleText).
+		Background(theme.TitleBackground).
+		Padding(0, 1).
+		Margin(0, 0, 0, 2)
+
+	valueStyle := lg.NewStyle().
+		Foreground(theme.Text).
+		Padding(0, 1)
+
+	labelStyle := lg.NewStyle().
+		Foreground(theme.Highlight).
+		Bold(true).
+		Width(12).
+		Margin(0, 2)
+
+	infoStyle := lg.NewStyle().
+		Foreground(theme.Error).
+		Padding(0, 2)
+
+	helpRendered, availHeight := m.renderFormHelp(hostKeyKeys)
+
+	var title string
+	title = titleStyle.Render("Unknown Host Key") + "\n\n"
+	availHeight -= lg.Height(title)
+	var b string
+
+	if m.unknownHostKey != nil {
+		e := m.unknownHostKey
+		b += infoStyle.Render("The authenticity of this host can't be established.") + "\n\n"
+		b += labelStyle.Render("Host") + valueStyle.Render(e.Address) + "\n"
+		b += labelStyle.Render("Key type") + valueStyle.Render(e.Key.Type()) + "\n"
+		b += labelStyle.Render("Fingerprint") + valueStyle.Render(e.Fingerprint()) + "\n\n"
+		b += infoStyle.Render("Accepting will add this key to "+e.KnownHostsFile+".") + "\n\n"
+	}
+
+	return m.calculateVisibleFormContent(availHeight, b, title, helpRendered, m.getVisibleDeleteLines)
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	lg "github.com/charmbracelet/lipgloss"
+	"github.com/nathanlytang/rolodex/internal/logger"
+)
+
+// Key map for the one-off identity file prompt
+type identityPromptKeyMap struct {
+	Complete key.Binding
+	Connect  key.Binding
+	Cancel   key.Binding
+}
+
+func (k identityPromptKeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.Complete, k.Connect, k.Cancel}
+}
+
+func (k identityPromptKeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{
+		{k.Complete, k.Connect, k.Cancel},
+	}
+}
+
+var identityPromptKeys = identityPromptKeyMap{
+	Complete: key.NewBinding(
+		key.WithKeys("tab"),
+		key.WithHelp("tab", "complete path"),
+	),
+	Connect: key.NewBinding(
+		key.WithKeys("enter"),
+		key.WithHelp("⏎", "connect"),
+	),
+	Cancel: key.NewBinding(
+		key.WithKeys("esc"),
+		key.WithHelp("esc", "cancel"),
+	),
+}
+
+// Asks for a key file to connect to h with instead of its saved authentication
+func (m Model) openIdentityPrompt(h Host) (tea.Model, tea.Cmd) {
+	t := textinput.New()
+	t.Prompt = "> "
+	t.PromptStyle = lg.NewStyle().Foreground(theme.Accent).Margin(0, 0, 0, 2)
+	t.Placeholder = "~/.ssh/id_ed25519"
+	t.CharLimit = 256
+	t.Focus()
+
+	m.identityPrompt = t
+	m.identityPromptHost = &h
+	m.identityCandidates = nil
+	m.view = identityPromptView
+	return m, textinput.Blink
+}
+
+// Returns a copy of h that only authenticates with the key at path
+func withOnlyIdentityFile(h Host, path string) Host {
+	h.SSHAgent = false
+	h.AgentKey = ""
+	h.IdentityFile = path
+	h.IdentityPassphrase = ""
+	h.AddKeysToAgent = false
+	h.KeyringService = ""
+	h.KeyringAccount = ""
+	h.Password = ""
+	h.PasswordCommand = ""
+	h.PromptPassword = false
+	h.InteractiveAuth = false
+	return h
+}
+
+func (m Model) updateIdentityPrompt(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "tab":
+		value, candidates := completePath(m.identityPrompt.Value())
+		m.identityPrompt.SetValue(value)
+		m.identityPrompt.CursorEnd()
+		m.identityCandidates = candidates
+		return m, nil
+
+	case "enter":
+		path := strings.TrimSpace(m.identityPrompt.Value())
+		if path == "" {
+			return m, nil
+		}
+
+		// The key only applies to this connection and is never saved
+		h := withOnlyIdentityFile(*m.identityPromptHost, path)
+		logger.Printf("Connecting to %s with one-off identity file %s", h.Name, path)
+		m.identityPromptHost = nil
+		m.identityCandidates = nil
+		m.browse = false
+		m.tunnelMode = false
+		if m.needsConnectConfirm(h) {
+			m.hostToConfirm = &h
+			m.view = connectConfirmView
+			return m, nil
+		}
+		return m.connect(&h)
+
+	case "esc":
+		m.identityPromptHost = nil
+		m.identityCandidates = nil
+		m.view = listView
+		return m, nil
+	}
+
+	m.identityCandidates = nil
+	var cmd tea.Cmd
+	m.identityPrompt, cmd = m.identityPrompt.Update(msg)
+	return m, cmd
+}
+
+func (m Model) renderIdentityPrompt() string {
+	titleStyle := lg.NewStyle().
+		Bold(true).
+		Foreground(theme.TitleText).
+		Background(theme.TitleBackground).
+		Padding(0, 1).
+		Margin(0, 0, 0, 2)
+
+	labelStyle := lg.NewStyle().
+		Foreground(theme.Text).
+		Bold(true).
+		Margin(0, 0, 0, 2)
+
+	hintStyle := lg.NewStyle().
+		Foreground(theme.Muted).
+		Margin(0, 0, 0, 2)
+
+	helpRendered, availHeight := m.renderFormHelp(identityPromptKeys)
+
+	var title string
+	title = titleStyle.Render("Connect With Key") + "\n\n"
+	availHeight -= lg.Height(title)
+	var b string
+
+	if m.identityPromptHost != nil {
+		h := m.identityPromptHost
+		b += labelStyle.Render("Identity file for "+h.User+"@"+h.Host) + "\n"
+		b += m.identityPrompt.View() + "\n"
+		if len(m.identityCandidates) > 0 {
+			b += hintStyle.Render(formatPathCandidates(m.identityCandidates)) + "\n"
+		}
+		b += "\n" + hintStyle.Render("Only this key is offered. The saved host isn't changed.") + "\n"
+	}
+
+	return m.calculateVisibleFormContent(availHeight, b, title, helpRendered, m.getVisibleDeleteLines)
+}
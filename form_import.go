@@ -0,0 +1,343 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"slices"
+
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	lg "github.com/charmbracelet/lipgloss"
+)
+
+// Key map for choosing where to import PuTTY sessions from
+type importPathKeyMap struct {
+	Complete key.Binding
+	Load     key.Binding
+	Cancel   key.Binding
+}
+
+func (k importPathKeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.Complete, k.Load, k.Cancel}
+}
+
+func (k importPathKeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{
+		{k.Complete, k.Load, k.Cancel},
+	}
+}
+
+var importPathKeys = importPathKeyMap{
+	Complete: key.NewBinding(
+		key.WithKeys("tab"),
+		key.WithHelp("tab", "complete path"),
+	),
+	Load: key.NewBinding(
+		key.WithKeys("enter"),
+		key.WithHelp("⏎", "read sessions"),
+	),
+	Cancel: key.NewBinding(
+		key.WithKeys("esc"),
+		key.WithHelp("esc", "cancel"),
+	),
+}
+
+// Key map for picking which PuTTY sessions to import
+type importSelectKeyMap struct {
+	Navigate  key.Binding
+	Toggle    key.Binding
+	ToggleAll key.Binding
+	Import    key.Binding
+	Back      key.Binding
+}
+
+func (k importSelectKeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.Navigate, k.Toggle, k.ToggleAll, k.Import, k.Back}
+}
+
+func (k importSelectKeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{
+		{k.Navigate, k.Toggle, k.ToggleAll, k.Import, k.Back},
+	}
+}
+
+var importSelectKeys = importSelectKeyMap{
+	Navigate: key.NewBinding(
+		key.WithKeys("up", "down"),
+		key.WithHelp("↑/↓", "navigate"),
+	),
+	Toggle: key.NewBinding(
+		key.WithKeys(" "),
+		key.WithHelp("space", "select"),
+	),
+	ToggleAll: key.NewBinding(
+		key.WithKeys("a"),
+		key.WithHelp("a", "select all/none"),
+	),
+	Import: key.NewBinding(
+		key.WithKeys("enter"),
+		key.WithHelp("⏎", "import selected"),
+	),
+	Back: key.NewBinding(
+		key.WithKeys("esc"),
+		key.WithHelp("esc", "back"),
+	),
+}
+
+// State of the PuTTY import view
+// Sessions is nil while the path is being entered
+type importModel struct {
+	path       textinput.Model
+	candidates []string // Path completions shown below the input
+	sessions   []Host
+	selected   []bool
+	existing   []bool // Sessions whose name is already saved, which can't be imported
+	cursor     int
+	err        error
+}
+
+// Shows the PuTTY import view, asking where the sessions are
+func (m Model) openImport() (tea.Model, tea.Cmd) {
+	t := textinput.New()
+	t.Prompt = "> "
+	t.PromptStyle = lg.NewStyle().Foreground(theme.Accent).Margin(0, 0, 0, 2)
+	t.CharLimit = 256
+	if runtime.GOOS == "windows" {
+		t.Placeholder = "leave empty to read the registry"
+	} else {
+		t.SetValue(defaultPuttySessionsPath)
+	}
+	t.Focus()
+
+	m.importer = importModel{path: t}
+	m.view = importView
+	return m, textinput.Blink
+}
+
+func (m Model) updateImport(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.importer.sessions != nil {
+		return m.updateImportSelect(msg)
+	}
+
+	im := &m.importer
+	switch msg.String() {
+	case "tab":
+		value, candidates := completePath(im.path.Value())
+		im.path.SetValue(value)
+		im.path.CursorEnd()
+		im.candidates = candidates
+		return m, nil
+
+	case "enter":
+		sessions, err := loadPuttySessions(im.path.Value())
+		if err != nil {
+			im.err = err
+			return m, nil
+		}
+		if len(sessions) == 0 {
+			im.err = errors.New("no PuTTY SSH sessions found")
+			return m, nil
+		}
+
+		// Sessions are selected unless a host of that name is already saved
+		names := make(map[string]bool)
+		for _, h := range m.allHosts() {
+			names[h.Name] = true
+		}
+		im.sessions = sessions
+		im.selected = make([]bool, len(sessions))
+		im.existing = make([]bool, len(sessions))
+		for i, h := range sessions {
+			im.existing[i] = names[h.Name]
+			im.selected[i] = !names[h.Name]
+		}
+		im.cursor = 0
+		im.candidates = nil
+		im.err = nil
+		return m, nil
+
+	case "esc":
+		m.view = listView
+		return m, nil
+	}
+
+	im.candidates = nil
+	var cmd tea.Cmd
+	im.path, cmd = im.path.Update(msg)
+	return m, cmd
+}
+
+func (m Model) updateImportSelect(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	im := &m.importer
+	switch msg.String() {
+	case "up":
+		if im.cursor > 0 {
+			im.cursor--
+		}
+
+	case "down":
+		if im.cursor < len(im.sessions)-1 {
+			im.cursor++
+		}
+
+	case " ":
+		if !im.existing[im.cursor] {
+			im.selected[im.cursor] = !im.selected[im.cursor]
+		}
+
+	case "a":
+		// Select everything importable unless it already is, then select nothing
+		all := true
+		for i := range im.sessions {
+			if !im.existing[i] && !im.selected[i] {
+				all = false
+			}
+		}
+		for i := range im.sessions {
+			im.selected[i] = !all && !im.existing[i]
+		}
+
+	case "enter":
+		var hosts []Host
+		for i, h := range im.sessions {
+			if im.selected[i] {
+				hosts = append(hosts, h)
+			}
+		}
+		if len(hosts) == 0 {
+			im.err = errors.New("no sessions selected")
+			return m, nil
+		}
+
+		snapshot := takeSnapshot(m.configPath, fmt.Sprintf("importing %d PuTTY sessions", len(hosts)))
+		if err := saveHostsToConfig(m.configPath, puttyImportFolder, hosts); err != nil {
+			im.err = fmt.Errorf("failed to import sessions: %w", err)
+			return m, nil
+		}
+		m.undo = snapshot
+
+		config, err := readConfig(m.configPath)
+		if err != nil {
+			m.err = fmt.Errorf("failed to reload config: %w", err)
+			m.showErr = true
+			m.view = listView
+			return m, nil
+		}
+		m.hosts = config.Hosts
+		m.folders = config.Folders
+		m.marked = nil
+		m.list = m.buildList()
+		m.view = listView
+		return m, m.list.NewStatusMessage(fmt.Sprintf("Imported %d PuTTY sessions into the %s folder", len(hosts), puttyImportFolder))
+
+	case "esc":
+		// Back to the path so a different file can be tried
+		im.sessions = nil
+		im.err = nil
+	}
+	return m, nil
+}
+
+func (m Model) renderImport() string {
+	titleStyle := lg.NewStyle().
+		Bold(true).
+		Foreground(theme.TitleText).
+		Background(theme.TitleBackground).
+		Padding(0, 1).
+		Margin(0, 0, 0, 2)
+
+	labelStyle := lg.NewStyle().
+		Foreground(theme.Text).
+		Bold(true).
+		Margin(0, 0, 0, 2)
+
+	hintStyle := lg.NewStyle().
+		Foreground(theme.Muted).
+		Margin(0, 0, 0, 2)
+
+	errStyle := lg.NewStyle().
+		Foreground(theme.Error).
+		Margin(0, 0, 0, 2)
+
+	hostStyle := lg.NewStyle().
+		Foreground(theme.Text).
+		Margin(0, 0, 0, 2)
+
+	selectedHostStyle := lg.NewStyle().
+		Foreground(theme.Highlight).
+		Bold(true).
+		Margin(0, 0, 0, 2)
+
+	im := m.importer
+	var keys help.KeyMap = importPathKeys
+	if im.sessions != nil {
+		keys = importSelectKeys
+	}
+	helpRendered, availHeight := m.renderFormHelp(keys)
+
+	var title string
+	title = titleStyle.Render("Import PuTTY Sessions") + "\n\n"
+	availHeight -= lg.Height(title)
+	var b string
+
+	if im.sessions == nil {
+		b += labelStyle.Render("Exported .reg file or sessions folder") + "\n"
+		b += im.path.View() + "\n"
+		if len(im.candidates) > 0 {
+			b += hintStyle.Render(formatPathCandidates(im.candidates)) + "\n"
+		}
+		if im.err != nil {
+			b += errStyle.Render(im.err.Error()) + "\n"
+		}
+		b += "\n" + hintStyle.Render("Export sessions on Windows with: reg export HKCU\\Software\\SimonTatham\\PuTTY\\Sessions putty.reg") + "\n"
+		return m.calculateVisibleFormContent(availHeight, b, title, helpRendered, m.getVisibleDeleteLines)
+	}
+
+	count := 0
+	for _, s := range im.selected {
+		if s {
+			count++
+		}
+	}
+	b += hintStyle.Render(fmt.Sprintf("%d of %d sessions selected, saved to the %s folder", count, len(im.sessions), puttyImportFolder)) + "\n"
+	if im.err != nil {
+		b += errStyle.Render(im.err.Error()) + "\n"
+	}
+	b += "\n"
+
+	for i, h := range im.sessions {
+		box := "[ ]"
+		if im.selected[i] {
+			box = "[x]"
+		}
+		line := box + " " + h.Name + "  " + h.User + "@" + h.Host
+		if h.Port != 22 {
+			line += fmt.Sprintf(":%d", h.Port)
+		}
+		switch {
+		case im.existing[i]:
+			line += "  (already saved)"
+		case h.Notes != "":
+			line += "  (" + h.Notes + ")"
+		}
+
+		style := hostStyle
+		if i == im.cursor {
+			style = selectedHostStyle
+		}
+		b += style.Render(focusMarker(i == im.cursor)+line) + "\n"
+	}
+
+	return m.calculateVisibleFormContent(availHeight, b, title, helpRendered, m.getVisibleImportLines)
+}
+
+// Returns the visible portion of the session list, keeping the cursor in view
+func (m Model) getVisibleImportLines(lines []string, availHeight int) []string {
+	// The count, any error, and a blank line come before the first session
+	header := slices.Index(lines, "") + 1
+	offset := max(0, header+m.importer.cursor-availHeight+1)
+	return scrollLines(lines, offset, availHeight)
+}
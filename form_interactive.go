@@ -0,0 +1,180 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	lg "github.com/charmbracelet/lipgloss"
+)
+
+// Returned when the user cancels a keyboard-interactive prompt
+var errInteractiveCancelled = errors.New("keyboard-interactive authentication cancelled")
+
+// Key map for keyboard-interactive prompts
+type interactiveKeyMap struct {
+	Navigate key.Binding
+	Submit   key.Binding
+	Cancel   key.Binding
+}
+
+func (k interactiveKeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.Navigate, k.Submit, k.Cancel}
+}
+
+func (k interactiveKeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{
+		{k.Navigate, k.Submit, k.Cancel},
+	}
+}
+
+var interactiveKeys = interactiveKeyMap{
+	Navigate: key.NewBinding(
+		key.WithKeys("tab", "shift+tab"),
+		key.WithHelp("tab", "next question"),
+	),
+	Submit: key.NewBinding(
+		key.WithKeys("enter"),
+		key.WithHelp("⏎", "submit"),
+	),
+	Cancel: key.NewBinding(
+		key.WithKeys("esc", "ctrl+c"),
+		key.WithHelp("esc", "cancel"),
+	),
+}
+
+// Standalone Bubble Tea model that asks the server's keyboard-interactive questions
+// It runs while the SSH handshake is in progress, after the host list has exited
+type interactiveModel struct {
+	name        string
+	instruction string
+	questions   []string
+	inputs      []textinput.Model
+	focusIndex  int
+	cancelled   bool
+}
+
+func newInteractiveModel(name, instruction string, questions []string, echos []bool) interactiveModel {
+	inputs := make([]textinput.Model, len(questions))
+	for i := range inputs {
+		t := textinput.New()
+		t.Prompt = "> "
+		t.PromptStyle = lg.NewStyle().Foreground(theme.Accent).Margin(0, 0, 0, 2)
+		t.CharLimit = 256
+		if !echos[i] {
+			t.EchoMode = textinput.EchoPassword
+		}
+		if i == 0 {
+			t.Focus()
+		}
+		inputs[i] = t
+	}
+
+	return interactiveModel{
+		name:        name,
+		instruction: instruction,
+		questions:   questions,
+		inputs:      inputs,
+	}
+}
+
+func (m interactiveModel) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+func (m interactiveModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+
+	switch keyMsg.String() {
+	case "esc", "ctrl+c":
+		m.cancelled = true
+		return m, tea.Quit
+
+	case "enter", "tab", "shift+tab":
+		// Enter on the last question submits, otherwise move between questions
+		if keyMsg.String() == "enter" && m.focusIndex == len(m.inputs)-1 {
+			return m, tea.Quit
+		}
+		if keyMsg.String() == "shift+tab" {
+			m.focusIndex = (m.focusIndex - 1 + len(m.inputs)) % len(m.inputs)
+		} else {
+			m.focusIndex = (m.focusIndex + 1) % len(m.inputs)
+		}
+
+		cmds := make([]tea.Cmd, len(m.inputs))
+		for i := range m.inputs {
+			if i == m.focusIndex {
+				cmds[i] = m.inputs[i].Focus()
+			} else {
+				m.inputs[i].Blur()
+			}
+		}
+		return m, tea.Batch(cmds...)
+	}
+
+	var cmd tea.Cmd
+	m.inputs[m.focusIndex], cmd = m.inputs[m.focusIndex].Update(msg)
+	return m, cmd
+}
+
+func (m interactiveModel) View() string {
+	titleStyle := lg.NewStyle().
+		Bold(true).
+		Foreground(theme.TitleText).
+		Background(theme.TitleBackground).
+		Padding(0, 1).
+		Margin(0, 0, 0, 2)
+
+	infoStyle := lg.NewStyle().
+		Foreground(theme.Muted).
+		Padding(0, 2)
+
+	labelStyle := lg.NewStyle().
+		Foreground(theme.Text).
+		Bold(true).
+		Margin(0, 0, 0, 2)
+
+	helpStyle := lg.NewStyle().
+		Padding(1, 0, 0, 2)
+
+	title := "Server Authentication"
+	if m.name != "" {
+		title = m.name
+	}
+
+	b := titleStyle.Render(title) + "\n\n"
+	if m.instruction != "" {
+		b += infoStyle.Render(m.instruction) + "\n\n"
+	}
+	for i, input := range m.inputs {
+		b += labelStyle.Render(focusMarker(i == m.focusIndex)+m.questions[i]) + "\n"
+		b += input.View() + "\n\n"
+	}
+
+	return docStyle.Render(b + helpStyle.Render(help.New().View(interactiveKeys)))
+}
+
+// Asks keyboard-interactive questions in a temporary TUI and returns the answers
+func promptKeyboardInteractive(name, instruction string, questions []string, echos []bool) ([]string, error) {
+	p := tea.NewProgram(newInteractiveModel(name, instruction, questions, echos))
+	finalModel, err := p.Run()
+	if err != nil {
+		return nil, err
+	}
+
+	m, ok := finalModel.(interactiveModel)
+	if !ok || m.cancelled {
+		return nil, errInteractiveCancelled
+	}
+
+	answers := make([]string, len(m.inputs))
+	for i, input := range m.inputs {
+		answers[i] = input.Value()
+	}
+	return answers, nil
+}
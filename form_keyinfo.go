@@ -0,0 +1,105 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	lg "github.com/charmbracelet/lipgloss"
+	"github.com/nathanlytang/rolodex/internal/ssh"
+)
+
+// How long typing in the identity file input has to pause before the key is read
+const keyInfoDelay = 300 * time.Millisecond
+
+// Sent once typing in the identity file input pauses, ignored if it has changed since
+type keyInfoTickMsg struct {
+	id int
+}
+
+// Result of reading the key at the identity file path, delivered back to Update
+type keyInfoMsg struct {
+	id   int
+	path string
+	info ssh.KeyInfo
+	err  error
+}
+
+// Details of the key at the identity file path, shown under the input
+type keyInfoState struct {
+	info ssh.KeyInfo
+	err  error
+}
+
+// Schedules reading the key at the identity file path once typing pauses
+// Each call supersedes the ones before it
+func (f *formModel) identityFileChanged() tea.Cmd {
+	f.keyInfoID++
+	id := f.keyInfoID
+	return tea.Tick(keyInfoDelay, func(time.Time) tea.Msg {
+		return keyInfoTickMsg{id: id}
+	})
+}
+
+// Reads the key at the identity file path if nothing was typed since the tick was scheduled
+func (m Model) inspectIdentityFile(msg keyInfoTickMsg) (tea.Model, tea.Cmd) {
+	if m.view != formView || msg.id != m.form.keyInfoID {
+		return m, nil
+	}
+	path := strings.TrimSpace(m.form.inputs[identityFileInput].Value())
+	if path == "" {
+		m.form.keyInfo = nil
+		return m, nil
+	}
+	return m, func() tea.Msg {
+		info, err := ssh.InspectKeyFile(path)
+		return keyInfoMsg{id: msg.id, path: path, info: info, err: err}
+	}
+}
+
+// Records the key details if the identity file path hasn't changed since it was read
+func (m Model) finishKeyInfo(msg keyInfoMsg) (tea.Model, tea.Cmd) {
+	if m.view != formView || msg.id != m.form.keyInfoID ||
+		msg.path != strings.TrimSpace(m.form.inputs[identityFileInput].Value()) {
+		return m, nil
+	}
+	m.form.keyInfo = &keyInfoState{info: msg.info, err: msg.err}
+	return m, nil
+}
+
+// Renders the key type and fingerprint shown under the identity file input
+func (k *keyInfoState) View() string {
+	mutedStyle := lg.NewStyle().
+		Foreground(theme.Muted).
+		Margin(0, 0, 0, 4)
+
+	okStyle := lg.NewStyle().
+		Foreground(theme.Success).
+		Margin(0, 0, 0, 4)
+
+	warnStyle := lg.NewStyle().
+		Foreground(theme.Warning).
+		Margin(0, 0, 0, 4)
+
+	failStyle := lg.NewStyle().
+		Foreground(theme.Error).
+		Margin(0, 0, 0, 4)
+
+	switch {
+	case errors.Is(k.err, fs.ErrNotExist):
+		return mutedStyle.Render("No file at this path") + "\n"
+	case k.err != nil:
+		return failStyle.Render("✗ "+k.err.Error()) + "\n"
+	}
+
+	var b string
+	if k.info.Fingerprint != "" {
+		b += okStyle.Render("✓ "+k.info.Type+" "+k.info.Fingerprint) + "\n"
+	}
+	if k.info.Encrypted {
+		b += warnStyle.Render("Encrypted, a passphrase will be needed") + "\n"
+	}
+	return b
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	lg "github.com/charmbracelet/lipgloss"
+	"github.com/nathanlytang/rolodex/internal/ssh"
+)
+
+// Key map for the identity file permissions warning
+type keyPermKeyMap struct {
+	Fix     key.Binding
+	Connect key.Binding
+	Cancel  key.Binding
+}
+
+func (k keyPermKeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.Fix, k.Connect, k.Cancel}
+}
+
+func (k keyPermKeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{
+		{k.Fix, k.Connect, k.Cancel},
+	}
+}
+
+var keyPermKeys = keyPermKeyMap{
+	Fix: key.NewBinding(
+		key.WithKeys("f", "y"),
+		key.WithHelp("f", "chmod 600 and connect"),
+	),
+	Connect: key.NewBinding(
+		key.WithKeys("c"),
+		key.WithHelp("c", "connect anyway"),
+	),
+	Cancel: key.NewBinding(
+		key.WithKeys("n", "esc"),
+		key.WithHelp("n/esc", "cancel"),
+	),
+}
+
+func (m Model) updateKeyPerm(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	h := m.keyPermHost
+	if h == nil {
+		m.view = listView
+		return m, nil
+	}
+
+	switch msg.String() {
+	case "f", "y":
+		if err := ssh.FixKeyPermissions(h.IdentityFile); err != nil {
+			m.keyPermHost = nil
+			m.keyPermErr = nil
+			m.err = err
+			m.showErr = true
+			m.view = listView
+			return m, nil
+		}
+		m.keyPermHost = nil
+		m.keyPermErr = nil
+		return m.promptAndConnect(h)
+
+	case "c":
+		m.keyPermHost = nil
+		m.keyPermErr = nil
+		return m.promptAndConnect(h)
+
+	case "n", "esc":
+		m.keyPermHost = nil
+		m.keyPermErr = nil
+		m.view = listView
+		return m, nil
+	}
+
+	return m, nil
+}
+
+func (m Model) renderKeyPerm() string {
+	titleStyle := lg.NewStyle().
+		Bold(true).
+		Foreground(theme.TitleText).
+		Background(theme.TitleBackground).
+		Padding(0, 1).
+		Margin(0, 0, 0, 2)
+
+	warnStyle := lg.NewStyle().
+		Foreground(theme.Warning).
+		Padding(0, 2)
+
+	infoStyle := lg.NewStyle().
+		Foreground(theme.Muted).
+		Padding(0, 2)
+
+	helpRendered, availHeight := m.renderFormHelp(keyPermKeys)
+
+	var title string
+	title = titleStyle.Render("Insecure Key Permissions") + "\n\n"
+	availHeight -= lg.Height(title)
+	var b string
+
+	if e := m.keyPermErr; e != nil {
+		b += warnStyle.Render(fmt.Sprintf("%s is readable by other users (%04o).", e.Path, e.Mode.Perm())) + "\n\n"
+		b += infoStyle.Render("OpenSSH refuses keys like this. Fixing restricts the file to you (chmod 600).") + "\n"
+		if m.strictKeyPermissions {
+			b += infoStyle.Render("Strict key permissions are on, so connecting anyway won't offer this key.") + "\n"
+		}
+	}
+
+	return m.calculateVisibleFormContent(availHeight, b, title, helpRendered, m.getVisibleDeleteLines)
+}
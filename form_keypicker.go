@@ -0,0 +1,118 @@
+package main
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+	lg "github.com/charmbracelet/lipgloss"
+	"github.com/nathanlytang/rolodex/internal/ssh"
+)
+
+// A key offered by the picker
+type keyOption struct {
+	value  string // Written to the input when selected
+	detail string // Fingerprint or other description shown below the value
+}
+
+// Selection list of SSH keys shown under the input it fills
+type keyPicker struct {
+	input  int // Form input the selected key is written to
+	keys   []keyOption
+	empty  string // Shown when there are no keys to pick
+	cursor int
+}
+
+// Builds a picker from the key files found in common ~/.ssh locations
+func newIdentityPicker() *keyPicker {
+	var keys []keyOption
+	for _, path := range ssh.FindAvailableKeys() {
+		fingerprint, err := ssh.KeyFileFingerprint(path)
+		if err != nil {
+			fingerprint = "unreadable: " + err.Error()
+		}
+		keys = append(keys, keyOption{value: path, detail: fingerprint})
+	}
+	return &keyPicker{input: identityFileInput, keys: keys, empty: "No keys found in ~/.ssh"}
+}
+
+// Builds a picker from the keys loaded in the SSH agent
+func newAgentKeyPicker() *keyPicker {
+	p := &keyPicker{input: agentKeyInput, empty: "No keys loaded in the SSH agent"}
+
+	agentKeys, err := ssh.ListAgentKeys()
+	if err != nil {
+		p.empty = err.Error()
+		return p
+	}
+	for _, k := range agentKeys {
+		detail := k.Type
+		if k.Comment != "" {
+			detail += " " + k.Comment
+		}
+		p.keys = append(p.keys, keyOption{value: k.Fingerprint, detail: detail})
+	}
+	return p
+}
+
+// Handles keys while the picker is open
+func (m Model) updateKeyPicker(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	p := m.form.keyPicker
+
+	switch msg.String() {
+	case "esc":
+		m.form.keyPicker = nil
+
+	case "up", "shift+tab":
+		if p.cursor > 0 {
+			p.cursor--
+		}
+
+	case "down", "tab":
+		if p.cursor < len(p.keys)-1 {
+			p.cursor++
+		}
+
+	case "enter":
+		var cmd tea.Cmd
+		if len(p.keys) > 0 {
+			m.form.inputs[p.input].SetValue(p.keys[p.cursor].value)
+			m.form.inputs[p.input].CursorEnd()
+			if p.input == identityFileInput {
+				cmd = m.form.identityFileChanged()
+			}
+		}
+		m.form.keyPicker = nil
+		return m, cmd
+	}
+
+	return m, nil
+}
+
+// Renders the picker entries with their details
+func (p *keyPicker) View() string {
+	itemStyle := lg.NewStyle().
+		Foreground(theme.Text).
+		Margin(0, 0, 0, 4)
+
+	selectedStyle := lg.NewStyle().
+		Foreground(theme.Highlight).
+		Bold(true).
+		Margin(0, 0, 0, 2)
+
+	detailStyle := lg.NewStyle().
+		Foreground(theme.Muted).
+		Margin(0, 0, 0, 6)
+
+	if len(p.keys) == 0 {
+		return detailStyle.Render(p.empty+" (esc to close)") + "\n"
+	}
+
+	var b string
+	for i, k := range p.keys {
+		if i == p.cursor {
+			b += selectedStyle.Render("▸ "+k.value) + "\n"
+		} else {
+			b += itemStyle.Render(k.value) + "\n"
+		}
+		b += detailStyle.Render(k.detail) + "\n"
+	}
+	return b
+}
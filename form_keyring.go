@@ -0,0 +1,253 @@
+package main
+
+import (
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	lg "github.com/charmbracelet/lipgloss"
+	"github.com/nathanlytang/rolodex/internal/logger"
+	"github.com/nathanlytang/rolodex/internal/ssh"
+)
+
+// Key map for the keyring view
+type keyringKeyMap struct {
+	Set    key.Binding
+	Delete key.Binding
+	Back   key.Binding
+}
+
+func (k keyringKeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.Set, k.Delete, k.Back}
+}
+
+func (k keyringKeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{
+		{k.Set, k.Delete, k.Back},
+	}
+}
+
+var keyringKeys = keyringKeyMap{
+	Set: key.NewBinding(
+		key.WithKeys("s"),
+		key.WithHelp("s", "set password"),
+	),
+	Delete: key.NewBinding(
+		key.WithKeys("d"),
+		key.WithHelp("d", "delete password"),
+	),
+	Back: key.NewBinding(
+		key.WithKeys("esc", "q"),
+		key.WithHelp("esc", "back to list"),
+	),
+}
+
+// Key map while typing a new keyring password
+type keyringInputKeyMap struct {
+	Save   key.Binding
+	Cancel key.Binding
+}
+
+func (k keyringInputKeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.Save, k.Cancel}
+}
+
+func (k keyringInputKeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{
+		{k.Save, k.Cancel},
+	}
+}
+
+var keyringInputKeys = keyringInputKeyMap{
+	Save: key.NewBinding(
+		key.WithKeys("enter"),
+		key.WithHelp("⏎", "save"),
+	),
+	Cancel: key.NewBinding(
+		key.WithKeys("esc"),
+		key.WithHelp("esc", "cancel"),
+	),
+}
+
+// State of the keyring view
+type keyringModel struct {
+	host     *Host
+	exists   bool            // A password is stored for the host's service and account
+	input    textinput.Model // New password, typed while setting
+	setting  bool
+	deleting bool   // Waiting for the deletion to be confirmed
+	status   string // Result of the last change
+	err      error  // Keyring failure, shown instead of the status
+}
+
+// Shows whether the host's keyring entry holds a password
+func (m Model) openKeyring(h Host) (tea.Model, tea.Cmd) {
+	if h.KeyringService == "" || h.KeyringAccount == "" {
+		return m, m.list.NewStatusMessage(h.Name + " has no keyring service and account")
+	}
+	m.keyring = keyringModel{host: &h}
+	m.keyring.refresh()
+	m.view = keyringView
+	return m, nil
+}
+
+// Checks the keyring for the host's password
+func (k *keyringModel) refresh() {
+	exists, err := ssh.KeyringHasSecret(k.host.KeyringService, k.host.KeyringAccount)
+	if err != nil {
+		logger.Errorf("Failed to check keyring for %s/%s: %v", k.host.KeyringService, k.host.KeyringAccount, err)
+	}
+	k.exists = exists
+	k.err = err
+}
+
+func (m Model) updateKeyring(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	k := &m.keyring
+	h := k.host
+
+	if k.setting {
+		switch msg.String() {
+		case "enter":
+			password := k.input.Value()
+			k.input.Reset()
+			k.setting = false
+			if password == "" {
+				return m, nil
+			}
+			if err := ssh.StoreInKeyring(h.KeyringService, h.KeyringAccount, password); err != nil {
+				logger.Errorf("Failed to store password in keyring for %s/%s: %v", h.KeyringService, h.KeyringAccount, err)
+				k.err = err
+				return m, nil
+			}
+			logger.Printf("Stored password for %s in keyring as %s/%s", h.Name, h.KeyringService, h.KeyringAccount)
+			k.refresh()
+			k.status = "Password saved"
+			return m, nil
+		case "esc":
+			k.input.Reset()
+			k.setting = false
+			return m, nil
+		}
+		var cmd tea.Cmd
+		k.input, cmd = k.input.Update(msg)
+		return m, cmd
+	}
+
+	if k.deleting {
+		switch msg.String() {
+		case "y", "Y":
+			k.deleting = false
+			if err := ssh.DeleteFromKeyring(h.KeyringService, h.KeyringAccount); err != nil {
+				logger.Errorf("Failed to delete password from keyring for %s/%s: %v", h.KeyringService, h.KeyringAccount, err)
+				k.err = err
+				return m, nil
+			}
+			logger.Printf("Deleted keyring password %s/%s", h.KeyringService, h.KeyringAccount)
+			k.refresh()
+			k.status = "Password deleted"
+		case "n", "N", "esc":
+			k.deleting = false
+		}
+		return m, nil
+	}
+
+	switch msg.String() {
+	case "s":
+		t := textinput.New()
+		t.Prompt = "> "
+		t.PromptStyle = lg.NewStyle().Foreground(theme.Accent).Margin(0, 0, 0, 2)
+		t.EchoMode = textinput.EchoPassword
+		t.CharLimit = 256
+		t.Focus()
+		k.input = t
+		k.setting = true
+		k.status = ""
+		return m, textinput.Blink
+	case "d":
+		if k.exists {
+			k.deleting = true
+			k.status = ""
+		}
+	case "esc", "q":
+		m.keyring = keyringModel{}
+		m.view = listView
+	}
+	return m, nil
+}
+
+func (m Model) renderKeyring() string {
+	titleStyle := lg.NewStyle().
+		Bold(true).
+		Foreground(theme.TitleText).
+		Background(theme.TitleBackground).
+		Padding(0, 1).
+		Margin(0, 0, 0, 2)
+
+	hostStyle := lg.NewStyle().
+		Foreground(theme.Highlight).
+		Bold(true).
+		Margin(0, 2)
+
+	hostDescriptionStyle := lg.NewStyle().
+		Foreground(theme.Text).
+		Padding(0, 1)
+
+	labelStyle := lg.NewStyle().
+		Foreground(theme.Text).
+		Bold(true).
+		Margin(0, 0, 0, 2)
+
+	infoStyle := lg.NewStyle().
+		Foreground(theme.Muted).
+		Padding(0, 2)
+
+	successStyle := lg.NewStyle().
+		Foreground(theme.Success).
+		Padding(0, 2)
+
+	errStyle := lg.NewStyle().
+		Foreground(theme.Error).
+		Padding(0, 2)
+
+	k := m.keyring
+	var helpRendered string
+	var availHeight int
+	if k.setting {
+		helpRendered, availHeight = m.renderFormHelp(keyringInputKeys)
+	} else if k.deleting {
+		helpRendered, availHeight = m.renderFormHelp(deleteKeys)
+	} else {
+		helpRendered, availHeight = m.renderFormHelp(keyringKeys)
+	}
+
+	var title string
+	title = titleStyle.Render("Keyring Password") + "\n\n"
+	availHeight -= lg.Height(title)
+	var b string
+
+	if h := k.host; h != nil {
+		b += hostStyle.Render("Host") + hostDescriptionStyle.Render(h.Name) + "\n"
+		b += hostStyle.Render("Service") + hostDescriptionStyle.Render(h.KeyringService) + "\n"
+		b += hostStyle.Render("Account") + hostDescriptionStyle.Render(h.KeyringAccount) + "\n\n"
+
+		switch {
+		case k.err != nil:
+			b += errStyle.Render("Keyring error: "+k.err.Error()) + "\n\n"
+		case k.exists:
+			b += successStyle.Render("A password is stored.") + "\n\n"
+		default:
+			b += infoStyle.Render("No password stored.") + "\n\n"
+		}
+
+		switch {
+		case k.setting:
+			b += labelStyle.Render("New password") + "\n"
+			b += k.input.View() + "\n\n"
+		case k.deleting:
+			b += errStyle.Render("Are you sure you want to delete this password from the keyring?") + "\n\n"
+		case k.status != "":
+			b += infoStyle.Render(k.status) + "\n\n"
+		}
+	}
+
+	return m.calculateVisibleFormContent(availHeight, b, title, helpRendered, m.getVisibleDeleteLines)
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	lg "github.com/charmbracelet/lipgloss"
+	"github.com/nathanlytang/rolodex/internal/logger"
+)
+
+// Most lines the log viewer keeps from the end of the log file
+const logViewerLines = 1000
+
+// Most bytes read from the end of the log file, so a large day of debug logging stays quick to open
+const logViewerMaxBytes = 1 << 20
+
+// Key map for the log viewer
+type logsKeyMap struct {
+	Scroll key.Binding
+	Page   key.Binding
+	Ends   key.Binding
+	Reload key.Binding
+	Back   key.Binding
+}
+
+func (k logsKeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.Scroll, k.Page, k.Ends, k.Reload, k.Back}
+}
+
+func (k logsKeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{
+		{k.Scroll, k.Page, k.Ends, k.Reload, k.Back},
+	}
+}
+
+var logsKeys = logsKeyMap{
+	Scroll: key.NewBinding(
+		key.WithKeys("up", "down"),
+		key.WithHelp("↑/↓", "scroll"),
+	),
+	Page: key.NewBinding(
+		key.WithKeys("pgup", "pgdown"),
+		key.WithHelp("pgup/pgdn", "page"),
+	),
+	Ends: key.NewBinding(
+		key.WithKeys("home", "end"),
+		key.WithHelp("home/end", "oldest/newest"),
+	),
+	Reload: key.NewBinding(
+		key.WithKeys("r"),
+		key.WithHelp("r", "reload"),
+	),
+	Back: key.NewBinding(
+		key.WithKeys("esc", "q"),
+		key.WithHelp("esc", "back"),
+	),
+}
+
+// State of the log viewer
+type logViewerModel struct {
+	path  string
+	lines []string
+	back  int // Lines scrolled up from the newest, 0 follows the end of the log
+	err   error
+	from  viewState
+}
+
+// Shows the end of today's log file, returning to from when closed
+func (m Model) openLogs(from viewState) (tea.Model, tea.Cmd) {
+	m.logs = logViewerModel{path: logger.Path(), from: from}
+	m.logs.reload()
+	m.view = logsView
+	return m, nil
+}
+
+// Rereads the log file, keeping the scroll position unless it was following the end
+func (l *logViewerModel) reload() {
+	if l.path == "" {
+		l.lines = nil
+		l.err = errors.New("logging isn't available, the log file couldn't be opened")
+		return
+	}
+	l.lines, l.err = readLogTail(l.path, logViewerLines)
+	l.back = min(l.back, max(len(l.lines)-1, 0))
+}
+
+// Returns the last n lines of the file at path
+// Only the last logViewerMaxBytes are read, dropping the partial line they start in
+func readLogTail(path string, n int) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	start := max(info.Size()-logViewerMaxBytes, 0)
+	if _, err := f.Seek(start, io.SeekStart); err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+	if start > 0 {
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			data = data[i+1:]
+		}
+	}
+
+	text := strings.TrimRight(string(data), "\n")
+	if text == "" {
+		return nil, nil
+	}
+	lines := strings.Split(text, "\n")
+	return lines[max(len(lines)-n, 0):], nil
+}
+
+func (m Model) updateLogs(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	l := &m.logs
+	page := max(m.height/2, 1)
+	last := max(len(l.lines)-1, 0)
+
+	switch msg.String() {
+	case "up":
+		l.back = min(l.back+1, last)
+	case "down":
+		l.back = max(l.back-1, 0)
+	case "pgup":
+		l.back = min(l.back+page, last)
+	case "pgdown":
+		l.back = max(l.back-page, 0)
+	case "home":
+		l.back = last
+	case "end":
+		l.back = 0
+	case "r":
+		l.reload()
+	case "esc", "q":
+		m.view = l.from
+		m.logs = logViewerModel{}
+	}
+	return m, nil
+}
+
+// Reports whether a log line starts a new entry rather than continuing a multi-line message
+// Entries start with the date log.LstdFlags writes, like 2024/01/02
+func isLogEntryStart(line string) bool {
+	return len(line) >= 10 && line[4] == '/' && line[7] == '/'
+}
+
+func (m Model) renderLogs() string {
+	titleStyle := lg.NewStyle().
+		Bold(true).
+		Foreground(theme.TitleText).
+		Background(theme.TitleBackground).
+		Padding(0, 1).
+		Margin(0, 0, 0, 2)
+
+	hintStyle := lg.NewStyle().
+		Foreground(theme.Muted).
+		Margin(0, 0, 0, 2)
+
+	errStyle := lg.NewStyle().
+		Foreground(theme.Error).
+		Margin(0, 0, 0, 2)
+
+	width := m.width - docStyle.GetHorizontalFrameSize() - 2
+	if m.width == 0 {
+		width = 80 // fallback
+	}
+	lineStyle := lg.NewStyle().Foreground(theme.Text).Margin(0, 0, 0, 2).MaxWidth(width + 2)
+	fatalStyle := lineStyle.Foreground(theme.Error).Bold(true)
+	errorStyle := lineStyle.Foreground(theme.Warning)
+
+	helpRendered, availHeight := m.renderFormHelp(logsKeys)
+
+	l := m.logs
+	title := titleStyle.Render("Logs") + "\n"
+	switch {
+	case l.err != nil:
+		title += errStyle.Render(l.err.Error()) + "\n\n"
+	case l.back > 0:
+		title += hintStyle.Render(fmt.Sprintf("%s, %d lines up from the newest", l.path, l.back)) + "\n\n"
+	default:
+		title += hintStyle.Render(l.path) + "\n\n"
+	}
+	availHeight -= lg.Height(title)
+
+	if len(l.lines) == 0 {
+		b := ""
+		if l.err == nil {
+			b = hintStyle.Render("The log is empty.")
+		}
+		return m.calculateVisibleFormContent(availHeight, b, title, helpRendered, m.getVisibleLogLines)
+	}
+
+	// Continuation lines of a multi-line message keep the style of the entry they belong to
+	var rendered []string
+	style := lineStyle
+	for _, line := range l.lines {
+		if isLogEntryStart(line) {
+			switch {
+			case strings.Contains(line, " FATAL: "):
+				style = fatalStyle
+			case strings.Contains(line, " ERROR: "):
+				style = errorStyle
+			default:
+				style = lineStyle
+			}
+		}
+		line = strings.ReplaceAll(sanitizeBanner(line), "\t", "    ")
+		rendered = append(rendered, style.Render(line))
+	}
+
+	return m.calculateVisibleFormContent(availHeight, strings.Join(rendered, "\n"), title, helpRendered, m.getVisibleLogLines)
+}
+
+// Returns the lines ending m.logs.back lines above the newest
+func (m Model) getVisibleLogLines(lines []string, availHeight int) []string {
+	return scrollLines(lines, len(lines)-availHeight-m.logs.back, availHeight)
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	lg "github.com/charmbracelet/lipgloss"
+	"github.com/nathanlytang/rolodex/internal/ssh"
+)
+
+// Key map for connect-time password prompt
+type passwordPromptKeyMap struct {
+	Connect key.Binding
+	Cancel  key.Binding
+}
+
+func (k passwordPromptKeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.Connect, k.Cancel}
+}
+
+func (k passwordPromptKeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{
+		{k.Connect, k.Cancel},
+	}
+}
+
+var passwordPromptKeys = passwordPromptKeyMap{
+	Connect: key.NewBinding(
+		key.WithKeys("enter"),
+		key.WithHelp("⏎", "connect"),
+	),
+	Cancel: key.NewBinding(
+		key.WithKeys("esc"),
+		key.WithHelp("esc", "cancel"),
+	),
+}
+
+// Connects to a host, first warning about an identity file others can read
+// Disabled hosts aren't connected to, the list shows why instead
+func (m Model) connect(h *Host) (tea.Model, tea.Cmd) {
+	if h.Disabled {
+		m.view = listView
+		return m, m.list.NewStatusMessage(disabledHostError(h).Error())
+	}
+	if h.IdentityFile != "" {
+		var permErr *ssh.KeyPermissionError
+		if errors.As(ssh.CheckKeyPermissions(h.IdentityFile), &permErr) {
+			m.keyPermHost = h
+			m.keyPermErr = permErr
+			m.view = keyPermView
+			return m, nil
+		}
+	}
+	return m.promptAndConnect(h)
+}
+
+// Quits the TUI to connect to a host, asking for a password first if required
+func (m Model) promptAndConnect(h *Host) (tea.Model, tea.Cmd) {
+	if h.PromptPassword {
+		t := textinput.New()
+		t.Prompt = "> "
+		t.PromptStyle = lg.NewStyle().Foreground(theme.Accent).Margin(0, 0, 0, 2)
+		t.EchoMode = textinput.EchoPassword
+		t.CharLimit = 256
+		t.Focus()
+
+		m.passwordPrompt = t
+		m.promptHost = h
+		m.view = passwordPromptView
+		return m, textinput.Blink
+	}
+
+	if m.tunnelMode {
+		return m.openTunnel(h, nil)
+	}
+	return m.startConnect(h, nil)
+}
+
+func (m Model) updatePasswordPrompt(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "enter":
+		// Hand the password to main for this connection only, it is never saved
+		password := []byte(m.passwordPrompt.Value())
+		m.passwordPrompt.Reset()
+		h := m.promptHost
+		m.promptHost = nil
+		if m.tunnelMode {
+			return m.openTunnel(h, password)
+		}
+		return m.startConnect(h, password)
+
+	case "esc":
+		// Cancel and return to list
+		m.passwordPrompt.Reset()
+		m.promptHost = nil
+		m.view = listView
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.passwordPrompt, cmd = m.passwordPrompt.Update(msg)
+	return m, cmd
+}
+
+func (m Model) renderPasswordPrompt() string {
+	titleStyle := lg.NewStyle().
+		Bold(true).
+		Foreground(theme.TitleText).
+		Background(theme.TitleBackground).
+		Padding(0, 1).
+		Margin(0, 0, 0, 2)
+
+	labelStyle := lg.NewStyle().
+		Foreground(theme.Text).
+		Bold(true).
+		Margin(0, 0, 0, 2)
+
+	helpRendered, availHeight := m.renderFormHelp(passwordPromptKeys)
+
+	var title string
+	title = titleStyle.Render("Password Required") + "\n\n"
+	availHeight -= lg.Height(title)
+	var b string
+
+	if m.promptHost != nil {
+		h := m.promptHost
+		b += labelStyle.Render("Password for "+h.User+"@"+h.Host) + "\n"
+		b += m.passwordPrompt.View() + "\n\n"
+	}
+
+	return m.calculateVisibleFormContent(availHeight, b, title, helpRendered, m.getVisibleDeleteLines)
+}
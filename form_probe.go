@@ -0,0 +1,92 @@
+package main
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	lg "github.com/charmbracelet/lipgloss"
+	"github.com/nathanlytang/rolodex/internal/ssh"
+)
+
+// Shown when the connection test is run before host and port are filled in
+var errProbeIncomplete = errors.New("enter a host and port to test the connection")
+
+// Result of a form connection test, delivered back to Update
+type probeResultMsg struct {
+	address string
+	err     error
+}
+
+// State of the form's connection test
+type probeState struct {
+	address string
+	running bool
+	err     error
+}
+
+// Returns a command that checks the address is reachable over TCP without authenticating
+func probeHost(address string, timeout time.Duration) tea.Cmd {
+	return func() tea.Msg {
+		return probeResultMsg{address: address, err: ssh.CheckReachable(address, timeout)}
+	}
+}
+
+// Starts a connection test against the host and port currently in the form
+func (m Model) startProbe() (tea.Model, tea.Cmd) {
+	host, portStr := splitHostInput(m.form.inputs[hostInput].Value())
+	if portStr == "" {
+		portStr = strings.TrimSpace(m.form.inputs[portInput].Value())
+	}
+	port, err := strconv.Atoi(portStr)
+	if host == "" || err != nil {
+		m.form.probe = &probeState{err: errProbeIncomplete}
+		return m, nil
+	}
+
+	timeout := ssh.DefaultDialTimeout
+	if m.form.base.ConnectTimeout > 0 {
+		timeout = time.Duration(m.form.base.ConnectTimeout) * time.Second
+	}
+
+	address := ssh.Address(host, port)
+	m.form.probe = &probeState{address: address, running: true}
+	return m, probeHost(address, timeout)
+}
+
+// Records a connection test result if it still matches the form
+func (m Model) finishProbe(msg probeResultMsg) (tea.Model, tea.Cmd) {
+	p := m.form.probe
+	if m.view != formView || p == nil || !p.running || p.address != msg.address {
+		return m, nil
+	}
+	p.running = false
+	p.err = msg.err
+	return m, nil
+}
+
+// Renders the connection test status shown under the port input
+func (p *probeState) View() string {
+	pendingStyle := lg.NewStyle().
+		Foreground(theme.Muted).
+		Margin(0, 0, 0, 4)
+
+	okStyle := lg.NewStyle().
+		Foreground(theme.Success).
+		Margin(0, 0, 0, 4)
+
+	failStyle := lg.NewStyle().
+		Foreground(theme.Error).
+		Margin(0, 0, 0, 4)
+
+	switch {
+	case p.running:
+		return pendingStyle.Render("Testing connection to "+p.address+"...") + "\n"
+	case p.err != nil:
+		return failStyle.Render("✗ "+p.err.Error()) + "\n"
+	default:
+		return okStyle.Render("✓ "+p.address+" is reachable") + "\n"
+	}
+}
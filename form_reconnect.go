@@ -0,0 +1,129 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	lg "github.com/charmbracelet/lipgloss"
+	"github.com/nathanlytang/rolodex/internal/logger"
+	"github.com/nathanlytang/rolodex/internal/ssh"
+)
+
+// Default number of reconnect attempts after a dropped connection
+const defaultMaxReconnectAttempts = 5
+
+// Longest wait between reconnect attempts
+const maxReconnectBackoff = 30 * time.Second
+
+// Key map for connection lost view
+type reconnectKeyMap struct {
+	Retry  key.Binding
+	Cancel key.Binding
+}
+
+func (k reconnectKeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.Retry, k.Cancel}
+}
+
+func (k reconnectKeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{
+		{k.Retry, k.Cancel},
+	}
+}
+
+var reconnectKeys = reconnectKeyMap{
+	Retry: key.NewBinding(
+		key.WithKeys("y", "Y"),
+		key.WithHelp("y", "reconnect"),
+	),
+	Cancel: key.NewBinding(
+		key.WithKeys("n", "N", "esc"),
+		key.WithHelp("n/esc", "back to list"),
+	),
+}
+
+func (m Model) updateReconnect(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "y", "Y":
+		// Reconnect with backoff once we leave the TUI
+		h := m.disconnectHost
+		m.disconnectErr = nil
+		m.disconnectHost = nil
+		if h == nil {
+			m.view = listView
+			return m, nil
+		}
+		m.reconnect = true
+		return m.connect(h)
+
+	case "n", "N", "esc":
+		// Return to list
+		m.view = listView
+		m.disconnectErr = nil
+		m.disconnectHost = nil
+		return m, nil
+	}
+
+	return m, nil
+}
+
+func (m Model) renderReconnect() string {
+	titleStyle := lg.NewStyle().
+		Bold(true).
+		Foreground(theme.TitleText).
+		Background(theme.TitleBackground).
+		Padding(0, 1).
+		Margin(0, 0, 0, 2)
+
+	errorStyle := lg.NewStyle().
+		Foreground(theme.Error).
+		Padding(0, 2)
+
+	infoStyle := lg.NewStyle().
+		Foreground(theme.Text).
+		Padding(0, 2)
+
+	helpRendered, availHeight := m.renderFormHelp(reconnectKeys)
+
+	var title string
+	title = titleStyle.Render("Connection Lost") + "\n\n"
+	availHeight -= lg.Height(title)
+	var b string
+
+	if m.disconnectErr != nil {
+		b += errorStyle.Render(m.disconnectErr.Error()) + "\n\n"
+	}
+	if m.disconnectHost != nil {
+		b += infoStyle.Render(fmt.Sprintf("Reconnect to %s?", m.disconnectHost.Name)) + "\n\n"
+	}
+
+	return m.calculateVisibleFormContent(availHeight, b, title, helpRendered, m.getVisibleDeleteLines)
+}
+
+// Runs connect with exponential backoff (1s, 2s, 4s, ... capped at maxReconnectBackoff)
+// Stops early on success, an unknown host key, or a session that dropped again
+func reconnectWithBackoff(attempts int, connect func() error) error {
+	backoff := time.Second
+	var err error
+
+	for attempt := 1; attempt <= attempts; attempt++ {
+		fmt.Printf("Reconnecting in %v (attempt %d/%d)...\r\n", backoff, attempt, attempts)
+		time.Sleep(backoff)
+		backoff = min(backoff*2, maxReconnectBackoff)
+
+		err = connect()
+		var unknownErr *ssh.UnknownHostKeyError
+		var disconnectErr *ssh.DisconnectError
+		if err == nil || errors.As(err, &unknownErr) || errors.As(err, &disconnectErr) {
+			return err
+		}
+
+		logger.Printf("Reconnect attempt %d/%d failed: %v", attempt, attempts, err)
+		fmt.Printf("Attempt %d failed: %v\r\n", attempt, err)
+	}
+
+	return fmt.Errorf("gave up after %d reconnect attempts: %w", attempts, err)
+}
@@ -0,0 +1,533 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	lg "github.com/charmbracelet/lipgloss"
+	"github.com/nathanlytang/rolodex/internal/logger"
+	"github.com/nathanlytang/rolodex/internal/ssh"
+)
+
+// Key map for settings view
+type settingsKeyMap struct {
+	Navigate key.Binding
+	Toggle   key.Binding
+	Save     key.Binding
+	Cancel   key.Binding
+}
+
+func (k settingsKeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.Navigate, k.Toggle, k.Save, k.Cancel}
+}
+
+func (k settingsKeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{
+		{k.Navigate, k.Toggle, k.Save, k.Cancel},
+	}
+}
+
+var settingsKeys = settingsKeyMap{
+	Navigate: key.NewBinding(
+		key.WithKeys("tab", "shift+tab", "up", "down"),
+		key.WithHelp("tab/↑↓", "navigate"),
+	),
+	Toggle: key.NewBinding(
+		key.WithKeys(" "),
+		key.WithHelp("space", "toggle option"),
+	),
+	Save: key.NewBinding(
+		key.WithKeys("enter"),
+		key.WithHelp("⏎", "save"),
+	),
+	Cancel: key.NewBinding(
+		key.WithKeys("esc"),
+		key.WithHelp("esc", "cancel"),
+	),
+}
+
+// A global option editable in the settings view
+type settingField struct {
+	label   string
+	choices []string // Values cycled with space instead of typed, nil for free text
+	get     func(s Settings) string
+	set     func(s *Settings, value string) error
+}
+
+var settingFields = []settingField{
+	{
+		label: "Known Hosts File",
+		get:   func(s Settings) string { return s.KnownHostsFile },
+		set: func(s *Settings, v string) error {
+			s.KnownHostsFile = v
+			return nil
+		},
+	},
+	{
+		label:   "Host Key Policy",
+		choices: ssh.HostKeyPolicies(),
+		get: func(s Settings) string {
+			if s.HostKeyPolicy == "" {
+				return ssh.HostKeyPolicyTOFU
+			}
+			return s.HostKeyPolicy
+		},
+		set: func(s *Settings, v string) error {
+			if !slices.Contains(ssh.HostKeyPolicies(), v) {
+				return fmt.Errorf("unknown host key policy %q", v)
+			}
+			s.HostKeyPolicy = v
+			return nil
+		},
+	},
+	{
+		label:   "Strict Key Permissions",
+		choices: []string{"false", "true"},
+		get:     func(s Settings) string { return strconv.FormatBool(s.StrictKeyPermissions) },
+		set: func(s *Settings, v string) error {
+			s.StrictKeyPermissions = v == "true"
+			return nil
+		},
+	},
+	{
+		label: "Agent Key Lifetime (seconds)",
+		get:   func(s Settings) string { return formatIntSetting(s.AgentKeyLifetime) },
+		set: func(s *Settings, v string) (err error) {
+			s.AgentKeyLifetime, err = parseIntSetting("agent key lifetime", v, 1, 604800)
+			return err
+		},
+	},
+	{
+		label: "Default Port",
+		get:   func(s Settings) string { return formatIntSetting(s.DefaultPort) },
+		set: func(s *Settings, v string) (err error) {
+			s.DefaultPort, err = parseIntSetting("default port", v, 1, 65535)
+			return err
+		},
+	},
+	{
+		label: "Default User",
+		get:   func(s Settings) string { return s.DefaultUser },
+		set: func(s *Settings, v string) error {
+			s.DefaultUser = v
+			return nil
+		},
+	},
+	{
+		label: "Default Identity File",
+		get:   func(s Settings) string { return s.DefaultIdentityFile },
+		set: func(s *Settings, v string) error {
+			s.DefaultIdentityFile = v
+			return nil
+		},
+	},
+	{
+		label: "Default Jump Host (name of a saved host)",
+		get:   func(s Settings) string { return s.DefaultJumpHost },
+		set: func(s *Settings, v string) error {
+			s.DefaultJumpHost = v
+			return nil
+		},
+	},
+	{
+		label: "Proxy (socks5:// or http:// URL)",
+		get:   func(s Settings) string { return s.Proxy },
+		set: func(s *Settings, v string) error {
+			if v != "" {
+				if _, err := ssh.ParseProxy(v); err != nil {
+					return err
+				}
+			}
+			s.Proxy = v
+			return nil
+		},
+	},
+	{
+		label: "Connect Timeout (seconds)",
+		get:   func(s Settings) string { return formatIntSetting(s.ConnectTimeout) },
+		set: func(s *Settings, v string) (err error) {
+			s.ConnectTimeout, err = parseIntSetting("connect timeout", v, 1, 3600)
+			return err
+		},
+	},
+	{
+		label: "Max Reconnect Attempts",
+		get:   func(s Settings) string { return formatIntSetting(s.MaxReconnectAttempts) },
+		set: func(s *Settings, v string) (err error) {
+			s.MaxReconnectAttempts, err = parseIntSetting("max reconnect attempts", v, 1, 100)
+			return err
+		},
+	},
+	{
+		label:   "Sort Mode",
+		choices: []string{string(sortConfig), string(sortName), string(sortRecent)},
+		get:     func(s Settings) string { return string(parseSortMode(s.SortMode)) },
+		set: func(s *Settings, v string) error {
+			s.SortMode = v
+			return nil
+		},
+	},
+	{
+		label:   "Filter Mode",
+		choices: []string{string(filterFuzzy), string(filterSubstring)},
+		get:     func(s Settings) string { return string(parseFilterMode(s.FilterMode)) },
+		set: func(s *Settings, v string) error {
+			s.FilterMode = v
+			return nil
+		},
+	},
+	{
+		label:   "Log Level",
+		choices: []string{"debug", "info", "error"},
+		get: func(s Settings) string {
+			if s.LogLevel == "" {
+				return "info"
+			}
+			return s.LogLevel
+		},
+		set: func(s *Settings, v string) error {
+			if _, err := logger.ParseLevel(v); err != nil {
+				return err
+			}
+			s.LogLevel = v
+			return nil
+		},
+	},
+	{
+		label: "Max Log Age (days)",
+		get:   func(s Settings) string { return formatIntSetting(s.MaxLogAge) },
+		set: func(s *Settings, v string) (err error) {
+			s.MaxLogAge, err = parseIntSetting("max log age", v, 1, 3650)
+			return err
+		},
+	},
+	{
+		label: "Max Log Files",
+		get:   func(s Settings) string { return formatIntSetting(s.MaxLogFiles) },
+		set: func(s *Settings, v string) (err error) {
+			s.MaxLogFiles, err = parseIntSetting("max log files", v, 1, 10000)
+			return err
+		},
+	},
+	{
+		label: "Health Check Interval (seconds)",
+		get:   func(s Settings) string { return formatIntSetting(s.HealthCheckInterval) },
+		set: func(s *Settings, v string) (err error) {
+			s.HealthCheckInterval, err = parseIntSetting("health check interval", v, 5, 86400)
+			return err
+		},
+	},
+	{
+		label:   "Mouse Support",
+		choices: []string{"false", "true"},
+		get:     func(s Settings) string { return strconv.FormatBool(s.Mouse) },
+		set: func(s *Settings, v string) error {
+			s.Mouse = v == "true"
+			return nil
+		},
+	},
+	{
+		label:   "Compact List",
+		choices: []string{"false", "true"},
+		get:     func(s Settings) string { return strconv.FormatBool(s.CompactList) },
+		set: func(s *Settings, v string) error {
+			s.CompactList = v == "true"
+			return nil
+		},
+	},
+	{
+		label:   "Confirm Delete",
+		choices: []string{"false", "true"},
+		get:     func(s Settings) string { return strconv.FormatBool(s.confirmsDelete()) },
+		set: func(s *Settings, v string) error {
+			// Asking is the default, so only turning it off is written to config.json
+			s.ConfirmDelete = nil
+			if v == "false" {
+				s.ConfirmDelete = new(bool)
+			}
+			return nil
+		},
+	},
+	{
+		label:   "Acknowledge Server Banners",
+		choices: []string{"false", "true"},
+		get:     func(s Settings) string { return strconv.FormatBool(s.AcknowledgeBanners) },
+		set: func(s *Settings, v string) error {
+			s.AcknowledgeBanners = v == "true"
+			return nil
+		},
+	},
+	{
+		label:   "Theme",
+		choices: themeNames(),
+		get: func(s Settings) string {
+			if s.Theme == nil || s.Theme.Name == "" {
+				return autoThemeName
+			}
+			return s.Theme.Name
+		},
+		set: func(s *Settings, v string) error {
+			if s.Theme == nil && v == autoThemeName {
+				return nil
+			}
+			// Copy so custom colors are kept without changing the loaded settings
+			t := Theme{}
+			if s.Theme != nil {
+				t = *s.Theme
+			}
+			t.Name = v
+			s.Theme = &t
+			return nil
+		},
+	},
+	{
+		label: "Confirm Tags (comma-separated)",
+		get:   func(s Settings) string { return strings.Join(s.ConfirmTags, ", ") },
+		set: func(s *Settings, v string) error {
+			s.ConfirmTags = parseTags(v)
+			return nil
+		},
+	},
+	{
+		label: "Filter Fields (comma-separated)",
+		get:   func(s Settings) string { return strings.Join(s.FilterFields, ", ") },
+		set: func(s *Settings, v string) error {
+			fields := parseTags(v)
+			for _, f := range fields {
+				if !slices.Contains(defaultFilterFields, f) {
+					return fmt.Errorf("unknown filter field %q, expected one of %s", f, strings.Join(defaultFilterFields, ", "))
+				}
+			}
+			s.FilterFields = fields
+			return nil
+		},
+	},
+	{
+		label: "Forward Env (comma-separated, e.g. LANG, COLORTERM)",
+		get:   func(s Settings) string { return strings.Join(s.ForwardEnv, ", ") },
+		set: func(s *Settings, v string) error {
+			names := parseTags(v)
+			for _, name := range names {
+				if strings.ContainsAny(name, "= \t") {
+					return fmt.Errorf("invalid variable name %q", name)
+				}
+			}
+			s.ForwardEnv = names
+			return nil
+		},
+	},
+}
+
+// Formats an integer setting, leaving unset values blank
+func formatIntSetting(n int) string {
+	if n == 0 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
+
+// Parses an integer setting within [lo, hi], blank means unset
+func parseIntSetting(name, v string, lo, hi int) (int, error) {
+	if v == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < lo || n > hi {
+		return 0, fmt.Errorf("%s must be a number between %d and %d", name, lo, hi)
+	}
+	return n, nil
+}
+
+// State of the settings view
+type settingsModel struct {
+	inputs     []textinput.Model
+	focusIndex int
+	base       Settings // Settings as loaded, keeps options the view doesn't expose
+	err        error    // Validation error shown under the focused field
+}
+
+// Loads the current settings and shows the settings view
+func (m Model) openSettings() (tea.Model, tea.Cmd) {
+	config, err := readConfig(m.configPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		m.err = err
+		m.showErr = true
+		return m, nil
+	}
+
+	inputs := make([]textinput.Model, len(settingFields))
+	for i, field := range settingFields {
+		t := textinput.New()
+		t.Prompt = "> "
+		t.PromptStyle = lg.NewStyle().Foreground(theme.Accent).Margin(0, 0, 0, 2)
+		t.CharLimit = 256
+		t.SetValue(field.get(config.Settings))
+		if i == 0 {
+			t.Focus()
+		}
+		inputs[i] = t
+	}
+
+	m.settings = settingsModel{inputs: inputs, base: config.Settings}
+	m.view = settingsView
+	return m, textinput.Blink
+}
+
+func (m Model) updateSettings(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	s := &m.settings
+	field := settingFields[s.focusIndex]
+
+	switch msg.String() {
+	case "esc":
+		// Discard changes and return to list
+		m.view = listView
+		return m, nil
+
+	case "tab", "shift+tab", "up", "down":
+		s.err = nil
+		if msg.String() == "up" || msg.String() == "shift+tab" {
+			s.focusIndex = (s.focusIndex - 1 + len(s.inputs)) % len(s.inputs)
+		} else {
+			s.focusIndex = (s.focusIndex + 1) % len(s.inputs)
+		}
+
+		cmds := make([]tea.Cmd, len(s.inputs))
+		for i := range s.inputs {
+			if i == s.focusIndex {
+				cmds[i] = s.inputs[i].Focus()
+			} else {
+				s.inputs[i].Blur()
+			}
+		}
+		return m, tea.Batch(cmds...)
+
+	case " ":
+		// Cycle options; free text fields take the space as input
+		if field.choices != nil {
+			input := &s.inputs[s.focusIndex]
+			i := slices.Index(field.choices, input.Value())
+			input.SetValue(field.choices[(i+1)%len(field.choices)])
+			return m, nil
+		}
+
+	case "enter":
+		settings := s.base
+		for i, field := range settingFields {
+			if err := field.set(&settings, strings.TrimSpace(s.inputs[i].Value())); err != nil {
+				s.err = err
+				s.focusIndex = i
+				for j := range s.inputs {
+					s.inputs[j].Blur()
+				}
+				return m, s.inputs[i].Focus()
+			}
+		}
+
+		if err := saveSettings(m.configPath, settings); err != nil {
+			s.err = err
+			return m, nil
+		}
+
+		m, cmd := m.applySettings(settings)
+		m.view = listView
+		return m, tea.Batch(m.list.SetItems(m.buildItems()), m.list.NewStatusMessage("Settings saved"), cmd)
+	}
+
+	// Options can only be cycled, not typed
+	if field.choices != nil {
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	s.inputs[s.focusIndex], cmd = s.inputs[s.focusIndex].Update(msg)
+	return m, cmd
+}
+
+// Applies settings to the running list, leaving its items to be rebuilt by the caller
+func (m Model) applySettings(settings Settings) (Model, tea.Cmd) {
+	m.filterFields = settings.FilterFields
+	if len(m.filterFields) == 0 {
+		m.filterFields = defaultFilterFields
+	}
+	m.sortMode = parseSortMode(settings.SortMode)
+	m.filterMode = parseFilterMode(settings.FilterMode)
+	m.list.Filter = m.filterMode.filterFunc()
+	m.confirmTags = settings.ConfirmTags
+	m.confirmDelete = settings.confirmsDelete()
+	m.defaultPort = settings.DefaultPort
+	m.defaultUser = settings.DefaultUser
+	m.defaultIdentity = settings.DefaultIdentityFile
+	m.strictKeyPermissions = settings.StrictKeyPermissions
+	setLogLevel(settings.LogLevel)
+	theme = loadTheme(settings.Theme)
+	applyListTheme(&m.list)
+	m.compactList = settings.CompactList
+	m.defaultJumpHost = settings.DefaultJumpHost
+	m.defaultProxy = settings.Proxy
+	m.list.SetDelegate(m.listDelegate())
+
+	var cmds []tea.Cmd
+	var healthCmd tea.Cmd
+	m, healthCmd = m.setHealthCheckInterval(time.Duration(settings.HealthCheckInterval) * time.Second)
+	cmds = append(cmds, healthCmd)
+	if settings.Mouse != m.mouse {
+		m.mouse = settings.Mouse
+		if m.mouse {
+			cmds = append(cmds, tea.EnableMouseCellMotion)
+		} else {
+			cmds = append(cmds, tea.DisableMouse)
+		}
+	}
+	return m, tea.Batch(cmds...)
+}
+
+func (m Model) renderSettings() string {
+	titleStyle := lg.NewStyle().
+		Bold(true).
+		Foreground(theme.TitleText).
+		Background(theme.TitleBackground).
+		Padding(0, 1).
+		Margin(0, 0, 0, 2)
+
+	labelStyle := lg.NewStyle().
+		Foreground(theme.Text).
+		Bold(true).
+		Margin(0, 0, 0, 2)
+
+	optionStyle := lg.NewStyle().
+		Foreground(theme.Muted)
+
+	errStyle := lg.NewStyle().
+		Foreground(theme.Error).
+		Margin(0, 0, 0, 2)
+
+	helpRendered, availHeight := m.renderFormHelp(settingsKeys)
+
+	var title string
+	title = titleStyle.Render("Settings") + "\n\n"
+	availHeight -= lg.Height(title)
+	var b string
+
+	for i, field := range settingFields {
+		labelText := labelStyle.Render(focusMarker(i == m.settings.focusIndex) + field.label)
+		if field.choices != nil {
+			labelText += " " + optionStyle.Render("("+strings.Join(field.choices, "/")+")")
+		}
+		b += labelText + "\n"
+		b += m.settings.inputs[i].View() + "\n"
+		if i == m.settings.focusIndex && m.settings.err != nil {
+			b += errStyle.Render(m.settings.err.Error()) + "\n"
+		}
+		b += "\n"
+	}
+
+	return m.calculateVisibleFormContent(availHeight, b, title, helpRendered, m.getVisibleDeleteLines)
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Returns the settings field whose label starts with prefix
+func settingFieldByLabel(t *testing.T, prefix string) settingField {
+	t.Helper()
+	for _, field := range settingFields {
+		if strings.HasPrefix(field.label, prefix) {
+			return field
+		}
+	}
+	t.Fatalf("no settings field labelled %q", prefix)
+	return settingField{}
+}
+
+func TestForwardEnvSetting(t *testing.T) {
+	field := settingFieldByLabel(t, "Forward Env")
+	tests := []struct {
+		value   string
+		want    []string
+		wantErr bool
+	}{
+		{value: "", want: nil},
+		{value: "LANG", want: []string{"LANG"}},
+		{value: " LANG , COLORTERM,,", want: []string{"LANG", "COLORTERM"}},
+		{value: "LANG=C", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			var s Settings
+			err := field.set(&s, tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("set(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if err == nil && len(s.ForwardEnv)+len(tt.want) > 0 && !reflect.DeepEqual(s.ForwardEnv, tt.want) {
+				t.Errorf("set(%q) ForwardEnv = %q, want %q", tt.value, s.ForwardEnv, tt.want)
+			}
+			if err == nil {
+				if got := field.get(s); got != strings.Join(tt.want, ", ") {
+					t.Errorf("get() = %q, want %q", got, strings.Join(tt.want, ", "))
+				}
+			}
+		})
+	}
+}
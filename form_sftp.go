@@ -0,0 +1,488 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	lg "github.com/charmbracelet/lipgloss"
+	"github.com/nathanlytang/rolodex/internal/ssh"
+	"github.com/pkg/sftp"
+)
+
+// Key map for the SFTP file browser
+type sftpKeyMap struct {
+	Navigate key.Binding
+	Open     key.Binding
+	Parent   key.Binding
+	Switch   key.Binding
+	Transfer key.Binding
+	Quit     key.Binding
+}
+
+func (k sftpKeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.Navigate, k.Open, k.Parent, k.Switch, k.Transfer, k.Quit}
+}
+
+func (k sftpKeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{
+		{k.Navigate, k.Open, k.Parent, k.Switch, k.Transfer, k.Quit},
+	}
+}
+
+var sftpKeys = sftpKeyMap{
+	Navigate: key.NewBinding(
+		key.WithKeys("up", "down"),
+		key.WithHelp("↑/↓", "navigate"),
+	),
+	Open: key.NewBinding(
+		key.WithKeys("enter"),
+		key.WithHelp("⏎", "open folder"),
+	),
+	Parent: key.NewBinding(
+		key.WithKeys("backspace"),
+		key.WithHelp("backspace", "parent folder"),
+	),
+	Switch: key.NewBinding(
+		key.WithKeys("tab"),
+		key.WithHelp("tab", "switch pane"),
+	),
+	Transfer: key.NewBinding(
+		key.WithKeys("c"),
+		key.WithHelp("c", "copy to other pane"),
+	),
+	Quit: key.NewBinding(
+		key.WithKeys("esc", "q"),
+		key.WithHelp("esc", "close"),
+	),
+}
+
+// A file or folder shown in a browser pane
+type sftpEntry struct {
+	name  string
+	isDir bool
+	size  int64
+}
+
+// One side of the file browser, listing either local or remote files
+type sftpPane struct {
+	title   string
+	dir     string
+	entries []sftpEntry
+	cursor  int
+	offset  int // First visible entry
+	remote  bool
+}
+
+// Selected entry, or nil when the folder is empty
+func (p *sftpPane) selected() *sftpEntry {
+	if p.cursor < len(p.entries) {
+		return &p.entries[p.cursor]
+	}
+	return nil
+}
+
+// Joins a name onto the pane's directory using the right separator for its side
+func (p *sftpPane) join(name string) string {
+	if p.remote {
+		return path.Join(p.dir, name)
+	}
+	return filepath.Join(p.dir, name)
+}
+
+// Returns the pane's parent directory
+func (p *sftpPane) parent() string {
+	if p.remote {
+		return path.Dir(p.dir)
+	}
+	return filepath.Dir(p.dir)
+}
+
+// Progress of the running transfer
+type sftpProgressMsg struct {
+	done, total int64
+}
+
+// Result of a finished transfer
+type sftpTransferDoneMsg struct {
+	err error
+}
+
+// Standalone Bubble Tea model that browses local and remote files over SFTP
+// It runs in place of a shell session, after the host list has exited
+type sftpModel struct {
+	client   *sftp.Client
+	host     string
+	panes    [2]sftpPane // Local on the left, remote on the right
+	active   int
+	width    int
+	height   int
+	status   string
+	progress chan tea.Msg // Transfer progress and completion, nil when idle
+	transfer string       // Name of the file being copied
+}
+
+func newSFTPModel(client *sftp.Client, host string) (sftpModel, error) {
+	localDir, err := os.Getwd()
+	if err != nil {
+		return sftpModel{}, err
+	}
+	remoteDir, err := client.Getwd()
+	if err != nil {
+		return sftpModel{}, err
+	}
+
+	m := sftpModel{
+		client: client,
+		host:   host,
+		panes: [2]sftpPane{
+			{title: "Local", dir: localDir},
+			{title: host, dir: remoteDir, remote: true},
+		},
+		active: 1,
+	}
+	for i := range m.panes {
+		if err := m.load(i, m.panes[i].dir); err != nil {
+			return sftpModel{}, err
+		}
+	}
+	return m, nil
+}
+
+// Lists dir into pane i, folders first
+func (m *sftpModel) load(i int, dir string) error {
+	var infos []os.FileInfo
+	var err error
+	if m.panes[i].remote {
+		infos, err = m.client.ReadDir(dir)
+	} else {
+		var entries []os.DirEntry
+		entries, err = os.ReadDir(dir)
+		for _, e := range entries {
+			if info, infoErr := e.Info(); infoErr == nil {
+				infos = append(infos, info)
+			}
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	entries := []sftpEntry{{name: "..", isDir: true}}
+	for _, info := range infos {
+		entries = append(entries, sftpEntry{name: info.Name(), isDir: info.IsDir(), size: info.Size()})
+	}
+	slices.SortFunc(entries[1:], func(a, b sftpEntry) int {
+		if a.isDir != b.isDir {
+			if a.isDir {
+				return -1
+			}
+			return 1
+		}
+		return cmp.Compare(strings.ToLower(a.name), strings.ToLower(b.name))
+	})
+
+	m.panes[i].dir = dir
+	m.panes[i].entries = entries
+	m.panes[i].cursor = 0
+	m.panes[i].offset = 0
+	return nil
+}
+
+func (m sftpModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m sftpModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		return m, nil
+
+	case sftpProgressMsg:
+		m.status = fmt.Sprintf("Copying %s: %s", m.transfer, formatProgress(msg.done, msg.total))
+		return m, waitForTransfer(m.progress)
+
+	case sftpTransferDoneMsg:
+		m.progress = nil
+		if msg.err != nil {
+			m.status = msg.err.Error()
+		} else {
+			m.status = "Copied " + m.transfer
+		}
+		// Refresh the destination pane
+		dest := 1 - m.active
+		if err := m.load(dest, m.panes[dest].dir); err != nil {
+			m.status = err.Error()
+		}
+		return m, nil
+
+	case tea.KeyMsg:
+		return m.updateKeys(msg)
+	}
+	return m, nil
+}
+
+func (m sftpModel) updateKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	p := &m.panes[m.active]
+
+	switch msg.String() {
+	case "ctrl+c", "esc", "q":
+		if m.progress != nil {
+			m.status = "Wait for the current copy to finish"
+			return m, nil
+		}
+		return m, tea.Quit
+
+	case "tab":
+		m.active = 1 - m.active
+
+	case "up":
+		if p.cursor > 0 {
+			p.cursor--
+		}
+
+	case "down":
+		if p.cursor < len(p.entries)-1 {
+			p.cursor++
+		}
+
+	case "backspace":
+		if err := m.load(m.active, p.parent()); err != nil {
+			m.status = err.Error()
+		}
+
+	case "enter":
+		e := p.selected()
+		if e == nil || !e.isDir {
+			return m, nil
+		}
+		dir := p.parent()
+		if e.name != ".." {
+			dir = p.join(e.name)
+		}
+		if err := m.load(m.active, dir); err != nil {
+			m.status = err.Error()
+		}
+
+	case "c":
+		return m.startTransfer()
+	}
+	return m, nil
+}
+
+// Copies the selected file to the other pane's folder in the background
+func (m sftpModel) startTransfer() (tea.Model, tea.Cmd) {
+	if m.progress != nil {
+		m.status = "A copy is already running"
+		return m, nil
+	}
+
+	src, dst := &m.panes[m.active], &m.panes[1-m.active]
+	e := src.selected()
+	if e == nil || e.isDir {
+		m.status = "Select a file to copy"
+		return m, nil
+	}
+
+	from, to, download := src.join(e.name), dst.join(e.name), src.remote
+	progress := make(chan tea.Msg, 1)
+	m.progress = progress
+	m.transfer = e.name
+	m.status = "Copying " + e.name + "..."
+
+	go func() {
+		report := func(done, total int64) {
+			// Drop updates while the TUI is still drawing the last one
+			select {
+			case progress <- sftpProgressMsg{done: done, total: total}:
+			default:
+			}
+		}
+
+		var err error
+		if download {
+			err = ssh.Download(m.client, from, to, report)
+		} else {
+			err = ssh.Upload(m.client, from, to, report)
+		}
+
+		// Drain a pending progress update so completion is never dropped
+		select {
+		case <-progress:
+		default:
+		}
+		progress <- sftpTransferDoneMsg{err: err}
+	}()
+
+	return m, waitForTransfer(progress)
+}
+
+// Returns a command that waits for the next transfer update
+func waitForTransfer(progress chan tea.Msg) tea.Cmd {
+	if progress == nil {
+		return nil
+	}
+	return func() tea.Msg {
+		return <-progress
+	}
+}
+
+// Formats a byte count like 1.2 MB
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for n/div >= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// Formats transfer progress like 45% (1.2 MB / 2.6 MB)
+func formatProgress(done, total int64) string {
+	if total <= 0 {
+		return formatBytes(done)
+	}
+	return fmt.Sprintf("%d%% (%s / %s)", done*100/total, formatBytes(done), formatBytes(total))
+}
+
+func (m sftpModel) View() string {
+	titleStyle := lg.NewStyle().
+		Bold(true).
+		Foreground(theme.TitleText).
+		Background(theme.TitleBackground).
+		Padding(0, 1).
+		Margin(0, 0, 0, 2)
+
+	statusStyle := lg.NewStyle().
+		Foreground(theme.Muted).
+		Padding(1, 2, 0, 2)
+
+	helpStyle := lg.NewStyle().
+		Padding(1, 0, 0, 2)
+
+	width, height := m.width, m.height
+	if width == 0 {
+		width, height = 80, 24 // fallback
+	}
+	_, v := docStyle.GetFrameSize()
+	h, _ := docStyle.GetFrameSize()
+
+	title := titleStyle.Render("Files on "+m.host) + "\n"
+	status := statusStyle.Render(m.status)
+	helpModel := help.New()
+	helpModel.Width = width - h - helpStyle.GetHorizontalFrameSize()
+	helpRendered := helpStyle.Render(helpModel.View(sftpKeys))
+
+	paneHeight := height - v - lg.Height(title) - lg.Height(status) - lg.Height(helpRendered)
+	paneWidth := (width-h)/2 - 1
+
+	left := m.renderPane(0, paneWidth, paneHeight)
+	right := m.renderPane(1, paneWidth, paneHeight)
+	panes := lg.JoinHorizontal(lg.Top, left, " ", right)
+
+	return docStyle.Render(lg.JoinVertical(lg.Left, title, panes, status, helpRendered))
+}
+
+// Renders pane i with a border, highlighting it when active
+func (m *sftpModel) renderPane(i, width, height int) string {
+	p := &m.panes[i]
+
+	border, borderColor := lg.RoundedBorder(), theme.Border
+	if i == m.active {
+		borderColor = theme.ActiveBorder
+		if noColor {
+			border = lg.ThickBorder()
+		}
+	}
+	boxStyle := lg.NewStyle().
+		Border(border).
+		BorderForeground(borderColor).
+		Width(max(width-2, 10)).
+		Height(max(height-2, 3))
+
+	headerStyle := lg.NewStyle().
+		Foreground(theme.Header).
+		Bold(true)
+
+	dirStyle := lg.NewStyle().
+		Foreground(theme.Accent).
+		Bold(true)
+
+	fileStyle := lg.NewStyle().
+		Foreground(theme.Text)
+
+	selectedStyle := lg.NewStyle().
+		Foreground(theme.Highlight).
+		Bold(true)
+
+	sizeStyle := lg.NewStyle().
+		Foreground(theme.Muted)
+
+	// Keep the cursor in view
+	rows := max(height-4, 1)
+	if p.cursor < p.offset {
+		p.offset = p.cursor
+	} else if p.cursor >= p.offset+rows {
+		p.offset = p.cursor - rows + 1
+	}
+
+	b := headerStyle.Render(p.title+": "+p.dir) + "\n\n"
+	for j := p.offset; j < min(p.offset+rows, len(p.entries)); j++ {
+		e := p.entries[j]
+		name := e.name
+		style := fileStyle
+		if e.isDir {
+			name += "/"
+			style = dirStyle
+		}
+		if j == p.cursor && i == m.active {
+			name = "▸ " + name
+			style = selectedStyle
+		} else {
+			name = "  " + name
+		}
+
+		line := style.Render(name)
+		if !e.isDir {
+			line += " " + sizeStyle.Render(formatBytes(e.size))
+		}
+		b += line + "\n"
+	}
+
+	return boxStyle.Render(strings.TrimSuffix(b, "\n"))
+}
+
+// Connects to the host and opens the file browser until the user closes it
+func browseHostFiles(h *Host, authConfig ssh.AuthConfig, sessionConfig ssh.SessionConfig) error {
+	client, err := ssh.Connect(h.Host, h.Port, h.User, authConfig, sessionConfig)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	sc, err := client.SFTP()
+	if err != nil {
+		return err
+	}
+	defer sc.Close()
+
+	model, err := newSFTPModel(sc, h.Name)
+	if err != nil {
+		return fmt.Errorf("failed to list files: %w", err)
+	}
+
+	_, err = tea.NewProgram(model, tea.WithAltScreen()).Run()
+	return err
+}
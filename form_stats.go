@@ -0,0 +1,139 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	lg "github.com/charmbracelet/lipgloss"
+)
+
+// Key map for the connection stats view
+type statsKeyMap struct {
+	Scroll key.Binding
+	Back   key.Binding
+}
+
+func (k statsKeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.Scroll, k.Back}
+}
+
+func (k statsKeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{
+		{k.Scroll, k.Back},
+	}
+}
+
+var statsKeys = statsKeyMap{
+	Scroll: key.NewBinding(
+		key.WithKeys("up", "down"),
+		key.WithHelp("↑/↓", "scroll"),
+	),
+	Back: key.NewBinding(
+		key.WithKeys("esc", "q"),
+		key.WithHelp("esc", "back to list"),
+	),
+}
+
+// Returns the hosts that have been connected to, most time connected first
+func (m Model) statsHosts() []Host {
+	var hosts []Host
+	for _, h := range m.allHosts() {
+		if h.ConnectCount > 0 {
+			hosts = append(hosts, h)
+		}
+	}
+	slices.SortStableFunc(hosts, func(a, b Host) int {
+		return cmp.Or(
+			cmp.Compare(b.TotalDuration, a.TotalDuration),
+			cmp.Compare(b.ConnectCount, a.ConnectCount),
+			cmp.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name)),
+		)
+	})
+	return hosts
+}
+
+func (m Model) updateStats(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "up":
+		if m.statsOffset > 0 {
+			m.statsOffset--
+		}
+	case "down":
+		if m.statsOffset < len(m.statsHosts())-1 {
+			m.statsOffset++
+		}
+	case "esc", "q":
+		m.view = listView
+		m.statsOffset = 0
+	}
+	return m, nil
+}
+
+// Formats a duration in seconds like 3h 12m, dropping seconds past an hour
+func formatDuration(seconds int) string {
+	d := time.Duration(seconds) * time.Second
+	switch {
+	case d >= time.Hour:
+		return fmt.Sprintf("%dh %dm", int(d.Hours()), int(d.Minutes())%60)
+	case d >= time.Minute:
+		return fmt.Sprintf("%dm %ds", int(d.Minutes()), int(d.Seconds())%60)
+	}
+	return fmt.Sprintf("%ds", seconds)
+}
+
+func (m Model) renderStats() string {
+	titleStyle := lg.NewStyle().
+		Bold(true).
+		Foreground(theme.TitleText).
+		Background(theme.TitleBackground).
+		Padding(0, 1).
+		Margin(0, 0, 0, 2)
+
+	headerStyle := lg.NewStyle().
+		Foreground(theme.Header).
+		Bold(true).
+		Margin(0, 0, 0, 2)
+
+	rowStyle := lg.NewStyle().
+		Foreground(theme.Text).
+		Margin(0, 0, 0, 2)
+
+	emptyStyle := lg.NewStyle().
+		Foreground(theme.Muted).
+		Margin(0, 0, 0, 2)
+
+	helpRendered, availHeight := m.renderFormHelp(statsKeys)
+
+	title := titleStyle.Render("Connection Stats") + "\n\n"
+	availHeight -= lg.Height(title)
+
+	hosts := m.statsHosts()
+	if len(hosts) == 0 {
+		b := emptyStyle.Render("No sessions recorded yet.")
+		return m.calculateVisibleFormContent(availHeight, b, title, helpRendered, m.getVisibleDeleteLines)
+	}
+
+	nameWidth := len("Host")
+	for _, h := range hosts {
+		nameWidth = max(nameWidth, lg.Width(h.Name))
+	}
+	row := func(name, total, count, last string) string {
+		return fmt.Sprintf("%-*s  %10s  %11s  %s", nameWidth, name, total, count, last)
+	}
+
+	b := headerStyle.Render(row("Host", "Total", "Connections", "Last")) + "\n"
+	for _, h := range hosts[min(m.statsOffset, len(hosts)-1):] {
+		last := "never"
+		if !h.LastConnected.IsZero() {
+			last = relativeTime(h.LastConnected)
+		}
+		b += rowStyle.Render(row(h.Name, formatDuration(h.TotalDuration), fmt.Sprint(h.ConnectCount), last)) + "\n"
+	}
+
+	return m.calculateVisibleFormContent(availHeight, b, title, helpRendered, m.getVisibleDeleteLines)
+}
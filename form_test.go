@@ -0,0 +1,200 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestWriteConfigKeepsMode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file modes aren't enforced on Windows")
+	}
+	tests := []struct {
+		name     string
+		existing os.FileMode // 0 for no existing config
+		want     os.FileMode
+	}{
+		{"new config", 0, 0600},
+		{"private config", 0600, 0600},
+		{"group readable config", 0640, 0640},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath := filepath.Join(t.TempDir(), "config.json")
+			if tt.existing != 0 {
+				if err := os.WriteFile(configPath, []byte("{}"), tt.existing); err != nil {
+					t.Fatal(err)
+				}
+				// A stale backup left world-readable should be tightened too
+				if err := os.WriteFile(configPath+".bak", []byte("{}"), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := writeConfig(configPath, Configuration{Hosts: []Host{{Name: "web", Host: "web.example.com", Port: 22}}}); err != nil {
+				t.Fatalf("writeConfig: %v", err)
+			}
+
+			info, err := os.Stat(configPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := info.Mode().Perm(); got != tt.want {
+				t.Errorf("config mode = %o, want %o", got, tt.want)
+			}
+			if tt.existing == 0 {
+				return
+			}
+			info, err = os.Stat(configPath + ".bak")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := info.Mode().Perm(); got != tt.want {
+				t.Errorf("backup mode = %o, want %o", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetHostDisabledInConfig(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	original := Configuration{
+		Hosts:   []Host{{Name: "web", Host: "web", Port: 22, User: "alice", Tags: []string{"prod"}}},
+		Folders: []Folder{{Name: "prod", Hosts: []Host{{Name: "db", Host: "db", Port: 5432}}}},
+	}
+	if err := writeConfig(configPath, original); err != nil {
+		t.Fatal(err)
+	}
+
+	steps := []struct {
+		loc      hostLocation
+		disabled bool
+		wantWeb  bool
+		wantDB   bool
+	}{
+		{hostLocation{folder: -1, index: 0}, true, true, false},
+		{hostLocation{folder: 0, index: 0}, true, true, true},
+		{hostLocation{folder: -1, index: 0}, false, false, true},
+	}
+	for _, step := range steps {
+		if err := setHostDisabledInConfig(configPath, step.loc, step.disabled); err != nil {
+			t.Fatalf("setHostDisabledInConfig(%+v, %v): %v", step.loc, step.disabled, err)
+		}
+		config, err := readConfig(configPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		web, db := config.Hosts[0], config.Folders[0].Hosts[0]
+		if web.Disabled != step.wantWeb || db.Disabled != step.wantDB {
+			t.Errorf("after setting %+v to %v: web disabled %v, db disabled %v, want %v, %v",
+				step.loc, step.disabled, web.Disabled, db.Disabled, step.wantWeb, step.wantDB)
+		}
+		// Nothing else about the host changes
+		web.Disabled = false
+		if !reflect.DeepEqual(web, original.Hosts[0]) {
+			t.Errorf("web = %+v, want %+v apart from disabled", web, original.Hosts[0])
+		}
+	}
+
+	if err := setHostDisabledInConfig(configPath, hostLocation{folder: -1, index: 5}, true); err == nil {
+		t.Error("setHostDisabledInConfig() with an invalid index succeeded, want an error")
+	}
+}
+
+func TestConnectToDisabledHost(t *testing.T) {
+	h := &Host{Name: "web", Host: "web", Port: 22, Disabled: true}
+	err := connectInTerminal(h, &Configuration{}, filepath.Join(t.TempDir(), "config.json"))
+	if err == nil || !strings.Contains(err.Error(), "web is disabled, press ctrl+d") {
+		t.Errorf("connectInTerminal() error = %v, want one saying web is disabled", err)
+	}
+}
+
+func TestUpdateHostInConfig(t *testing.T) {
+	// Four folders fill the slice decoding the config allocates, so creating
+	// another has to move them
+	original := Configuration{
+		Hosts: []Host{{Name: "web", Host: "web", Port: 22}},
+		Folders: []Folder{
+			{Name: "A", Hosts: []Host{{Name: "a1", Host: "a1", Port: 22}, {Name: "a2", Host: "a2", Port: 22}, {Name: "a3", Host: "a3", Port: 22}}},
+			{Name: "B", Hosts: []Host{{Name: "b1", Host: "b1", Port: 22}}},
+			{Name: "C"},
+			{Name: "D"},
+		},
+	}
+	tests := []struct {
+		name    string
+		loc     hostLocation
+		folder  string
+		host    Host
+		wantLoc hostLocation
+		want    map[string][]string // Host names by folder, "" for the top level
+	}{
+		{
+			name: "edit in place", loc: hostLocation{folder: 0, index: 1}, folder: "A",
+			host:    Host{Name: "a2-renamed", Host: "a2", Port: 22},
+			wantLoc: hostLocation{folder: 0, index: 1},
+			want:    map[string][]string{"": {"web"}, "A": {"a1", "a2-renamed", "a3"}, "B": {"b1"}},
+		},
+		{
+			name: "move to an existing folder", loc: hostLocation{folder: 0, index: 0}, folder: "B",
+			host:    Host{Name: "a1", Host: "a1", Port: 22},
+			wantLoc: hostLocation{folder: 1, index: 1},
+			want:    map[string][]string{"": {"web"}, "A": {"a2", "a3"}, "B": {"b1", "a1"}},
+		},
+		{
+			name: "move to the top level", loc: hostLocation{folder: 0, index: 2}, folder: "",
+			host:    Host{Name: "a3", Host: "a3", Port: 22},
+			wantLoc: hostLocation{folder: -1, index: 1},
+			want:    map[string][]string{"": {"web", "a3"}, "A": {"a1", "a2"}, "B": {"b1"}},
+		},
+		{
+			name: "move to a new folder", loc: hostLocation{folder: 0, index: 0}, folder: "E",
+			host:    Host{Name: "a1", Host: "a1", Port: 22},
+			wantLoc: hostLocation{folder: 4, index: 0},
+			want:    map[string][]string{"": {"web"}, "A": {"a2", "a3"}, "B": {"b1"}, "E": {"a1"}},
+		},
+		{
+			name: "move from the top level to a new folder", loc: hostLocation{folder: -1, index: 0}, folder: "E",
+			host:    Host{Name: "web", Host: "web", Port: 22},
+			wantLoc: hostLocation{folder: 4, index: 0},
+			want:    map[string][]string{"A": {"a1", "a2", "a3"}, "B": {"b1"}, "E": {"web"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath := filepath.Join(t.TempDir(), "config.json")
+			if err := writeConfig(configPath, original); err != nil {
+				t.Fatal(err)
+			}
+
+			loc, err := updateHostInConfig(configPath, tt.loc, tt.folder, tt.host)
+			if err != nil {
+				t.Fatalf("updateHostInConfig: %v", err)
+			}
+			if loc != tt.wantLoc {
+				t.Errorf("updateHostInConfig() location = %+v, want %+v", loc, tt.wantLoc)
+			}
+
+			config, err := readConfig(configPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := map[string][]string{}
+			for _, h := range config.Hosts {
+				got[""] = append(got[""], h.Name)
+			}
+			for _, f := range config.Folders {
+				for _, h := range f.Hosts {
+					got[f.Name] = append(got[f.Name], h.Name)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("hosts after update = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,287 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	lg "github.com/charmbracelet/lipgloss"
+	"github.com/nathanlytang/rolodex/internal/logger"
+	"github.com/nathanlytang/rolodex/internal/ssh"
+)
+
+// Key map for the tunnels view
+type tunnelsKeyMap struct {
+	Navigate key.Binding
+	Stop     key.Binding
+	Back     key.Binding
+}
+
+func (k tunnelsKeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.Navigate, k.Stop, k.Back}
+}
+
+func (k tunnelsKeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{
+		{k.Navigate, k.Stop, k.Back},
+	}
+}
+
+var tunnelsKeys = tunnelsKeyMap{
+	Navigate: key.NewBinding(
+		key.WithKeys("up", "down"),
+		key.WithHelp("↑/↓", "navigate"),
+	),
+	Stop: key.NewBinding(
+		key.WithKeys("d", "x"),
+		key.WithHelp("d", "stop tunnel"),
+	),
+	Back: key.NewBinding(
+		key.WithKeys("esc", "q"),
+		key.WithHelp("esc", "back to list"),
+	),
+}
+
+// A running tunnel-only connection
+type activeTunnel struct {
+	id      int
+	host    Host
+	tunnel  *ssh.Tunnel
+	started time.Time
+}
+
+// Tunnels that outlive the TUI between sessions, shared by every Model main creates
+type tunnelSet struct {
+	mu      sync.Mutex
+	nextID  int
+	tunnels []*activeTunnel
+}
+
+// Registers a started tunnel and returns its entry
+func (s *tunnelSet) add(h Host, t *ssh.Tunnel) *activeTunnel {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextID++
+	at := &activeTunnel{id: s.nextID, host: h, tunnel: t, started: time.Now()}
+	s.tunnels = append(s.tunnels, at)
+	return at
+}
+
+// Forgets the tunnel with id, returning it if it was still registered
+func (s *tunnelSet) remove(id int) *activeTunnel {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := slices.IndexFunc(s.tunnels, func(t *activeTunnel) bool { return t.id == id })
+	if i < 0 {
+		return nil
+	}
+	at := s.tunnels[i]
+	s.tunnels = slices.Delete(s.tunnels, i, i+1)
+	return at
+}
+
+// Returns the running tunnels, oldest first
+func (s *tunnelSet) list() []*activeTunnel {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.tunnels)
+}
+
+// Stops every tunnel, used when rolodex exits
+func (s *tunnelSet) closeAll() {
+	for _, at := range s.list() {
+		s.remove(at.id)
+		at.tunnel.Close()
+	}
+}
+
+// Result of starting a tunnel, delivered back to Update
+type tunnelStartedMsg struct {
+	host       *Host
+	tunnel     *ssh.Tunnel
+	authMethod string // Method the server accepted, shown in the status message
+	err        error
+}
+
+// Sent when a tunnel stops on its own because the connection dropped
+type tunnelStoppedMsg struct {
+	id  int
+	err error
+}
+
+// Returns a command that connects and starts the host's forwards without a shell
+// Keyboard-interactive questions can't be asked while the TUI is running, so only
+// stored answers are used
+func startTunnel(h *Host, configPath string, password []byte) tea.Cmd {
+	return func() tea.Msg {
+		config, err := readConfig(configPath)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return tunnelStartedMsg{host: h, err: err}
+		}
+		authConfig, sessionConfig, err := connectionConfig(h, &config, password)
+		if err != nil {
+			return tunnelStartedMsg{host: h, err: err}
+		}
+		authConfig.Prompter = nil
+
+		client, err := ssh.Connect(h.Host, h.Port, h.User, authConfig, sessionConfig)
+		if err != nil {
+			return tunnelStartedMsg{host: h, err: err}
+		}
+		t, err := ssh.StartTunnel(client, sessionConfig)
+		return tunnelStartedMsg{host: h, tunnel: t, authMethod: client.AuthMethod, err: err}
+	}
+}
+
+// Returns a command that waits for a tunnel to stop on its own, or for done to close
+// The next program waits for the tunnel again, so giving up when done closes loses nothing
+func waitForTunnel(at *activeTunnel, done <-chan struct{}) tea.Cmd {
+	return func() tea.Msg {
+		select {
+		case <-at.tunnel.Done():
+			return tunnelStoppedMsg{id: at.id, err: at.tunnel.Err()}
+		case <-done:
+			return nil
+		}
+	}
+}
+
+// Connects to h in the background, asking to trust its host key first if needed
+func (m Model) openTunnel(h *Host, password []byte) (tea.Model, tea.Cmd) {
+	if len(h.LocalForwards) == 0 && len(h.RemoteForwards) == 0 {
+		m.view = listView
+		return m, m.list.NewStatusMessage(h.Name + " has no port forwards to run as a tunnel")
+	}
+	m.view = listView
+	return m, tea.Batch(
+		startTunnel(h, m.configPath, password),
+		m.list.NewStatusMessage("Starting tunnel to "+h.Name+"..."),
+	)
+}
+
+// Registers a started tunnel or shows why it failed
+func (m Model) finishTunnelStart(msg tunnelStartedMsg) (tea.Model, tea.Cmd) {
+	var unknownErr *ssh.UnknownHostKeyError
+	if errors.As(msg.err, &unknownErr) {
+		m.unknownHostKey = unknownErr
+		m.unknownHostKeyHost = msg.host
+		m.tunnelMode = true
+		m.view = hostKeyConfirmView
+		return m, nil
+	}
+	if msg.err != nil {
+		m.err = fmt.Errorf("failed to start tunnel to %s: %w", msg.host.Name, msg.err)
+		m.showErr = true
+		return m, nil
+	}
+
+	at := m.tunnels.add(*msg.host, msg.tunnel)
+	m.list.Title = m.listTitle()
+	status := "Tunnel to " + msg.host.Name + " running"
+	if msg.authMethod != "" {
+		status += ", authenticated via " + msg.authMethod
+	}
+	return m, tea.Batch(
+		waitForTunnel(at, m.done),
+		m.list.NewStatusMessage(status),
+	)
+}
+
+// Drops a tunnel that stopped on its own and reports why
+func (m Model) finishTunnelStop(msg tunnelStoppedMsg) (tea.Model, tea.Cmd) {
+	at := m.tunnels.remove(msg.id)
+	m.list.Title = m.listTitle()
+	if at == nil || msg.err == nil {
+		return m, nil
+	}
+	logger.Errorf("Tunnel to %s stopped: %v", at.host.Name, msg.err)
+	return m, m.list.NewStatusMessage("Tunnel to " + at.host.Name + " stopped: " + msg.err.Error())
+}
+
+func (m Model) updateTunnels(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	tunnels := m.tunnels.list()
+
+	switch msg.String() {
+	case "up":
+		if m.tunnelCursor > 0 {
+			m.tunnelCursor--
+		}
+	case "down":
+		if m.tunnelCursor < len(tunnels)-1 {
+			m.tunnelCursor++
+		}
+	case "d", "x":
+		if m.tunnelCursor < len(tunnels) {
+			at := tunnels[m.tunnelCursor]
+			m.tunnels.remove(at.id)
+			at.tunnel.Close()
+			m.tunnelCursor = max(0, min(m.tunnelCursor, len(tunnels)-2))
+			m.list.Title = m.listTitle()
+		}
+	case "esc", "q":
+		m.view = listView
+	}
+	return m, nil
+}
+
+func (m Model) renderTunnels() string {
+	titleStyle := lg.NewStyle().
+		Bold(true).
+		Foreground(theme.TitleText).
+		Background(theme.TitleBackground).
+		Padding(0, 1).
+		Margin(0, 0, 0, 2)
+
+	hostStyle := lg.NewStyle().
+		Foreground(theme.Text).
+		Margin(0, 0, 0, 4)
+
+	selectedStyle := lg.NewStyle().
+		Foreground(theme.Highlight).
+		Bold(true).
+		Margin(0, 0, 0, 2)
+
+	detailStyle := lg.NewStyle().
+		Foreground(theme.Muted).
+		Margin(0, 0, 0, 6)
+
+	helpRendered, availHeight := m.renderFormHelp(tunnelsKeys)
+
+	title := titleStyle.Render("Tunnels") + "\n\n"
+	availHeight -= lg.Height(title)
+
+	tunnels := m.tunnels.list()
+	if len(tunnels) == 0 {
+		b := detailStyle.Render("No tunnels running. Press p on a host with port forwards to start one.")
+		return m.calculateVisibleFormContent(availHeight, b, title, helpRendered, m.getVisibleDeleteLines)
+	}
+
+	var b string
+	for i, at := range tunnels {
+		name := at.host.Name + " (" + at.host.User + "@" + at.host.Host + ")"
+		if i == m.tunnelCursor {
+			b += selectedStyle.Render("▸ "+name) + "\n"
+		} else {
+			b += hostStyle.Render(name) + "\n"
+		}
+		var forwards []string
+		for _, f := range at.host.LocalForwards {
+			forwards = append(forwards, "-L "+f)
+		}
+		for _, f := range at.host.RemoteForwards {
+			forwards = append(forwards, "-R "+f)
+		}
+		b += detailStyle.Render(strings.Join(forwards, "  ")+" · started "+relativeTime(at.started)) + "\n\n"
+	}
+
+	return m.calculateVisibleFormContent(availHeight, b, title, helpRendered, m.getVisibleDeleteLines)
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nathanlytang/rolodex/internal/ssh"
+)
+
+func TestWaitForTunnelStopsWithProgram(t *testing.T) {
+	// A tunnel that never stops, as one still running when a session starts
+	at := &activeTunnel{id: 1, tunnel: &ssh.Tunnel{}}
+	done := make(chan struct{})
+	msgs := make(chan any, 1)
+	go func() { msgs <- waitForTunnel(at, done)() }()
+
+	select {
+	case msg := <-msgs:
+		t.Fatalf("waitForTunnel returned %v while the tunnel and program were running", msg)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(done)
+	select {
+	case msg := <-msgs:
+		if msg != nil {
+			t.Errorf("waitForTunnel returned %v after the program exited, want nil", msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("waitForTunnel still waiting after the program exited")
+	}
+}
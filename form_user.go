@@ -0,0 +1,118 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	lg "github.com/charmbracelet/lipgloss"
+)
+
+// Key map for the connect-as-user prompt
+type userPromptKeyMap struct {
+	Connect key.Binding
+	Cancel  key.Binding
+}
+
+func (k userPromptKeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.Connect, k.Cancel}
+}
+
+func (k userPromptKeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{
+		{k.Connect, k.Cancel},
+	}
+}
+
+var userPromptKeys = userPromptKeyMap{
+	Connect: key.NewBinding(
+		key.WithKeys("enter"),
+		key.WithHelp("⏎", "connect"),
+	),
+	Cancel: key.NewBinding(
+		key.WithKeys("esc"),
+		key.WithHelp("esc", "cancel"),
+	),
+}
+
+// Asks which user to log in as on h instead of its saved user
+func (m Model) openUserPrompt(h Host) (tea.Model, tea.Cmd) {
+	t := textinput.New()
+	t.Prompt = "> "
+	t.PromptStyle = lg.NewStyle().Foreground(theme.Accent).Margin(0, 0, 0, 2)
+	t.Placeholder = "root"
+	t.CharLimit = 256
+	t.Focus()
+
+	m.userPrompt = t
+	m.userPromptHost = &h
+	m.view = userPromptView
+	return m, textinput.Blink
+}
+
+func (m Model) updateUserPrompt(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "enter":
+		user := strings.TrimSpace(m.userPrompt.Value())
+		if user == "" {
+			return m, nil
+		}
+
+		// The override only applies to this connection and is never saved
+		h := *m.userPromptHost
+		h.User = user
+		m.userPromptHost = nil
+		m.browse = false
+		m.tunnelMode = false
+		if m.needsConnectConfirm(h) {
+			m.hostToConfirm = &h
+			m.view = connectConfirmView
+			return m, nil
+		}
+		return m.connect(&h)
+
+	case "esc":
+		m.userPromptHost = nil
+		m.view = listView
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.userPrompt, cmd = m.userPrompt.Update(msg)
+	return m, cmd
+}
+
+func (m Model) renderUserPrompt() string {
+	titleStyle := lg.NewStyle().
+		Bold(true).
+		Foreground(theme.TitleText).
+		Background(theme.TitleBackground).
+		Padding(0, 1).
+		Margin(0, 0, 0, 2)
+
+	labelStyle := lg.NewStyle().
+		Foreground(theme.Text).
+		Bold(true).
+		Margin(0, 0, 0, 2)
+
+	hintStyle := lg.NewStyle().
+		Foreground(theme.Muted).
+		Margin(0, 0, 0, 2)
+
+	helpRendered, availHeight := m.renderFormHelp(userPromptKeys)
+
+	var title string
+	title = titleStyle.Render("Connect As") + "\n\n"
+	availHeight -= lg.Height(title)
+	var b string
+
+	if m.userPromptHost != nil {
+		h := m.userPromptHost
+		b += labelStyle.Render("User for "+h.Name+" ("+h.Host+")") + "\n"
+		b += m.userPrompt.View() + "\n\n"
+		b += hintStyle.Render("Normally "+h.User+". The saved host isn't changed.") + "\n"
+	}
+
+	return m.calculateVisibleFormContent(availHeight, b, title, helpRendered, m.getVisibleDeleteLines)
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/nathanlytang/rolodex/internal/logger"
+)
+
+// Quits the TUI to connect to every marked host one after another
+// Hosts expanded from a marked template are all connected to, disabled hosts are skipped
+func (m Model) connectMarkedHosts() (tea.Model, tea.Cmd) {
+	var hosts []Host
+	for _, loc := range m.markedLocations() {
+		if h := m.hostAt(loc); !h.Disabled {
+			hosts = append(hosts, expandHost(h)...)
+		}
+	}
+	if len(hosts) == 0 {
+		return m, m.list.NewStatusMessage("All marked hosts are disabled")
+	}
+	m.connectGroup = hosts
+	return Quit(m)
+}
+
+// Connects to each host in turn in the current terminal, moving on when a session ends
+// A failed host is reported and skipped; the failures are returned together at the end
+func connectSequentially(hosts []Host, configuration *Configuration, configPath string) error {
+	logger.Printf("Connecting to %d hosts in turn", len(hosts))
+
+	var errs []error
+	for i := range hosts {
+		h := &hosts[i]
+		fmt.Fprintf(os.Stderr, "\n[%d/%d] Connecting to %s (%s@%s)\n", i+1, len(hosts), h.Name, h.User, h.Host)
+		if err := connectInTerminal(h, configuration, configPath); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			errs = append(errs, fmt.Errorf("%s: %w", h.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
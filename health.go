@@ -0,0 +1,131 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/nathanlytang/rolodex/internal/ssh"
+)
+
+// Reachability of a host's SSH port from the last health check
+type healthStatus int
+
+const (
+	healthUnknown healthStatus = iota
+	healthReachable
+	healthUnreachable
+)
+
+// Returns a text marker for the host's health when colors are disabled
+func (i Item) healthMarker() string {
+	if !noColor {
+		return ""
+	}
+	switch i.health {
+	case healthReachable:
+		return " (up)"
+	case healthUnreachable:
+		return " (down)"
+	}
+	return ""
+}
+
+// How many hosts are probed at once
+const maxConcurrentHealthChecks = 10
+
+// How long each probe waits for the TCP connection
+const healthCheckTimeout = 3 * time.Second
+
+// Delivered when a round of health checks finishes, keyed by host name
+// gen identifies the check loop so results from a loop that was since restarted are dropped
+type healthCheckMsg struct {
+	gen    int
+	status map[string]healthStatus
+}
+
+// Delivered when the next round of health checks is due
+type healthTickMsg struct {
+	gen int
+}
+
+// Returns a command that probes every host's SSH port without authenticating
+func checkHealth(gen int, hosts []Host) tea.Cmd {
+	return func() tea.Msg {
+		var (
+			mu     sync.Mutex
+			wg     sync.WaitGroup
+			sem    = make(chan struct{}, maxConcurrentHealthChecks)
+			status = make(map[string]healthStatus, len(hosts))
+		)
+
+		for _, h := range hosts {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				sem <- struct{}{}
+				defer func() { <-sem }()
+
+				result := healthReachable
+				address := ssh.Address(h.Host, h.Port)
+				if err := ssh.CheckReachable(address, healthCheckTimeout); err != nil {
+					result = healthUnreachable
+				}
+
+				mu.Lock()
+				status[h.Name] = result
+				mu.Unlock()
+			}()
+		}
+		wg.Wait()
+
+		return healthCheckMsg{gen: gen, status: status}
+	}
+}
+
+// Returns a command that waits for the next round of health checks
+func scheduleHealthCheck(gen int, interval time.Duration) tea.Cmd {
+	return tea.Tick(interval, func(time.Time) tea.Msg {
+		return healthTickMsg{gen: gen}
+	})
+}
+
+// Returns every host, including those inside folders
+func (m Model) allHosts() []Host {
+	hosts := append([]Host{}, m.hosts...)
+	for _, f := range m.folders {
+		hosts = append(hosts, f.Hosts...)
+	}
+	return hosts
+}
+
+// Records health check results and schedules the next round
+func (m Model) finishHealthCheck(msg healthCheckMsg) (tea.Model, tea.Cmd) {
+	if msg.gen != m.healthGen {
+		return m, nil
+	}
+	m.health = msg.status
+	return m, tea.Batch(m.list.SetItems(m.buildItems()), scheduleHealthCheck(m.healthGen, m.healthCheckInterval))
+}
+
+// Runs the next round of health checks if the loop is still current
+func (m Model) nextHealthCheck(msg healthTickMsg) (tea.Model, tea.Cmd) {
+	if msg.gen != m.healthGen {
+		return m, nil
+	}
+	return m, checkHealth(m.healthGen, expandHosts(m.allHosts()))
+}
+
+// Restarts background health checks with a new interval, 0 turns them off
+func (m Model) setHealthCheckInterval(interval time.Duration) (Model, tea.Cmd) {
+	if interval == m.healthCheckInterval {
+		return m, nil
+	}
+	m.healthCheckInterval = interval
+	m.healthGen++
+	if interval <= 0 {
+		m.health = nil
+		return m, m.list.SetItems(m.buildItems())
+	}
+	return m, checkHealth(m.healthGen, expandHosts(m.allHosts()))
+}
@@ -1,49 +1,197 @@
 package logger
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"os"
 	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
 	"time"
 )
 
 var (
 	fileLogger *log.Logger
 	logFile    *os.File
+	logsDir    string
+	level      = LevelInfo
 )
 
-// Initializes the file logger
-func Init() error {
-	// Get executable path
-	exePath, err := os.Executable()
-	if err != nil {
-		return fmt.Errorf("failed to get executable path: %w", err)
+// Days log files are kept when neither an age nor a file limit is configured
+const DefaultMaxLogAge = 30
+
+// Date format used in log file names
+const logDateFormat = "2006-01-02"
+
+// Severity of a log message
+type Level int
+
+const (
+	LevelDebug Level = iota // Verbose connection tracing
+	LevelInfo               // Normal operation
+	LevelError              // Failures only
+)
+
+var levelNames = map[string]Level{
+	"debug": LevelDebug,
+	"info":  LevelInfo,
+	"error": LevelError,
+}
+
+// Parses a level name (debug, info or error), an empty name is info
+func ParseLevel(s string) (Level, error) {
+	if s == "" {
+		return LevelInfo, nil
 	}
-	exeDir := filepath.Dir(exePath)
+	l, ok := levelNames[strings.ToLower(s)]
+	if !ok {
+		return LevelInfo, fmt.Errorf("unknown log level %q, expected debug, info or error", s)
+	}
+	return l, nil
+}
 
-	// Create logs directory if it doesn't exist (beside the executable)
-	logsDir := filepath.Join(exeDir, "logs")
-	if err := os.MkdirAll(logsDir, 0755); err != nil {
-		return fmt.Errorf("failed to create logs directory: %w", err)
+// Sets the minimum level written to the log file
+func SetLevel(l Level) {
+	level = l
+}
+
+// Returns the directories logs may be written to, in order of preference
+// ROLODEX_LOG_DIR overrides the platform state directory, and the temp
+// directory is the last resort when neither is writable
+func logDirCandidates() []string {
+	var dirs []string
+	if dir := os.Getenv("ROLODEX_LOG_DIR"); dir != "" {
+		dirs = append(dirs, dir)
+	}
+
+	if runtime.GOOS == "windows" {
+		if dir := os.Getenv("LOCALAPPDATA"); dir != "" {
+			dirs = append(dirs, filepath.Join(dir, "rolodex", "logs"))
+		}
+	} else if dir := os.Getenv("XDG_STATE_HOME"); dir != "" {
+		dirs = append(dirs, filepath.Join(dir, "rolodex", "logs"))
+	} else if home, err := os.UserHomeDir(); err == nil {
+		dirs = append(dirs, filepath.Join(home, ".local", "state", "rolodex", "logs"))
+	}
+
+	return append(dirs, filepath.Join(os.TempDir(), "rolodex", "logs"))
+}
+
+// Opens today's log file in dir, creating the directory if needed
+func openLogFile(dir string) (*os.File, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create logs directory: %w", err)
 	}
 
 	// Create log file with date (one file per day)
-	date := time.Now().Format("2006-01-02")
-	logPath := filepath.Join(logsDir, fmt.Sprintf("rolodex_%s.log", date))
+	date := time.Now().Format(logDateFormat)
+	logPath := filepath.Join(dir, fmt.Sprintf("rolodex_%s.log", date))
 
-	var openErr error
-	logFile, openErr = os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
-	if openErr != nil {
-		return fmt.Errorf("failed to open log file: %w", openErr)
+	f, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open log file: %w", err)
 	}
+	return f, nil
+}
 
-	// Create logger that writes to file
-	fileLogger = log.New(logFile, "", log.LstdFlags|log.Lshortfile)
+// Initializes the file logger in the first writable log directory
+func Init() error {
+	var errs []error
+	for _, dir := range logDirCandidates() {
+		f, err := openLogFile(dir)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		logFile = f
+		logsDir = dir
+
+		// Create logger that writes to file
+		fileLogger = log.New(logFile, "", log.LstdFlags|log.Lshortfile)
+
+		fileLogger.Printf("=== Rolodex session started ===")
+		fileLogger.Printf("Logging to %s", logFile.Name())
+		for _, err := range errs {
+			fileLogger.Printf("Skipped log location: %v", err)
+		}
+		return nil
+	}
+	return errors.Join(errs...)
+}
 
-	fileLogger.Printf("=== Rolodex session started ===")
-	return nil
+// Returns the directory log files are written to, empty before Init
+func Dir() string {
+	return logsDir
+}
+
+// Returns the path of the current log file, empty before Init
+func Path() string {
+	if logFile != nil {
+		return logFile.Name()
+	}
+	return ""
+}
+
+// Deletes log files older than maxAge days, then the oldest files beyond maxFiles
+// A limit of 0 disables it, and DefaultMaxLogAge applies when both are 0
+// Deletion failures are logged rather than returned so a read-only logs
+// directory doesn't stop the app from starting
+func Prune(maxAge, maxFiles int) {
+	if logsDir == "" {
+		return
+	}
+	if maxAge == 0 && maxFiles == 0 {
+		maxAge = DefaultMaxLogAge
+	}
+
+	entries, err := os.ReadDir(logsDir)
+	if err != nil {
+		Errorf("Failed to read logs directory: %v", err)
+		return
+	}
+
+	// Collect log files by the date in their name, newest first
+	type dated struct {
+		name string
+		date time.Time
+	}
+	var files []dated
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, "rolodex_") || !strings.HasSuffix(name, ".log") {
+			continue
+		}
+		date, err := time.ParseInLocation(logDateFormat, strings.TrimSuffix(strings.TrimPrefix(name, "rolodex_"), ".log"), time.Local)
+		if err != nil {
+			continue
+		}
+		files = append(files, dated{name, date})
+	}
+	slices.SortFunc(files, func(a, b dated) int { return b.date.Compare(a.date) })
+
+	today := time.Now().Format(logDateFormat)
+	cutoff := time.Now().AddDate(0, 0, -maxAge)
+	pruned := 0
+	for i, f := range files {
+		tooOld := maxAge > 0 && f.date.Before(cutoff)
+		tooMany := maxFiles > 0 && i >= maxFiles
+		if (!tooOld && !tooMany) || f.date.Format(logDateFormat) == today {
+			continue
+		}
+		if err := os.Remove(filepath.Join(logsDir, f.name)); err != nil {
+			Errorf("Failed to delete old log file %s: %v", f.name, err)
+			continue
+		}
+		pruned++
+	}
+
+	if pruned > 0 {
+		Printf("Pruned %d old log file(s)", pruned)
+	}
 }
 
 // Closes the log file
@@ -56,24 +204,38 @@ func Close() {
 	}
 }
 
+// Logs a formatted debug message to the file
+func Debugf(format string, v ...any) {
+	if fileLogger != nil && level <= LevelDebug {
+		fileLogger.Output(2, fmt.Sprintf("DEBUG: "+format, v...))
+	}
+}
+
 // Logs a formatted message to the file
 func Printf(format string, v ...any) {
-	if fileLogger != nil {
-		fileLogger.Printf(format, v...)
+	if fileLogger != nil && level <= LevelInfo {
+		fileLogger.Output(2, fmt.Sprintf(format, v...))
 	}
 }
 
 // Logs a message to the file
 func Print(v ...any) {
-	if fileLogger != nil {
-		fileLogger.Print(v...)
+	if fileLogger != nil && level <= LevelInfo {
+		fileLogger.Output(2, fmt.Sprint(v...))
 	}
 }
 
 // Logs a message with newline to the file
 func Println(v ...any) {
+	if fileLogger != nil && level <= LevelInfo {
+		fileLogger.Output(2, fmt.Sprintln(v...))
+	}
+}
+
+// Logs a formatted error to the file
+func Errorf(format string, v ...any) {
 	if fileLogger != nil {
-		fileLogger.Println(v...)
+		fileLogger.Output(2, fmt.Sprintf("ERROR: "+format, v...))
 	}
 }
 
@@ -82,7 +244,7 @@ func Println(v ...any) {
 func Fatal(v ...any) error {
 	msg := fmt.Sprint(v...)
 	if fileLogger != nil {
-		fileLogger.Printf("FATAL: %s", msg)
+		fileLogger.Output(2, "FATAL: "+msg)
 	}
 	return fmt.Errorf("%s", msg)
 }
@@ -92,7 +254,7 @@ func Fatal(v ...any) error {
 func Fatalf(format string, v ...any) error {
 	msg := fmt.Sprintf(format, v...)
 	if fileLogger != nil {
-		fileLogger.Printf("FATAL: %s", msg)
+		fileLogger.Output(2, "FATAL: "+msg)
 	}
 	return fmt.Errorf("%s", msg)
 }
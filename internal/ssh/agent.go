@@ -1,20 +1,48 @@
 package ssh
 
 import (
+	"errors"
+	"fmt"
 	"net"
 	"os"
+	"time"
 
 	"github.com/nathanlytang/rolodex/internal/logger"
 	"golang.org/x/crypto/ssh"
 	"golang.org/x/crypto/ssh/agent"
 )
 
-// Attempts to connect to the SSH agent and returns an AuthMethod if successful
-func TrySSHAgent() ssh.AuthMethod {
+// Connection to the local SSH agent, shared by authentication and agent forwarding
+type agentConn struct {
+	agent.ExtendedAgent
+	conn net.Conn
+}
+
+func (a *agentConn) Close() error {
+	return a.conn.Close()
+}
+
+// Returned when SSH_AUTH_SOCK doesn't point at an agent
+var ErrNoAgent = errors.New("SSH agent not available (SSH_AUTH_SOCK not set)")
+
+// Returned when ssh_agent is set but the agent can't be used to authenticate
+type AgentUnreachableError struct {
+	Err error
+}
+
+func (e *AgentUnreachableError) Error() string {
+	return fmt.Sprintf("SSH agent enabled but not reachable: %v. Is ssh-agent running?", e.Err)
+}
+
+func (e *AgentUnreachableError) Unwrap() error {
+	return e.Err
+}
+
+// Connects to the SSH agent named by SSH_AUTH_SOCK
+func dialAgent() (*agentConn, error) {
 	socket := os.Getenv("SSH_AUTH_SOCK")
 	if socket == "" {
-		logger.Printf("SSH agent not available (SSH_AUTH_SOCK not set)")
-		return nil
+		return nil, ErrNoAgent
 	}
 
 	// On Windows, SSH agent uses named pipes; on Unix, it uses Unix sockets
@@ -25,11 +53,157 @@ func TrySSHAgent() ssh.AuthMethod {
 
 	conn, err := net.Dial(network, socket)
 	if err != nil {
-		logger.Printf("Failed to connect to SSH agent: %v", err)
-		return nil
+		return nil, fmt.Errorf("failed to connect to SSH agent: %w", err)
+	}
+
+	logger.Debugf("Successfully connected to SSH agent")
+	return &agentConn{ExtendedAgent: agent.NewClient(conn), conn: conn}, nil
+}
+
+// A key loaded in the SSH agent
+type AgentKey struct {
+	Type        string
+	Fingerprint string // SHA256 fingerprint, as shown by ssh-add -l
+	Comment     string
+}
+
+// Lists the keys currently loaded in the SSH agent
+func ListAgentKeys() ([]AgentKey, error) {
+	agentClient, err := dialAgent()
+	if err != nil {
+		return nil, err
+	}
+	defer agentClient.Close()
+
+	keys, err := agentClient.List()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list agent keys: %w", err)
 	}
 
-	agentClient := agent.NewClient(conn)
-	logger.Printf("Successfully connected to SSH agent")
-	return ssh.PublicKeysCallback(agentClient.Signers)
+	var agentKeys []AgentKey
+	for _, k := range keys {
+		agentKeys = append(agentKeys, AgentKey{
+			Type:        k.Type(),
+			Fingerprint: ssh.FingerprintSHA256(k),
+			Comment:     k.Comment,
+		})
+	}
+	return agentKeys, nil
+}
+
+// Returns an AuthMethod that offers the keys held by the agent
+// If fingerprint is set only the matching key is offered, which avoids hitting
+// the server's MaxAuthTries when the agent holds many keys
+// Fails with an *AgentUnreachableError if the agent doesn't answer
+func TrySSHAgent(agentClient agent.Agent, fingerprint string) (ssh.AuthMethod, error) {
+	// A socket can accept the connection while nothing is serving it
+	if _, err := agentClient.List(); err != nil {
+		return nil, &AgentUnreachableError{Err: fmt.Errorf("failed to list agent keys: %w", err)}
+	}
+	if fingerprint == "" {
+		return ssh.PublicKeysCallback(agentClient.Signers), nil
+	}
+
+	return ssh.PublicKeysCallback(func() ([]ssh.Signer, error) {
+		signers, err := agentClient.Signers()
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range signers {
+			if ssh.FingerprintSHA256(s.PublicKey()) == fingerprint {
+				logger.Debugf("Offering agent key %s only", fingerprint)
+				return []ssh.Signer{s}, nil
+			}
+		}
+		logger.Printf("Agent key %s is not loaded in the agent", fingerprint)
+		return nil, nil
+	}), nil
+}
+
+// Loads an identity file into the agent so later connections can use it without its passphrase
+// Keys already in the agent aren't added again. If the key is encrypted and passphrase is empty,
+// prompter asks for it. A lifetime of 0 keeps the key until the agent exits
+// Returns an AuthMethod that offers only this key from the agent
+func AddIdentityToAgent(agentClient agent.Agent, identityFile, passphrase string, lifetime time.Duration, prompter KeyboardInteractivePrompter) (ssh.AuthMethod, error) {
+	identityFile, err := expandHome(identityFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get home directory: %w", err)
+	}
+
+	fingerprint, err := KeyFileFingerprint(identityFile)
+	if err != nil {
+		return nil, err
+	}
+
+	keys, err := agentClient.List()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list agent keys: %w", err)
+	}
+	for _, k := range keys {
+		if ssh.FingerprintSHA256(k) == fingerprint {
+			logger.Debugf("Identity file %s is already loaded in the agent", identityFile)
+			return TrySSHAgent(agentClient, fingerprint)
+		}
+	}
+
+	keyData, err := os.ReadFile(identityFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read identity file %s: %w", identityFile, err)
+	}
+
+	privateKey, err := ssh.ParseRawPrivateKey(keyData)
+	var missing *ssh.PassphraseMissingError
+	if errors.As(err, &missing) {
+		if passphrase == "" {
+			if prompter == nil {
+				return nil, fmt.Errorf("identity file %s is encrypted but no passphrase provided", identityFile)
+			}
+			answers, err := prompter("Add Key to SSH Agent", identityFile, []string{"Passphrase: "}, []bool{false})
+			if err != nil {
+				return nil, err
+			}
+			if len(answers) != 1 {
+				return nil, fmt.Errorf("expected 1 passphrase, got %d answers", len(answers))
+			}
+			passphrase = answers[0]
+		}
+		privateKey, err = ssh.ParseRawPrivateKeyWithPassphrase(keyData, []byte(passphrase))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse identity file %s: %w", identityFile, err)
+	}
+
+	added := agent.AddedKey{
+		PrivateKey:   privateKey,
+		Comment:      identityFile,
+		LifetimeSecs: uint32(lifetime / time.Second),
+	}
+	if err := agentClient.Add(added); err != nil {
+		return nil, fmt.Errorf("failed to add %s to the SSH agent: %w", identityFile, err)
+	}
+	if lifetime > 0 {
+		logger.Printf("Added identity file %s to the SSH agent for %v", identityFile, lifetime)
+	} else {
+		logger.Printf("Added identity file %s to the SSH agent", identityFile)
+	}
+
+	return TrySSHAgent(agentClient, fingerprint)
+}
+
+// Forwards the local agent to the remote session so onward hops can use local keys
+// Failures are logged rather than aborting the session
+func requestAgentForwarding(client *ssh.Client, session *ssh.Session, agentClient *agentConn) {
+	if agentClient == nil {
+		logger.Printf("Agent forwarding requested but no SSH agent is available")
+		return
+	}
+	if err := agent.ForwardToAgent(client, agentClient); err != nil {
+		logger.Errorf("Failed to set up agent forwarding: %v", err)
+		return
+	}
+	if err := agent.RequestAgentForwarding(session); err != nil {
+		logger.Printf("Server refused agent forwarding: %v", err)
+		return
+	}
+	logger.Printf("Agent forwarding requested successfully")
 }
@@ -0,0 +1,42 @@
+package ssh
+
+import (
+	"fmt"
+	"slices"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Checks that every host key algorithm and cipher is implemented by the SSH library
+// Insecure algorithms are accepted so legacy servers can still be reached
+func ValidateAlgorithms(hostKeyAlgorithms, ciphers []string) error {
+	supported, insecure := ssh.SupportedAlgorithms(), ssh.InsecureAlgorithms()
+
+	for _, a := range hostKeyAlgorithms {
+		if !slices.Contains(supported.HostKeys, a) && !slices.Contains(insecure.HostKeys, a) {
+			return fmt.Errorf("unsupported host key algorithm %q", a)
+		}
+	}
+	for _, c := range ciphers {
+		if !slices.Contains(supported.Ciphers, c) && !slices.Contains(insecure.Ciphers, c) {
+			return fmt.Errorf("unsupported cipher %q", c)
+		}
+	}
+	return nil
+}
+
+// Returns true if any of the algorithms are considered insecure by the SSH library
+func hasInsecureAlgorithms(hostKeyAlgorithms, ciphers []string) bool {
+	insecure := ssh.InsecureAlgorithms()
+	for _, a := range hostKeyAlgorithms {
+		if slices.Contains(insecure.HostKeys, a) {
+			return true
+		}
+	}
+	for _, c := range ciphers {
+		if slices.Contains(insecure.Ciphers, c) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,123 @@
+package ssh
+
+import (
+	"io"
+	"sync"
+
+	"github.com/nathanlytang/rolodex/internal/logger"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// Records which authentication method the server accepted
+// x/crypto/ssh doesn't report this, so each method notes when it answers the server.
+// Methods are tried in order and stop at the first success, so the last one to answer
+// is the one that authenticated
+type authTracker struct {
+	mu   sync.Mutex
+	last string
+}
+
+func (t *authTracker) record(method string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.last = method
+}
+
+// Returns a description of the method that last answered, e.g. "key ~/.ssh/id_ed25519"
+func (t *authTracker) method() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.last
+}
+
+// Returns an AuthMethod offering signer that records method once the server accepts the key
+func (t *authTracker) publicKey(method string, signer ssh.Signer) ssh.AuthMethod {
+	return ssh.PublicKeys(t.signer(method, signer))
+}
+
+// Returns a password AuthMethod that records method when the server asks for the password
+func (t *authTracker) password(method, password string) ssh.AuthMethod {
+	logger.Debugf("Adding %s authentication method", method)
+	return ssh.PasswordCallback(func() (string, error) {
+		t.record(method)
+		return password, nil
+	})
+}
+
+// Returns a keyboard-interactive AuthMethod that records method when the server asks questions
+func (t *authTracker) keyboardInteractive(method string, challenge ssh.KeyboardInteractiveChallenge) ssh.AuthMethod {
+	return ssh.KeyboardInteractive(func(name, instruction string, questions []string, echos []bool) ([]string, error) {
+		t.record(method)
+		return challenge(name, instruction, questions, echos)
+	})
+}
+
+// Wraps an agent so the keys it offers record which one the server accepted
+func (t *authTracker) agent(a agent.Agent) agent.Agent {
+	return &trackedAgent{Agent: a, tracker: t}
+}
+
+// Wraps signer so method is recorded when it signs
+// The server only asks for a signature after accepting the key. RSA keys keep their
+// SHA-2 signature algorithms, which the wrapper would otherwise hide
+func (t *authTracker) signer(method string, signer ssh.Signer) ssh.Signer {
+	algSigner, ok := signer.(ssh.AlgorithmSigner)
+	if !ok {
+		return &trackedSigner{Signer: signer, record: func() { t.record(method) }}
+	}
+	tracked := &trackedAlgorithmSigner{AlgorithmSigner: algSigner, record: func() { t.record(method) }}
+	if multi, ok := signer.(ssh.MultiAlgorithmSigner); ok {
+		if wrapped, err := ssh.NewSignerWithAlgorithms(tracked, multi.Algorithms()); err == nil {
+			return wrapped
+		}
+	}
+	return tracked
+}
+
+type trackedSigner struct {
+	ssh.Signer
+	record func()
+}
+
+func (s *trackedSigner) Sign(rand io.Reader, data []byte) (*ssh.Signature, error) {
+	s.record()
+	return s.Signer.Sign(rand, data)
+}
+
+type trackedAlgorithmSigner struct {
+	ssh.AlgorithmSigner
+	record func()
+}
+
+func (s *trackedAlgorithmSigner) Sign(rand io.Reader, data []byte) (*ssh.Signature, error) {
+	s.record()
+	return s.AlgorithmSigner.Sign(rand, data)
+}
+
+func (s *trackedAlgorithmSigner) SignWithAlgorithm(rand io.Reader, data []byte, algorithm string) (*ssh.Signature, error) {
+	s.record()
+	return s.AlgorithmSigner.SignWithAlgorithm(rand, data, algorithm)
+}
+
+type trackedAgent struct {
+	agent.Agent
+	tracker *authTracker
+}
+
+// Returns the agent's signers, each recording itself as "agent key COMMENT (FINGERPRINT)"
+func (a *trackedAgent) Signers() ([]ssh.Signer, error) {
+	signers, err := a.Agent.Signers()
+	if err != nil {
+		return nil, err
+	}
+	tracked := make([]ssh.Signer, len(signers))
+	for i, s := range signers {
+		method := "agent key " + ssh.FingerprintSHA256(s.PublicKey())
+		if k, ok := s.PublicKey().(*agent.Key); ok && k.Comment != "" {
+			method = "agent key " + k.Comment + " (" + ssh.FingerprintSHA256(s.PublicKey()) + ")"
+		}
+		tracked[i] = a.tracker.signer(method, s)
+	}
+	return tracked, nil
+}
@@ -0,0 +1,142 @@
+package ssh
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"syscall"
+
+	"github.com/nathanlytang/rolodex/internal/logger"
+	"golang.org/x/crypto/ssh"
+)
+
+// Broad reason a connection failed, used to suggest what to check
+type ErrorKind int
+
+const (
+	ErrorUnknown        ErrorKind = iota
+	ErrorDNS                      // The host name didn't resolve
+	ErrorUnreachable              // The port refused the connection or there's no route to the host
+	ErrorTimeout                  // Dialing or the handshake took too long
+	ErrorAuth                     // The server rejected every auth method offered
+	ErrorHostKeyChanged           // The server's host key doesn't match known_hosts
+)
+
+// Returns a short title for the kind of failure, as shown above the error
+func (k ErrorKind) String() string {
+	switch k {
+	case ErrorDNS:
+		return "Host Not Found"
+	case ErrorUnreachable:
+		return "Host Unreachable"
+	case ErrorTimeout:
+		return "Connection Timed Out"
+	case ErrorAuth:
+		return "Authentication Failed"
+	case ErrorHostKeyChanged:
+		return "Host Key Changed"
+	}
+	return "Connection Error"
+}
+
+// Returns what the user should check for this kind of failure, empty when there's no useful advice
+func (k ErrorKind) Advice() string {
+	switch k {
+	case ErrorDNS:
+		return "Check the host name for typos and that DNS is working"
+	case ErrorUnreachable:
+		return "Check the port, that sshd is running, and that no firewall is blocking the connection"
+	case ErrorTimeout:
+		return "Check the network connection and firewall, or raise connect_timeout for slow links"
+	case ErrorAuth:
+		return "Check the user name, password, identity file, and that the key is authorized on the server"
+	case ErrorHostKeyChanged:
+		return "Confirm the change with the server's administrator before removing the old known_hosts entry"
+	}
+	return ""
+}
+
+// Returned by Connect when a host can't be reached or won't let us in
+// Kind says why so callers can suggest a fix
+type ConnectError struct {
+	Kind    ErrorKind
+	Address string
+	Err     error
+}
+
+func (e *ConnectError) Error() string {
+	switch e.Kind {
+	case ErrorDNS:
+		return fmt.Sprintf("cannot resolve %s: %v", e.Address, e.Err)
+	case ErrorUnreachable:
+		return fmt.Sprintf("cannot reach %s: %v", e.Address, e.Err)
+	case ErrorTimeout:
+		return fmt.Sprintf("connection to %s timed out: %v", e.Address, e.Err)
+	case ErrorAuth:
+		return fmt.Sprintf("authentication to %s failed: %v", e.Address, e.Err)
+	}
+	return fmt.Sprintf("connection to %s failed: %v", e.Address, e.Err)
+}
+
+func (e *ConnectError) Unwrap() error {
+	return e.Err
+}
+
+// Classifies err from connecting to address, logs it, and returns it as a *ConnectError
+func newConnectError(address string, err error) *ConnectError {
+	e := &ConnectError{Kind: ClassifyError(err), Address: address, Err: err}
+	logger.Fatal(e.Error())
+	return e
+}
+
+// Works out why a connection failed from the net and ssh errors wrapped in err
+// A *ConnectError anywhere in the chain keeps its kind
+func ClassifyError(err error) ErrorKind {
+	if err == nil {
+		return ErrorUnknown
+	}
+
+	var connectErr *ConnectError
+	if errors.As(err, &connectErr) {
+		return connectErr.Kind
+	}
+
+	var changedErr *HostKeyChangedError
+	if errors.As(err, &changedErr) {
+		return ErrorHostKeyChanged
+	}
+
+	// The ssh package doesn't type client auth failures, only the message identifies them
+	var authErr *ssh.ServerAuthError
+	if errors.As(err, &authErr) || strings.Contains(err.Error(), "unable to authenticate") {
+		return ErrorAuth
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && !dnsErr.IsTimeout {
+		return ErrorDNS
+	}
+
+	var netErr net.Error
+	if errors.Is(err, os.ErrDeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return ErrorTimeout
+	}
+
+	if errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.EHOSTUNREACH) || errors.Is(err, syscall.ENETUNREACH) {
+		return ErrorUnreachable
+	}
+	// The jump host couldn't open a connection to the next hop
+	var channelErr *ssh.OpenChannelError
+	if errors.As(err, &channelErr) && channelErr.Reason == ssh.ConnectionFailed {
+		return ErrorUnreachable
+	}
+	// Other dial failures, such as Windows socket errors, still mean the host couldn't be reached
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return ErrorUnreachable
+	}
+
+	return ErrorUnknown
+}
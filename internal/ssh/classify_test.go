@@ -0,0 +1,126 @@
+package ssh
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// Returns the error from dialing a local port nothing is listening on
+func refusedDialError(t *testing.T) error {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := l.Addr().String()
+	l.Close()
+	conn, err := net.Dial("tcp", address)
+	if err == nil {
+		conn.Close()
+		t.Skip("port was reused before it could be dialed")
+	}
+	return err
+}
+
+func testPublicKey(t *testing.T) ssh.PublicKey {
+	t.Helper()
+	pub, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestClassifyError(t *testing.T) {
+	key := testPublicKey(t)
+	tests := []struct {
+		name   string
+		err    error
+		want   ErrorKind
+		advice string
+	}{
+		{
+			name:   "DNS failure",
+			err:    &net.OpError{Op: "dial", Net: "tcp", Err: &net.DNSError{Err: "no such host", Name: "nope.invalid", IsNotFound: true}},
+			want:   ErrorDNS,
+			advice: "Check the host name for typos and that DNS is working",
+		},
+		{
+			name:   "connection refused",
+			err:    refusedDialError(t),
+			want:   ErrorUnreachable,
+			advice: "Check the port, that sshd is running, and that no firewall is blocking the connection",
+		},
+		{
+			name:   "timeout",
+			err:    fmt.Errorf("ssh: handshake failed: %w", &net.OpError{Op: "read", Net: "tcp", Err: os.ErrDeadlineExceeded}),
+			want:   ErrorTimeout,
+			advice: "Check the network connection and firewall, or raise connect_timeout for slow links",
+		},
+		{
+			name:   "DNS timeout",
+			err:    &net.OpError{Op: "dial", Net: "tcp", Err: &net.DNSError{Err: "i/o timeout", Name: "slow.example.com", IsTimeout: true}},
+			want:   ErrorTimeout,
+			advice: "Check the network connection and firewall, or raise connect_timeout for slow links",
+		},
+		{
+			name:   "auth failure",
+			err:    errors.New("ssh: handshake failed: ssh: unable to authenticate, attempted methods [none publickey], no supported methods remain"),
+			want:   ErrorAuth,
+			advice: "Check the user name, password, identity file, and that the key is authorized on the server",
+		},
+		{
+			name:   "host key changed",
+			err:    fmt.Errorf("ssh: handshake failed: %w", &HostKeyChangedError{Address: "web:22", Key: key, Want: knownhosts.KnownKey{Key: key, Filename: "known_hosts", Line: 3}}),
+			want:   ErrorHostKeyChanged,
+			advice: "Confirm the change with the server's administrator before removing the old known_hosts entry",
+		},
+		{
+			// The TUI asks whether to trust the key rather than showing advice
+			name:   "unknown host key",
+			err:    fmt.Errorf("ssh: handshake failed: %w", &UnknownHostKeyError{Address: "web:22", Key: key, KnownHostsFile: "known_hosts"}),
+			want:   ErrorUnknown,
+			advice: "",
+		},
+		{
+			name:   "kept from a ConnectError",
+			err:    fmt.Errorf("jump host bastion: %w", &ConnectError{Kind: ErrorAuth, Address: "bastion:22", Err: errors.New("denied")}),
+			want:   ErrorAuth,
+			advice: "Check the user name, password, identity file, and that the key is authorized on the server",
+		},
+		{
+			name:   "unknown error",
+			err:    errors.New("ssh: disconnect, reason 11: bye"),
+			want:   ErrorUnknown,
+			advice: "",
+		},
+		{
+			name:   "nil",
+			err:    nil,
+			want:   ErrorUnknown,
+			advice: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ClassifyError(tt.err)
+			if got != tt.want {
+				t.Errorf("ClassifyError(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+			if advice := got.Advice(); advice != tt.advice {
+				t.Errorf("Advice() = %q, want %q", advice, tt.advice)
+			}
+		})
+	}
+}
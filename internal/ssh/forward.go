@@ -0,0 +1,186 @@
+package ssh
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/nathanlytang/rolodex/internal/logger"
+	"golang.org/x/crypto/ssh"
+)
+
+// Parses a forward spec in the form [bind_address:]port:host:hostport
+// Returns the address to listen on and the address to connect to
+func ParseForward(spec string) (listenAddr, targetAddr string, err error) {
+	parts := strings.Split(spec, ":")
+
+	bind := "localhost"
+	switch len(parts) {
+	case 3:
+	case 4:
+		bind = parts[0]
+		parts = parts[1:]
+	default:
+		return "", "", fmt.Errorf("invalid forward %q: expected [bind_address:]port:host:hostport", spec)
+	}
+
+	for _, p := range []string{parts[0], parts[2]} {
+		port, err := strconv.Atoi(p)
+		if err != nil || port < 0 || port > 65535 {
+			return "", "", fmt.Errorf("invalid forward %q: bad port %q", spec, p)
+		}
+	}
+	if parts[1] == "" {
+		return "", "", fmt.Errorf("invalid forward %q: missing host", spec)
+	}
+
+	return net.JoinHostPort(bind, parts[0]), net.JoinHostPort(parts[1], parts[2]), nil
+}
+
+// Tracks listeners and connections for active port forwards
+type forwarder struct {
+	mu        sync.Mutex
+	listeners []net.Listener
+	conns     map[net.Conn]struct{}
+	wg        sync.WaitGroup
+}
+
+// Starts local (-L) and remote (-R) port forwards over the client
+// Bind failures are logged and skipped rather than aborting the session
+// Returns a function that closes all forwards and waits for them to exit
+func startForwards(client *ssh.Client, localForwards, remoteForwards []string) (stop func()) {
+	f := &forwarder{conns: make(map[net.Conn]struct{})}
+
+	for _, spec := range localForwards {
+		listenAddr, targetAddr, err := ParseForward(spec)
+		if err != nil {
+			logger.Printf("Skipping local forward: %v", err)
+			continue
+		}
+		l, err := net.Listen("tcp", listenAddr)
+		if err != nil {
+			logger.Errorf("Failed to bind local forward %s: %v", spec, err)
+			continue
+		}
+		logger.Debugf("Local forward %s -> %s (via remote)", listenAddr, targetAddr)
+		f.serve(l, targetAddr, func(addr string) (net.Conn, error) {
+			return client.Dial("tcp", addr)
+		})
+	}
+
+	for _, spec := range remoteForwards {
+		listenAddr, targetAddr, err := ParseForward(spec)
+		if err != nil {
+			logger.Printf("Skipping remote forward: %v", err)
+			continue
+		}
+		l, err := client.Listen("tcp", listenAddr)
+		if err != nil {
+			logger.Errorf("Failed to bind remote forward %s: %v", spec, err)
+			continue
+		}
+		logger.Debugf("Remote forward %s -> %s (via local)", listenAddr, targetAddr)
+		f.serve(l, targetAddr, func(addr string) (net.Conn, error) {
+			return net.Dial("tcp", addr)
+		})
+	}
+
+	return f.close
+}
+
+// Accepts connections on l and pipes each one to targetAddr using dial
+func (f *forwarder) serve(l net.Listener, targetAddr string, dial func(string) (net.Conn, error)) {
+	f.mu.Lock()
+	f.listeners = append(f.listeners, l)
+	f.mu.Unlock()
+
+	f.wg.Add(1)
+	go func() {
+		defer f.wg.Done()
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+
+			f.wg.Add(1)
+			go func() {
+				defer f.wg.Done()
+				f.pipe(conn, targetAddr, dial)
+			}()
+		}
+	}()
+}
+
+// Copies data between conn and a new connection to targetAddr until either side closes
+func (f *forwarder) pipe(conn net.Conn, targetAddr string, dial func(string) (net.Conn, error)) {
+	target, err := dial(targetAddr)
+	if err != nil {
+		logger.Errorf("Forward to %s failed: %v", targetAddr, err)
+		conn.Close()
+		return
+	}
+
+	if !f.track(conn, target) {
+		return
+	}
+	defer f.untrack(conn, target)
+
+	done := make(chan struct{}, 2)
+	go func() {
+		io.Copy(target, conn)
+		done <- struct{}{}
+	}()
+	go func() {
+		io.Copy(conn, target)
+		done <- struct{}{}
+	}()
+	<-done
+}
+
+// Registers open connections so close can shut them down
+// Returns false (and closes them) if the forwarder is already closed
+func (f *forwarder) track(conns ...net.Conn) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.conns == nil {
+		for _, c := range conns {
+			c.Close()
+		}
+		return false
+	}
+	for _, c := range conns {
+		f.conns[c] = struct{}{}
+	}
+	return true
+}
+
+// Closes and forgets connections once their pipe has finished
+func (f *forwarder) untrack(conns ...net.Conn) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, c := range conns {
+		c.Close()
+		delete(f.conns, c)
+	}
+}
+
+// Closes all listeners and open connections, then waits for goroutines to exit
+func (f *forwarder) close() {
+	f.mu.Lock()
+	for _, l := range f.listeners {
+		l.Close()
+	}
+	for c := range f.conns {
+		c.Close()
+	}
+	f.conns = nil
+	f.mu.Unlock()
+
+	f.wg.Wait()
+}
@@ -0,0 +1,217 @@
+package ssh
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/nathanlytang/rolodex/internal/logger"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// Returned when the server presents a host key that is not in known_hosts
+// The caller should ask the user to confirm the fingerprint before trusting it
+type UnknownHostKeyError struct {
+	Address        string
+	Key            ssh.PublicKey
+	KnownHostsFile string
+}
+
+func (e *UnknownHostKeyError) Error() string {
+	return fmt.Sprintf("unknown host key for %s (%s %s)", e.Address, e.Key.Type(), e.Fingerprint())
+}
+
+// Returns the SHA256 fingerprint of the server's host key
+func (e *UnknownHostKeyError) Fingerprint() string {
+	return ssh.FingerprintSHA256(e.Key)
+}
+
+// Returned when the server's host key doesn't match the one in known_hosts
+// This may be a man-in-the-middle attack, so the connection is always refused
+type HostKeyChangedError struct {
+	Address string
+	Key     ssh.PublicKey
+	Want    knownhosts.KnownKey // The known_hosts entry the key was expected to match
+}
+
+func (e *HostKeyChangedError) Error() string {
+	return fmt.Sprintf("HOST KEY FOR %s HAS CHANGED! Someone could be eavesdropping on you (man-in-the-middle attack).\nServer presented %s %s\nExpected key is at %s:%d\nRemove the old entry from known_hosts if the change is legitimate",
+		e.Address, e.Key.Type(), ssh.FingerprintSHA256(e.Key), e.Want.Filename, e.Want.Line)
+}
+
+// How server host keys are checked, set with host_key_policy
+const (
+	HostKeyPolicyTOFU     = "tofu"     // Verify against known_hosts and ask before trusting a new host (default)
+	HostKeyPolicyStrict   = "strict"   // Only connect to hosts already in known_hosts
+	HostKeyPolicyInsecure = "insecure" // Accept any host key without checking
+)
+
+// Returns the host key policies in the order they're offered in settings
+func HostKeyPolicies() []string {
+	return []string{HostKeyPolicyTOFU, HostKeyPolicyStrict, HostKeyPolicyInsecure}
+}
+
+// Ends a handshake once the host key has been read, before any authentication
+var errHostKeyCaptured = errors.New("host key captured")
+
+// A server's host key read without logging in
+type HostKeyInfo struct {
+	Type        string
+	Fingerprint string // SHA256 fingerprint, as shown by ssh-keygen -l
+	KnownHosts  error  // nil if the key is in known_hosts, *UnknownHostKeyError if the host isn't, otherwise why it doesn't match
+}
+
+// Handshakes with the server at address just far enough to read its host key, then disconnects
+// The key is also checked against known_hosts so callers can tell whether it's trusted
+func FetchHostKey(address, knownHostsFile string, timeout time.Duration, hostKeyAlgorithms []string) (HostKeyInfo, error) {
+	if timeout <= 0 {
+		timeout = DefaultHandshakeTimeout
+	}
+	conn, err := net.DialTimeout("tcp", address, timeout)
+	if err != nil {
+		return HostKeyInfo{}, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	var hostKey ssh.PublicKey
+	config := &ssh.ClientConfig{
+		HostKeyCallback: func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			hostKey = key
+			return errHostKeyCaptured
+		},
+		HostKeyAlgorithms: hostKeyAlgorithms,
+	}
+	if _, _, _, err := ssh.NewClientConn(conn, address, config); hostKey == nil {
+		return HostKeyInfo{}, fmt.Errorf("handshake failed: %w", err)
+	}
+
+	info := HostKeyInfo{Type: hostKey.Type(), Fingerprint: ssh.FingerprintSHA256(hostKey)}
+	verify, err := buildHostKeyCallback(HostKeyPolicyTOFU, knownHostsFile)
+	if err != nil {
+		info.KnownHosts = err
+	} else {
+		info.KnownHosts = verify(address, conn.RemoteAddr(), hostKey)
+	}
+	logger.Printf("Fetched %s host key for %s: %s", info.Type, address, info.Fingerprint)
+	return info, nil
+}
+
+// Returns the default known_hosts location (~/.ssh/known_hosts)
+func DefaultKnownHostsPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".ssh", "known_hosts")
+}
+
+// Expands ~ in the known_hosts path, falling back to the default location
+func resolveKnownHostsPath(path string) string {
+	if path == "" {
+		return DefaultKnownHostsPath()
+	}
+	if strings.HasPrefix(path, "~") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			logger.Errorf("Failed to get home directory: %v", err)
+			return path
+		}
+		return filepath.Join(home, path[1:])
+	}
+	return path
+}
+
+// Creates a host key callback for policy, verifying servers against known_hosts
+// Under tofu unknown hosts return an *UnknownHostKeyError, under strict they fail
+// Changed keys always abort the connection unless the policy is insecure
+func buildHostKeyCallback(policy, knownHostsFile string) (ssh.HostKeyCallback, error) {
+	switch policy {
+	case "", HostKeyPolicyTOFU, HostKeyPolicyStrict:
+	case HostKeyPolicyInsecure:
+		logger.Printf("Warning: host key checking is disabled by the insecure host key policy")
+		return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			logger.Printf("Accepting unverified host key for %s: %s", hostname, ssh.FingerprintSHA256(key))
+			return nil
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown host key policy %q: use tofu, strict, or insecure", policy)
+	}
+
+	path := resolveKnownHostsPath(knownHostsFile)
+	if path == "" {
+		return nil, fmt.Errorf("could not determine known_hosts location")
+	}
+
+	// A missing known_hosts file means every host is unknown
+	var files []string
+	if _, err := os.Stat(path); err == nil {
+		files = append(files, path)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read known_hosts %s: %w", path, err)
+	}
+
+	callback, err := knownhosts.New(files...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse known_hosts %s: %w", path, err)
+	}
+
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		err := callback(hostname, remote, key)
+		if err == nil {
+			logger.Debugf("Host key for %s verified against %s", hostname, path)
+			return nil
+		}
+
+		var keyErr *knownhosts.KeyError
+		if errors.As(err, &keyErr) {
+			if len(keyErr.Want) == 0 && policy == HostKeyPolicyStrict {
+				return fmt.Errorf("host key for %s (%s %s) isn't in %s and the host key policy is strict\nAdd the key to known_hosts, for example with ssh-keyscan, before connecting",
+					hostname, key.Type(), ssh.FingerprintSHA256(key), path)
+			}
+			if len(keyErr.Want) == 0 {
+				logger.Printf("Unknown host key for %s: %s", hostname, ssh.FingerprintSHA256(key))
+				return &UnknownHostKeyError{Address: hostname, Key: key, KnownHostsFile: path}
+			}
+			return &HostKeyChangedError{Address: hostname, Key: key, Want: keyErr.Want[0]}
+		}
+
+		var revokedErr *knownhosts.RevokedError
+		if errors.As(err, &revokedErr) {
+			return fmt.Errorf("host key for %s has been revoked in %s:%d", hostname, revokedErr.Revoked.Filename, revokedErr.Revoked.Line)
+		}
+
+		return err
+	}, nil
+}
+
+// Appends a host key to the known_hosts file, creating it if necessary
+func AddKnownHost(knownHostsFile, address string, key ssh.PublicKey) error {
+	path := resolveKnownHostsPath(knownHostsFile)
+	if path == "" {
+		return fmt.Errorf("could not determine known_hosts location")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create known_hosts directory: %w", err)
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open known_hosts: %w", err)
+	}
+	defer f.Close()
+
+	line := knownhosts.Line([]string{knownhosts.Normalize(address)}, key)
+	if _, err := f.WriteString(line + "\n"); err != nil {
+		return fmt.Errorf("failed to write known_hosts: %w", err)
+	}
+
+	logger.Printf("Added host key for %s to %s", address, path)
+	return nil
+}
@@ -1,36 +1,74 @@
 package ssh
 
 import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
+	"time"
 
 	"github.com/nathanlytang/rolodex/internal/logger"
 	"golang.org/x/crypto/ssh"
 )
 
+// Returned when a private key can be read by other users
+// OpenSSH refuses to use such keys
+type KeyPermissionError struct {
+	Path string
+	Mode os.FileMode
+}
+
+func (e *KeyPermissionError) Error() string {
+	return fmt.Sprintf("permissions %04o for %s are too open, the key should only be readable by you", e.Mode.Perm(), e.Path)
+}
+
+// Suffix OpenSSH gives the certificate issued for a key, as in id_ed25519-cert.pub
+const certificateSuffix = "-cert.pub"
+
 // Attempts to load and parse an SSH private key file
+// Keys readable by others are only warned about unless strictPermissions is set, which refuses them like OpenSSH.
+// A <keyfile>-cert.pub certificate beside the key is offered before the plain key
 // Returns nil if the file cannot be loaded or parsed
-func TryIdentityFile(identityFile, passphrase string) ssh.AuthMethod {
+func TryIdentityFile(identityFile, passphrase string, strictPermissions bool) ssh.AuthMethod {
+	signer := loadIdentityFile(identityFile, passphrase, strictPermissions)
+	if signer == nil {
+		return nil
+	}
+	if certSigner := loadCertificate(signer, identityFile, ""); certSigner != nil {
+		return ssh.PublicKeys(certSigner, signer)
+	}
+	return ssh.PublicKeys(signer)
+}
+
+// Loads the signer for TryIdentityFile, logging why and returning nil if it can't be used
+func loadIdentityFile(identityFile, passphrase string, strictPermissions bool) ssh.Signer {
 	if identityFile == "" {
 		return nil
 	}
 
-	// Expand ~ to home directory
-	if strings.HasPrefix(identityFile, "~") {
-		home, err := os.UserHomeDir()
-		if err != nil {
-			logger.Printf("Failed to get home directory: %v", err)
+	identityFile, err := expandHome(identityFile)
+	if err != nil {
+		logger.Errorf("Failed to get home directory: %v", err)
+		return nil
+	}
+
+	var permErr *KeyPermissionError
+	if err := ValidateKeyFile(identityFile); errors.As(err, &permErr) {
+		if strictPermissions {
+			logger.Errorf("Not using identity file: %v", permErr)
 			return nil
 		}
-		identityFile = filepath.Join(home, identityFile[1:])
+		logger.Printf("Warning: %v", permErr)
 	}
 
 	// Read the private key file
 	keyData, err := os.ReadFile(identityFile)
 	if err != nil {
-		logger.Printf("Failed to read identity file %s: %v", identityFile, err)
+		logger.Errorf("Failed to read identity file %s: %v", identityFile, err)
 		return nil
 	}
 
@@ -40,23 +78,101 @@ func TryIdentityFile(identityFile, passphrase string) ssh.AuthMethod {
 		if passphrase != "" {
 			signer, err = ssh.ParsePrivateKeyWithPassphrase(keyData, []byte(passphrase))
 			if err != nil {
-				logger.Printf("Failed to parse identity file %s with passphrase: %v", identityFile, err)
+				logger.Errorf("Failed to parse identity file %s with passphrase: %v", identityFile, err)
 				return nil
 			}
-			logger.Printf("Successfully loaded encrypted identity file: %s", identityFile)
+			logger.Debugf("Successfully loaded encrypted identity file: %s", identityFile)
 		} else {
 			if strings.Contains(err.Error(), "encrypted") || strings.Contains(err.Error(), "passphrase") {
 				logger.Printf("Identity file %s is encrypted but no passphrase provided", identityFile)
 				return nil
 			}
-			logger.Printf("Failed to parse identity file %s: %v", identityFile, err)
+			logger.Errorf("Failed to parse identity file %s: %v", identityFile, err)
 			return nil
 		}
 	} else {
-		logger.Printf("Successfully loaded identity file: %s", identityFile)
+		logger.Debugf("Successfully loaded identity file: %s", identityFile)
 	}
 
-	return ssh.PublicKeys(signer)
+	return signer
+}
+
+// Returns a signer presenting the certificate issued for signer's key, or nil if there isn't one
+// certificateFile is used when set, otherwise <identityFile>-cert.pub if it exists.
+// Certificates that can't be used are logged and skipped so the plain key is still offered
+func loadCertificate(signer ssh.Signer, identityFile, certificateFile string) ssh.Signer {
+	explicit := certificateFile != ""
+	if !explicit {
+		certificateFile = identityFile + certificateSuffix
+	}
+	certificateFile, err := expandHome(certificateFile)
+	if err != nil {
+		logger.Errorf("Failed to get home directory: %v", err)
+		return nil
+	}
+
+	data, err := os.ReadFile(certificateFile)
+	if err != nil {
+		// A missing certificate beside the key just means there isn't one
+		if explicit || !errors.Is(err, os.ErrNotExist) {
+			logger.Errorf("Failed to read certificate %s: %v", certificateFile, err)
+		}
+		return nil
+	}
+
+	pub, _, _, _, err := ssh.ParseAuthorizedKey(data)
+	if err != nil {
+		logger.Errorf("Failed to parse certificate %s: %v", certificateFile, err)
+		return nil
+	}
+	cert, ok := pub.(*ssh.Certificate)
+	if !ok {
+		logger.Errorf("%s is a public key, not a certificate", certificateFile)
+		return nil
+	}
+	certSigner, err := ssh.NewCertSigner(cert, signer)
+	if err != nil {
+		logger.Errorf("Not using certificate %s: %v", certificateFile, err)
+		return nil
+	}
+
+	logCertificate(certificateFile, cert)
+	return certSigner
+}
+
+// Logs a certificate's key ID, principals and validity window, warning when it isn't valid now
+func logCertificate(path string, cert *ssh.Certificate) {
+	principals := "any principal"
+	if len(cert.ValidPrincipals) > 0 {
+		principals = strings.Join(cert.ValidPrincipals, ", ")
+	}
+	logger.Printf("Using certificate %s (key ID %q) for %s, valid %s", path, cert.KeyId, principals, certificateValidity(cert))
+
+	now := uint64(time.Now().Unix())
+	switch {
+	case now < cert.ValidAfter:
+		logger.Printf("Warning: certificate %s is not valid until %s", path, certificateTime(cert.ValidAfter))
+	case cert.ValidBefore != ssh.CertTimeInfinity && now >= cert.ValidBefore:
+		logger.Printf("Warning: certificate %s expired at %s", path, certificateTime(cert.ValidBefore))
+	}
+}
+
+// Describes when a certificate is valid, like "from 2024-01-02 15:04:05 until 2024-01-03 15:04:05"
+func certificateValidity(cert *ssh.Certificate) string {
+	switch {
+	case cert.ValidAfter == 0 && cert.ValidBefore == ssh.CertTimeInfinity:
+		return "forever"
+	case cert.ValidBefore == ssh.CertTimeInfinity:
+		return "from " + certificateTime(cert.ValidAfter)
+	case cert.ValidAfter == 0:
+		return "until " + certificateTime(cert.ValidBefore)
+	}
+	return "from " + certificateTime(cert.ValidAfter) + " until " + certificateTime(cert.ValidBefore)
+}
+
+// Formats a certificate timestamp in local time
+func certificateTime(t uint64) string {
+	return time.Unix(int64(t), 0).Format(time.DateTime)
 }
 
 // Returns the fingerprint of a public key for identification
@@ -64,6 +180,107 @@ func GetKeyFingerprint(signer ssh.Signer) string {
 	return ssh.FingerprintSHA256(signer.PublicKey())
 }
 
+// Returns the SHA256 fingerprint of a private key file
+// Reads the matching .pub file when present so encrypted keys don't need a passphrase
+func KeyFileFingerprint(path string) (string, error) {
+	if pubData, err := os.ReadFile(path + ".pub"); err == nil {
+		if pub, _, _, _, err := ssh.ParseAuthorizedKey(pubData); err == nil {
+			return ssh.FingerprintSHA256(pub), nil
+		}
+	}
+
+	keyData, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read key: %w", err)
+	}
+
+	signer, err := ssh.ParsePrivateKey(keyData)
+	if err != nil {
+		var missing *ssh.PassphraseMissingError
+		if errors.As(err, &missing) && missing.PublicKey != nil {
+			return ssh.FingerprintSHA256(missing.PublicKey), nil
+		}
+		return "", fmt.Errorf("failed to parse key: %w", err)
+	}
+
+	return GetKeyFingerprint(signer), nil
+}
+
+// What InspectKeyFile found out about a private key file
+type KeyInfo struct {
+	Type        string // e.g. "ED25519" or "RSA 4096", empty if the public key couldn't be read
+	Fingerprint string // SHA256 fingerprint, empty if the public key couldn't be read
+	Encrypted   bool   // A passphrase is needed to use the key
+}
+
+// Describes the private key at path, with a leading ~ expanded, without needing its passphrase
+// Encrypted keys in the OpenSSH format carry their public key, older ones need the .pub beside them
+func InspectKeyFile(path string) (KeyInfo, error) {
+	path, err := expandHome(path)
+	if err != nil {
+		return KeyInfo{}, err
+	}
+	keyData, err := os.ReadFile(path)
+	if err != nil {
+		return KeyInfo{}, err
+	}
+
+	var info KeyInfo
+	var pub ssh.PublicKey
+	signer, err := ssh.ParsePrivateKey(keyData)
+	if err == nil {
+		pub = signer.PublicKey()
+		info.Fingerprint = GetKeyFingerprint(signer)
+	} else {
+		var missing *ssh.PassphraseMissingError
+		if !errors.As(err, &missing) {
+			return KeyInfo{}, fmt.Errorf("not a private key: %w", err)
+		}
+		info.Encrypted = true
+		pub = missing.PublicKey
+		if pub == nil {
+			if pubData, err := os.ReadFile(path + ".pub"); err == nil {
+				pub, _, _, _, _ = ssh.ParseAuthorizedKey(pubData)
+			}
+		}
+		if pub != nil {
+			info.Fingerprint = ssh.FingerprintSHA256(pub)
+		}
+	}
+	if pub != nil {
+		info.Type = keyType(pub)
+	}
+	return info, nil
+}
+
+// Names a public key's algorithm the way ssh-keygen -l does, with the size where it varies
+func keyType(pub ssh.PublicKey) string {
+	var bits int
+	if cpk, ok := pub.(ssh.CryptoPublicKey); ok {
+		switch k := cpk.CryptoPublicKey().(type) {
+		case *rsa.PublicKey:
+			bits = k.N.BitLen()
+		case *ecdsa.PublicKey:
+			bits = k.Curve.Params().BitSize
+		}
+	}
+	switch pub.Type() {
+	case ssh.KeyAlgoED25519:
+		return "ED25519"
+	case ssh.KeyAlgoSKED25519:
+		return "ED25519-SK"
+	case ssh.KeyAlgoSKECDSA256:
+		return "ECDSA-SK"
+	case ssh.KeyAlgoRSA:
+		return fmt.Sprintf("RSA %d", bits)
+	case ssh.KeyAlgoECDSA256, ssh.KeyAlgoECDSA384, ssh.KeyAlgoECDSA521:
+		return fmt.Sprintf("ECDSA %d", bits)
+	case ssh.KeyAlgoDSA:
+		return "DSA"
+	}
+	return pub.Type()
+}
+
 // Returns common SSH key file locations
 func ListCommonKeyPaths() []string {
 	home, err := os.UserHomeDir()
@@ -92,6 +309,7 @@ func FindAvailableKeys() []string {
 }
 
 // Checks if a key file exists and is readable
+// Returns *KeyPermissionError if group or others can read it
 func ValidateKeyFile(path string) error {
 	info, err := os.Stat(path)
 	if err != nil {
@@ -102,11 +320,54 @@ func ValidateKeyFile(path string) error {
 		return fmt.Errorf("path is a directory, not a file")
 	}
 
-	// Check permissions (should not be world-readable)
-	mode := info.Mode()
-	if mode.Perm()&0044 != 0 {
-		logger.Printf("Warning: key file %s has overly permissive permissions: %v", path, mode.Perm())
+	// Windows doesn't use Unix permission bits, so there's nothing to check
+	if runtime.GOOS != "windows" && info.Mode().Perm()&0044 != 0 {
+		return &KeyPermissionError{Path: path, Mode: info.Mode()}
 	}
 
 	return nil
 }
+
+// Returns *KeyPermissionError if the key at path, which may start with ~, can be read by others
+// Keys that don't exist or can't be read aren't reported, connecting explains those
+func CheckKeyPermissions(path string) error {
+	path, err := expandHome(path)
+	if err != nil {
+		return nil
+	}
+	var permErr *KeyPermissionError
+	if errors.As(ValidateKeyFile(path), &permErr) {
+		return permErr
+	}
+	return nil
+}
+
+// Restricts a key file, which may start with ~, to its owner like chmod 600
+func FixKeyPermissions(path string) error {
+	path, err := expandHome(path)
+	if err != nil {
+		return err
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		return fmt.Errorf("failed to change permissions of %s: %w", path, err)
+	}
+	logger.Printf("Restricted permissions of %s to 0600", path)
+	return nil
+}
+
+// Returns the path an identity file is read from, with a leading ~ expanded
+func ResolveIdentityFile(path string) (string, error) {
+	return expandHome(path)
+}
+
+// Expands a leading ~ to the home directory
+func expandHome(path string) (string, error) {
+	if !strings.HasPrefix(path, "~") {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, path[1:]), nil
+}
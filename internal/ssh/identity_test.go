@@ -0,0 +1,143 @@
+package ssh
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Writes data to name in a new temporary directory and returns its path
+func writeTempFile(t *testing.T, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestInspectKeyFile(t *testing.T) {
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fingerprint := func(key crypto.Signer) string {
+		pub, err := ssh.NewPublicKey(key.Public())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ssh.FingerprintSHA256(pub)
+	}
+	openSSH := func(key crypto.PrivateKey, passphrase string) []byte {
+		var block *pem.Block
+		var err error
+		if passphrase == "" {
+			block, err = ssh.MarshalPrivateKey(key, "")
+		} else {
+			block, err = ssh.MarshalPrivateKeyWithPassphrase(key, "", []byte(passphrase))
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		return pem.EncodeToMemory(block)
+	}
+
+	// Old-style encrypted PEM keys are still found on disk. They don't carry their public key,
+	// so the fingerprint comes from the .pub beside them
+	legacyBlock, err := x509.EncryptPEMBlock(rand.Reader, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey), []byte("secret"), x509.PEMCipherAES256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacy := writeTempFile(t, "id_rsa", pem.EncodeToMemory(legacyBlock))
+	rsaPub, err := ssh.NewPublicKey(rsaKey.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(legacy+".pub", ssh.MarshalAuthorizedKey(rsaPub), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		want    KeyInfo
+		wantErr string
+	}{
+		{
+			name: "ed25519",
+			path: writeTempFile(t, "id_ed25519", openSSH(edKey, "")),
+			want: KeyInfo{Type: "ED25519", Fingerprint: fingerprint(edKey)},
+		},
+		{
+			name: "encrypted ed25519",
+			path: writeTempFile(t, "id_ed25519", openSSH(edKey, "secret")),
+			want: KeyInfo{Type: "ED25519", Fingerprint: fingerprint(edKey), Encrypted: true},
+		},
+		{
+			name: "RSA PEM",
+			path: writeTempFile(t, "id_rsa", pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)})),
+			want: KeyInfo{Type: "RSA 2048", Fingerprint: fingerprint(rsaKey)},
+		},
+		{
+			name: "ECDSA",
+			path: writeTempFile(t, "id_ecdsa", openSSH(ecKey, "")),
+			want: KeyInfo{Type: "ECDSA 384", Fingerprint: fingerprint(ecKey)},
+		},
+		{
+			name: "encrypted PEM with a .pub",
+			path: legacy,
+			want: KeyInfo{Type: "RSA 2048", Fingerprint: fingerprint(rsaKey), Encrypted: true},
+		},
+		{
+			name: "encrypted PEM without a .pub",
+			path: writeTempFile(t, "id_rsa", pem.EncodeToMemory(legacyBlock)),
+			want: KeyInfo{Encrypted: true},
+		},
+		{
+			name:    "public key given instead",
+			path:    writeTempFile(t, "id_rsa.pub", ssh.MarshalAuthorizedKey(rsaPub)),
+			wantErr: "not a private key",
+		},
+		{
+			name:    "missing",
+			path:    filepath.Join(t.TempDir(), "id_missing"),
+			wantErr: "no such file",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := InspectKeyFile(tt.path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("InspectKeyFile() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("InspectKeyFile(): %v", err)
+			}
+			if info != tt.want {
+				t.Errorf("InspectKeyFile() = %+v, want %+v", info, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package ssh
+
+import (
+	"crypto/ed25519"
+	"io"
+	"net"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Starts an SSH server on a local port that accepts a single shell session
+// Returns a client connected to it and a channel receiving everything sent to the shell's stdin
+func shellServer(t *testing.T) (*ssh.Client, <-chan []byte) {
+	t.Helper()
+	_, private, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hostKey, err := ssh.NewSignerFromKey(private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serverConfig := &ssh.ServerConfig{NoClientAuth: true}
+	serverConfig.AddHostKey(hostKey)
+
+	// Both sides send their version first, which deadlocks on an unbuffered net.Pipe
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	received := make(chan []byte, 1)
+	go func() {
+		serverConn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		_, chans, reqs, err := ssh.NewServerConn(serverConn, serverConfig)
+		if err != nil {
+			return
+		}
+		go ssh.DiscardRequests(reqs)
+		for newChannel := range chans {
+			channel, requests, err := newChannel.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				// Accept pty-req, shell and anything else the client asks for
+				for req := range requests {
+					req.Reply(true, nil)
+				}
+			}()
+			data, _ := io.ReadAll(channel)
+			received <- data
+			channel.Close()
+		}
+	}()
+
+	client, err := ssh.Dial("tcp", l.Addr().String(), &ssh.ClientConfig{
+		User:            "test",
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client, received
+}
+
+func TestForwardInputPassesKeysUnchanged(t *testing.T) {
+	client, received := shellServer(t)
+	session, err := client.NewSession()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer session.Close()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	var firstInput atomic.Bool
+	stop, err := forwardInput(session, r, func() { firstInput.Store(true) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop()
+	if err := session.RequestPty("xterm-256color", 24, 80, ssh.TerminalModes{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := session.Shell(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Arrow keys in both cursor modes, sent as the very first input of the session
+	keys := "\x1b[A\x1b[B\x1b[C\x1b[D\x1bOA\x1bOB\x1bOC\x1bOD"
+	if _, err := w.Write([]byte(keys)); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+
+	select {
+	case data := <-received:
+		if string(data) != keys {
+			t.Errorf("server received %q, want %q", data, keys)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("server never received the input")
+	}
+	if !firstInput.Load() {
+		t.Error("onInput wasn't called")
+	}
+}
+
+func TestForwardInputLeavesLaterKeysAlone(t *testing.T) {
+	client, _ := shellServer(t)
+	session, err := client.NewSession()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer session.Close()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+
+	stop, err := forwardInput(session, r, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := session.Shell(); err != nil {
+		t.Fatal(err)
+	}
+	stop()
+
+	// Once stopped, the next key belongs to whatever reads the terminal next, like the host list
+	if _, err := w.Write([]byte("\x1b[A")); err != nil {
+		t.Fatal(err)
+	}
+	r.SetReadDeadline(time.Now().Add(5 * time.Second))
+	buf := make([]byte, 8)
+	n, err := r.Read(buf)
+	if err != nil {
+		t.Fatalf("reading after stop: %v", err)
+	}
+	if got := string(buf[:n]); got != "\x1b[A" {
+		t.Errorf("read %q after stop, want the arrow key", got)
+	}
+}
@@ -0,0 +1,118 @@
+package ssh
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/nathanlytang/rolodex/internal/logger"
+	"golang.org/x/crypto/ssh"
+)
+
+// A host to connect through on the way to the target, like one ssh -J hop
+// The target's Prompter answers keyboard-interactive questions for every hop
+type JumpHost struct {
+	Name string // Shown in logs and errors
+	Host string
+	Port int
+	User string
+	Auth AuthConfig
+}
+
+// Reports whether any hop authenticates with the agent
+func jumpHostsUseAgent(hops []JumpHost) bool {
+	return slices.ContainsFunc(hops, func(j JumpHost) bool { return j.Auth.SSHAgent || j.Auth.AddKeyToAgent })
+}
+
+// Connects to each hop in turn, dialing every hop after the first through the one before it
+// Returns the connections outermost first; on error any opened are closed
+func dialJumpHosts(hops []JumpHost, prompter KeyboardInteractivePrompter, agentClient *agentConn, agentErr error, sessionConfig SessionConfig, dialTimeout, handshakeTimeout time.Duration) ([]*ssh.Client, error) {
+	var clients []*ssh.Client
+	for _, hop := range hops {
+		logger.Printf("Connecting to jump host %s (%s@%s:%d)", hop.Name, hop.User, hop.Host, hop.Port)
+		auth := hop.Auth
+		auth.Prompter = prompter
+
+		var tracker authTracker
+		var banner strings.Builder
+		config, err := clientConfig(hop.User, auth, sessionConfig, agentClient, agentErr, &tracker, &banner, handshakeTimeout)
+		if err != nil {
+			closeJumpHosts(clients)
+			return nil, fmt.Errorf("jump host %s: %w", hop.Name, err)
+		}
+
+		var client *ssh.Client
+		if len(clients) == 0 && sessionConfig.Proxy != "" {
+			// The proxy only reaches the outermost jump host, the rest are reached through it
+			client, _, err = dialThroughProxy(sessionConfig.Proxy, []string{hop.Host}, hop.Port, config, dialTimeout)
+		} else if len(clients) == 0 {
+			client, err = dialDirect(Address(hop.Host, hop.Port), config, dialTimeout)
+		} else {
+			client, _, err = dialThroughJumpHost(clients[len(clients)-1], []string{hop.Host}, hop.Port, config)
+		}
+		if err != nil {
+			closeJumpHosts(clients)
+			// Unknown host keys are returned as-is so the caller can ask to trust the jump host
+			var unknownErr *UnknownHostKeyError
+			if errors.As(err, &unknownErr) {
+				return nil, unknownErr
+			}
+			return nil, fmt.Errorf("jump host %s: %w", hop.Name, newConnectError(Address(hop.Host, hop.Port), err))
+		}
+		if method := tracker.method(); method != "" {
+			logger.Printf("Authenticated to jump host %s via: %s", hop.Name, method)
+		}
+		clients = append(clients, client)
+	}
+	return clients, nil
+}
+
+// Opens an SSH connection to the first of hosts that jump can reach
+// Returns the client and the address it connected to
+func dialThroughJumpHost(jump *ssh.Client, hosts []string, port int, config *ssh.ClientConfig) (*ssh.Client, string, error) {
+	var errs []error
+	for _, host := range hosts {
+		address := Address(host, port)
+		logger.Debugf("Dialing %s through the jump host...", address)
+		conn, err := jump.Dial("tcp", address)
+		if err != nil {
+			if len(hosts) > 1 {
+				logger.Printf("Cannot reach %s through the jump host: %v", address, err)
+			}
+			errs = append(errs, fmt.Errorf("cannot reach %s through the jump host: %w", address, err))
+			continue
+		}
+		c, chans, reqs, err := ssh.NewClientConn(conn, address, config)
+		if err != nil {
+			conn.Close()
+			return nil, "", err
+		}
+		return ssh.NewClient(c, chans, reqs), address, nil
+	}
+	return nil, "", errors.Join(errs...)
+}
+
+// Opens an SSH connection to address, giving up on reaching its port after dialTimeout
+// ssh.Dial would wait for config.Timeout, the handshake timeout, instead
+func dialDirect(address string, config *ssh.ClientConfig, dialTimeout time.Duration) (*ssh.Client, error) {
+	conn, err := net.DialTimeout("tcp", address, dialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	c, chans, reqs, err := ssh.NewClientConn(conn, address, config)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return ssh.NewClient(c, chans, reqs), nil
+}
+
+// Closes jump host connections, innermost first
+func closeJumpHosts(clients []*ssh.Client) {
+	for _, c := range slices.Backward(clients) {
+		c.Close()
+	}
+}
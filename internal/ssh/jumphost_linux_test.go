@@ -0,0 +1,72 @@
+package ssh
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// Returns the address of a port whose accept queue is full, so connecting to it hangs
+// like a firewall dropping packets instead of being refused straight away
+func blackholeAddress(t *testing.T) string {
+	t.Helper()
+	fd, err := unix.Socket(unix.AF_INET, unix.SOCK_STREAM, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { unix.Close(fd) })
+	if err := unix.Bind(fd, &unix.SockaddrInet4{Addr: [4]byte{127, 0, 0, 1}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := unix.Listen(fd, 0); err != nil {
+		t.Fatal(err)
+	}
+	sa, err := unix.Getsockname(fd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := Address("127.0.0.1", sa.(*unix.SockaddrInet4).Port)
+
+	// A queue of length 0 still takes one connection, which is never accepted
+	conn, err := net.DialTimeout("tcp", address, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return address
+}
+
+func TestDialJumpHostsConnectTimeout(t *testing.T) {
+	address := blackholeAddress(t)
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hop := JumpHost{
+		Name: "bastion",
+		Host: host,
+		User: "test",
+		Auth: AuthConfig{Password: "secret", HostKeyPolicy: HostKeyPolicyInsecure},
+	}
+	if hop.Port, err = net.LookupPort("tcp", port); err != nil {
+		t.Fatal(err)
+	}
+
+	// The handshake timeout is far longer than the test runs, so only connect_timeout can end the dial
+	const dialTimeout = 200 * time.Millisecond
+	start := time.Now()
+	clients, err := dialJumpHosts([]JumpHost{hop}, nil, nil, nil, SessionConfig{}, dialTimeout, time.Minute)
+	elapsed := time.Since(start)
+	if err == nil {
+		closeJumpHosts(clients)
+		t.Fatal("dialJumpHosts() to an unreachable jump host succeeded")
+	}
+	if kind := ClassifyError(err); kind != ErrorTimeout {
+		t.Errorf("dialJumpHosts() error = %v, classified as %v, want a timeout", err, kind)
+	}
+	if elapsed > 5*dialTimeout {
+		t.Errorf("dialJumpHosts() took %v, want about %v", elapsed, dialTimeout)
+	}
+}
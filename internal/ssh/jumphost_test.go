@@ -0,0 +1,78 @@
+package ssh
+
+import (
+	"crypto/ed25519"
+	"net"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestDialDirect(t *testing.T) {
+	_, private, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hostKey, err := ssh.NewSignerFromKey(private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serverConfig := &ssh.ServerConfig{NoClientAuth: true}
+	serverConfig.AddHostKey(hostKey)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		serverConn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		conn, chans, reqs, err := ssh.NewServerConn(serverConn, serverConfig)
+		if err != nil {
+			return
+		}
+		go ssh.DiscardRequests(reqs)
+		go func() {
+			for newChannel := range chans {
+				newChannel.Reject(ssh.Prohibited, "no channels")
+			}
+		}()
+		conn.Wait()
+	}()
+
+	// A listener that was closed leaves a port nothing answers on
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closedAddress := closed.Addr().String()
+	closed.Close()
+
+	clientConfig := &ssh.ClientConfig{
+		User:            "test",
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+	}
+
+	t.Run("connects", func(t *testing.T) {
+		client, err := dialDirect(l.Addr().String(), clientConfig, 5*time.Second)
+		if err != nil {
+			t.Fatalf("dialDirect() error = %v", err)
+		}
+		defer client.Close()
+		if got := string(client.ServerVersion()); got == "" {
+			t.Error("dialDirect() returned a client without a server version")
+		}
+	})
+
+	t.Run("nothing listening", func(t *testing.T) {
+		client, err := dialDirect(closedAddress, clientConfig, 5*time.Second)
+		if err == nil {
+			client.Close()
+			t.Fatal("dialDirect() to a closed port succeeded")
+		}
+	})
+}
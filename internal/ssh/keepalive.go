@@ -0,0 +1,77 @@
+package ssh
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/nathanlytang/rolodex/internal/logger"
+	"golang.org/x/crypto/ssh"
+)
+
+// Default interval between keepalive requests
+const DefaultKeepAliveInterval = 30 * time.Second
+
+// Number of consecutive failed keepalives before the connection is considered dead
+const maxKeepAliveFailures = 3
+
+// Sends keepalive requests on a ticker until the returned stop function is called
+// Closes the client after maxKeepAliveFailures consecutive failures
+// stop waits for the goroutine to exit and returns the failure, if any
+func startKeepAlive(client *ssh.Client, interval time.Duration) (stop func() error) {
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	var keepAliveErr error
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		failures := 0
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+
+			if err := sendKeepAlive(client, interval); err != nil {
+				failures++
+				logger.Printf("Keepalive failed (%d/%d): %v", failures, maxKeepAliveFailures, err)
+				if failures >= maxKeepAliveFailures {
+					keepAliveErr = fmt.Errorf("no response to %d consecutive keepalives (interval %v): %w", failures, interval, err)
+					client.Close()
+					return
+				}
+				continue
+			}
+			failures = 0
+		}
+	}()
+
+	logger.Debugf("Keepalive enabled every %v", interval)
+	return func() error {
+		close(done)
+		wg.Wait()
+		return keepAliveErr
+	}
+}
+
+// Sends a single keepalive request, giving up after timeout
+func sendKeepAlive(client *ssh.Client, timeout time.Duration) error {
+	errc := make(chan error, 1)
+	go func() {
+		_, _, err := client.SendRequest("keepalive@openssh.com", true, nil)
+		errc <- err
+	}()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-time.After(timeout):
+		return fmt.Errorf("timed out after %v", timeout)
+	}
+}
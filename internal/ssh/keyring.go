@@ -1,7 +1,11 @@
 package ssh
 
 import (
+	"errors"
+	"runtime"
+	"strings"
 	"unicode/utf16"
+	"unicode/utf8"
 
 	"github.com/nathanlytang/rolodex/internal/logger"
 	"github.com/zalando/go-keyring"
@@ -17,6 +21,19 @@ func DeleteFromKeyring(service, account string) error {
 	return keyring.Delete(service, account)
 }
 
+// Reports whether the OS keyring holds a password for service and account
+// A missing entry isn't an error
+func KeyringHasSecret(service, account string) (bool, error) {
+	_, err := keyring.Get(service, account)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
 // Retrieves a password from the OS keyring
 func GetPasswordFromKeyring(service, account string) (string, error) {
 	if service == "" || account == "" {
@@ -24,29 +41,39 @@ func GetPasswordFromKeyring(service, account string) (string, error) {
 	}
 	password, err := keyring.Get(service, account)
 	if err != nil {
-		logger.Printf("Failed to retrieve password from keyring for %s/%s: %v", service, account, err)
+		logger.Errorf("Failed to retrieve password from keyring for %s/%s: %v", service, account, err)
 		return "", err
 	}
 
-	// Check if password is UTF-16LE encoded
-	if len(password) > 1 && password[1] == 0 {
-		// Convert UTF-16LE bytes to UTF-8 string
-		passwordBytes := []byte(password)
-		if len(passwordBytes)%2 != 0 {
-			return password, nil // Odd length, can't be valid UTF-16LE
+	// Credential Manager entries written by other Windows tools are often UTF-16LE,
+	// which go-keyring returns as raw bytes. Other platforms' keyrings store UTF-8
+	if runtime.GOOS == "windows" {
+		if decoded, ok := decodeUTF16LE(password); ok {
+			password = decoded
 		}
+	}
 
-		// Convert byte slice to uint16 slice
-		utf16Slice := make([]uint16, len(passwordBytes)/2)
-		for i := range utf16Slice {
-			utf16Slice[i] = uint16(passwordBytes[i*2]) | uint16(passwordBytes[i*2+1])<<8
-		}
+	logger.Debugf("Successfully retrieved password from keyring for %s/%s", service, account)
+	return password, nil
+}
 
-		// Decode UTF-16LE to UTF-8
-		runes := utf16.Decode(utf16Slice)
-		password = string(runes)
+// Decodes s as UTF-16LE if it looks like it was stored that way
+// Passwords don't contain NUL, so only even-length strings with a NUL byte are tried,
+// and the result is rejected if it has unpaired surrogates or NULs of its own
+func decodeUTF16LE(s string) (string, bool) {
+	if len(s)%2 != 0 || !strings.ContainsRune(s, 0) {
+		return "", false
 	}
 
-	logger.Printf("Successfully retrieved password from keyring for %s/%s", service, account)
-	return password, nil
+	units := make([]uint16, len(s)/2)
+	for i := range units {
+		units[i] = uint16(s[i*2]) | uint16(s[i*2+1])<<8
+	}
+
+	// Decode replaces unpaired surrogates with the replacement character
+	decoded := string(utf16.Decode(units))
+	if strings.ContainsRune(decoded, utf8.RuneError) || strings.ContainsRune(decoded, 0) {
+		return "", false
+	}
+	return decoded, true
 }
@@ -0,0 +1,44 @@
+package ssh
+
+import (
+	"testing"
+	"unicode/utf16"
+)
+
+// Encodes s as UTF-16LE, as Windows tools store Credential Manager secrets
+func utf16LE(s string) string {
+	var b []byte
+	for _, u := range utf16.Encode([]rune(s)) {
+		b = append(b, byte(u), byte(u>>8))
+	}
+	return string(b)
+}
+
+func TestDecodeUTF16LE(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		want   string
+		wantOK bool
+	}{
+		{"ASCII left alone", "hunter2", "", false},
+		{"even-length ASCII left alone", "hunter22", "", false},
+		{"UTF-8 multibyte left alone", "pässwörd🔑", "", false},
+		{"UTF-16LE ASCII", utf16LE("hunter2"), "hunter2", true},
+		{"UTF-16LE multibyte", utf16LE("pässwörd"), "pässwörd", true},
+		{"UTF-16LE surrogate pair", utf16LE("key🔑"), "key🔑", true},
+		{"odd length", utf16LE("hunter2") + "\x00", "", false},
+		{"unpaired high surrogate", utf16LE("ab") + "\x00\xd8", "", false},
+		{"unpaired low surrogate", "\x00\xdc" + utf16LE("ab"), "", false},
+		{"embedded NUL character", utf16LE("ab") + "\x00\x00", "", false},
+		{"empty", "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := decodeUTF16LE(tt.input)
+			if ok != tt.wantOK || got != tt.want {
+				t.Errorf("decodeUTF16LE(%q) = %q, %t, want %q, %t", tt.input, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
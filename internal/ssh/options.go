@@ -0,0 +1,57 @@
+package ssh
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nathanlytang/rolodex/internal/logger"
+)
+
+// Raw ssh_config options honored per host, matched case-insensitively like ssh_config
+//
+//	Compression          yes or no.  golang.org/x/crypto/ssh can't compress, so yes is logged and ignored
+//	SetEnv               Space separated NAME=value pairs sent to the server, which must allow them with AcceptEnv
+//	ServerAliveInterval  Seconds between keepalive requests, 0 disables.  Overrides keepalive_interval
+//
+// Any other key is logged and ignored
+type sessionOptions struct {
+	env       [][2]string    // Name and value pairs from SetEnv
+	keepAlive *time.Duration // From ServerAliveInterval, nil when unset
+}
+
+// Interprets the supported subset of raw ssh_config options
+func parseOptions(options map[string]string) sessionOptions {
+	var opts sessionOptions
+	for k, v := range options {
+		switch strings.ToLower(k) {
+		case "compression":
+			if !strings.EqualFold(v, "no") {
+				logger.Printf("Ignoring option Compression=%s: compression is not supported", v)
+			}
+
+		case "setenv":
+			for _, pair := range strings.Fields(v) {
+				name, value, ok := strings.Cut(pair, "=")
+				if !ok || name == "" {
+					logger.Errorf("Ignoring invalid SetEnv entry %q: expected NAME=value", pair)
+					continue
+				}
+				opts.env = append(opts.env, [2]string{name, value})
+			}
+
+		case "serveraliveinterval":
+			seconds, err := strconv.Atoi(v)
+			if err != nil || seconds < 0 {
+				logger.Errorf("Ignoring invalid ServerAliveInterval %q: expected seconds", v)
+				continue
+			}
+			interval := time.Duration(seconds) * time.Second
+			opts.keepAlive = &interval
+
+		default:
+			logger.Printf("Ignoring unsupported option %s", k)
+		}
+	}
+	return opts
+}
@@ -1,26 +1,98 @@
 package ssh
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
 	"github.com/nathanlytang/rolodex/internal/logger"
 	"golang.org/x/crypto/ssh"
 )
 
-// Adds password and keyboard-interactive authentication methods
-// Password is tried first, keyboard-interactive as fallback for PAM
-// Returns array of auth methods
-func TryPasswordAuth(password string) []ssh.AuthMethod {
-	logger.Printf("Adding password and keyboard-interactive authentication methods")
+// Collects answers to keyboard-interactive questions from the user
+// echos[i] reports whether the answer to questions[i] may be shown as typed
+type KeyboardInteractivePrompter func(name, instruction string, questions []string, echos []bool) ([]string, error)
+
+// Adds password authentication
+// Returns the auth method
+func TryPasswordAuth(password string) ssh.AuthMethod {
+	logger.Debugf("Adding password authentication method")
+	return ssh.Password(password)
+}
+
+// Time a password command may run before it is killed
+const PasswordCommandTimeout = 10 * time.Second
+
+// Runs a password manager command such as "pass show server" through the shell
+// Returns its output with surrounding whitespace trimmed; the output is never logged
+func RunPasswordCommand(command string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), PasswordCommandTimeout)
+	defer cancel()
 
-	var authMethods []ssh.AuthMethod
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
 
-	authMethods = append(authMethods, ssh.Password(password))
-	authMethods = append(authMethods, ssh.KeyboardInteractive(func(user, instruction string, questions []string, echos []bool) ([]string, error) {
-		answers := make([]string, len(questions))
-		for i := range questions {
-			answers[i] = password
+	logger.Debugf("Running password command")
+	out, err := cmd.Output()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return "", fmt.Errorf("password command timed out after %v", PasswordCommandTimeout)
+	}
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("password command failed: %v: %s", err, msg)
 		}
-		return answers, nil
-	}))
+		return "", fmt.Errorf("password command failed: %w", err)
+	}
+
+	password := strings.TrimSpace(string(out))
+	if password == "" {
+		return "", errors.New("password command printed nothing")
+	}
+	return password, nil
+}
+
+// Adds keyboard-interactive authentication, used by PAM and 2FA/OTP servers
+// When autoAnswer is set, a lone password question is answered with the stored password;
+// any other question is passed to prompter so the user can answer it
+func TryKeyboardInteractive(password string, autoAnswer bool, prompter KeyboardInteractivePrompter) ssh.AuthMethod {
+	logger.Debugf("Adding keyboard-interactive authentication method (auto-answer: %t)", autoAnswer && password != "")
+	return ssh.KeyboardInteractive(keyboardInteractiveChallenge(password, autoAnswer, prompter))
+}
+
+// Answers keyboard-interactive questions for TryKeyboardInteractive
+func keyboardInteractiveChallenge(password string, autoAnswer bool, prompter KeyboardInteractivePrompter) ssh.KeyboardInteractiveChallenge {
+	return func(name, instruction string, questions []string, echos []bool) ([]string, error) {
+		if len(questions) == 0 {
+			return []string{}, nil
+		}
+
+		if autoAnswer && password != "" && len(questions) == 1 && isPasswordQuestion(questions[0]) {
+			logger.Debugf("Answering keyboard-interactive password question with stored password")
+			return []string{password}, nil
+		}
+
+		if prompter == nil {
+			return nil, fmt.Errorf("server asked %d keyboard-interactive question(s) that need user input", len(questions))
+		}
+
+		logger.Debugf("Prompting user for %d keyboard-interactive question(s)", len(questions))
+		return prompter(name, instruction, questions, echos)
+	}
+}
 
-	return authMethods
+// Reports whether a keyboard-interactive question is asking for the account password
+func isPasswordQuestion(question string) bool {
+	q := strings.ToLower(question)
+	return strings.Contains(q, "password") && !strings.Contains(q, "one-time") && !strings.Contains(q, "otp")
 }
@@ -0,0 +1,50 @@
+package ssh
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestKeyboardInteractiveChallenge(t *testing.T) {
+	prompted := []string{"typed"}
+	tests := []struct {
+		name       string
+		password   string
+		autoAnswer bool
+		prompter   bool
+		questions  []string
+		want       []string // nil when an error is expected
+	}{
+		{"no questions", "secret", true, false, nil, []string{}},
+		{"password answered", "secret", true, false, []string{"Password: "}, []string{"secret"}},
+		{"answering left to the user", "secret", false, true, []string{"Password: "}, prompted},
+		{"OTP asked for", "secret", true, true, []string{"One-time password: "}, prompted},
+		{"verification code asked for", "secret", true, true, []string{"Verification code: "}, prompted},
+		{"several questions", "secret", true, true, []string{"Password: ", "Code: "}, prompted},
+		{"no stored password", "", true, true, []string{"Password: "}, prompted},
+		{"no one to ask", "secret", true, false, []string{"Verification code: "}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var prompter KeyboardInteractivePrompter
+			if tt.prompter {
+				prompter = func(name, instruction string, questions []string, echos []bool) ([]string, error) {
+					return prompted, nil
+				}
+			}
+			answers, err := keyboardInteractiveChallenge(tt.password, tt.autoAnswer, prompter)("", "", tt.questions, make([]bool, len(tt.questions)))
+			if tt.want == nil {
+				if err == nil {
+					t.Errorf("challenge answered %q, want an error", answers)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("challenge: %v", err)
+			}
+			if !slices.Equal(answers, tt.want) {
+				t.Errorf("challenge answered %q, want %q", answers, tt.want)
+			}
+		})
+	}
+}
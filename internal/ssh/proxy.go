@@ -0,0 +1,213 @@
+package ssh
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/nathanlytang/rolodex/internal/logger"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/net/proxy"
+)
+
+// Keyring service holding proxy passwords, under the account user@proxyhost:port
+const ProxyKeyringService = "rolodex-proxy"
+
+// Parses a proxy URL like socks5://user@proxy.example.com:1080 or http://proxy:3128
+// The port defaults to 1080 for SOCKS5 and 8080 for HTTP
+func ParseProxy(raw string) (*url.URL, error) {
+	// Errors leave out raw since it may hold a password
+	u, err := url.Parse(raw)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return nil, fmt.Errorf("invalid proxy URL: %w", err)
+	}
+	var defaultPort string
+	switch u.Scheme {
+	case "socks5", "socks5h":
+		defaultPort = "1080"
+	case "http":
+		defaultPort = "8080"
+	default:
+		return nil, fmt.Errorf("invalid proxy URL: expected socks5:// or http://, not %s://", u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return nil, errors.New("invalid proxy URL: no proxy host")
+	}
+	if u.Port() == "" {
+		u.Host = net.JoinHostPort(u.Hostname(), defaultPort)
+	}
+	return u, nil
+}
+
+// Returns the user and password to give the proxy, empty if it doesn't need any
+// A user without a password in the URL has theirs read from the keyring
+func proxyCredentials(u *url.URL) (string, string) {
+	if u.User == nil {
+		return "", ""
+	}
+	user := u.User.Username()
+	if password, ok := u.User.Password(); ok {
+		return user, password
+	}
+	password, err := GetPasswordFromKeyring(ProxyKeyringService, user+"@"+u.Host)
+	if err != nil {
+		logger.Printf("No proxy password for %s@%s in the keyring, trying without one", user, u.Host)
+	}
+	return user, password
+}
+
+// Opens a connection to address through the proxy at proxyURL
+// timeout covers reaching the proxy and it connecting onward
+func dialProxy(proxyURL, address string, timeout time.Duration) (net.Conn, error) {
+	u, err := ParseProxy(proxyURL)
+	if err != nil {
+		return nil, err
+	}
+	if user, password := proxyCredentials(u); user != "" {
+		u.User = url.UserPassword(user, password)
+	}
+	dialer, err := proxy.FromURL(u, &net.Dialer{Timeout: timeout})
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	conn, err := dialer.(proxy.ContextDialer).DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("proxy %s: %w", u.Host, err)
+	}
+	return conn, nil
+}
+
+// Opens an SSH connection to the first of hosts that the proxy can reach
+// Returns the client and the address it connected to
+func dialThroughProxy(proxyURL string, hosts []string, port int, config *ssh.ClientConfig, timeout time.Duration) (*ssh.Client, string, error) {
+	if u, err := ParseProxy(proxyURL); err == nil {
+		logger.Printf("Connecting through %s proxy %s", u.Scheme, u.Redacted())
+	}
+
+	var errs []error
+	for _, host := range hosts {
+		address := Address(host, port)
+		conn, err := dialProxy(proxyURL, address, timeout)
+		if err != nil {
+			if len(hosts) > 1 {
+				logger.Printf("Cannot reach %s through the proxy: %v", address, err)
+			}
+			errs = append(errs, err)
+			continue
+		}
+		c, chans, reqs, err := ssh.NewClientConn(conn, address, config)
+		if err != nil {
+			conn.Close()
+			return nil, "", err
+		}
+		return ssh.NewClient(c, chans, reqs), address, nil
+	}
+	return nil, "", errors.Join(errs...)
+}
+
+func init() {
+	// x/net/proxy only speaks SOCKS5 itself
+	proxy.RegisterDialerType("http", newHTTPProxyDialer)
+}
+
+// Tunnels through an HTTP proxy with CONNECT
+type httpProxyDialer struct {
+	host     string
+	user     string
+	password string
+	forward  proxy.Dialer // Reaches the proxy
+}
+
+func newHTTPProxyDialer(u *url.URL, forward proxy.Dialer) (proxy.Dialer, error) {
+	d := &httpProxyDialer{host: u.Host, forward: forward}
+	if u.User != nil {
+		d.user = u.User.Username()
+		d.password, _ = u.User.Password()
+	}
+	return d, nil
+}
+
+func (d *httpProxyDialer) Dial(network, address string) (net.Conn, error) {
+	return d.DialContext(context.Background(), network, address)
+}
+
+// Connects to the proxy and asks it to tunnel to address
+// ctx's deadline covers the CONNECT exchange as well as reaching the proxy
+func (d *httpProxyDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	var conn net.Conn
+	var err error
+	if forward, ok := d.forward.(proxy.ContextDialer); ok {
+		conn, err = forward.DialContext(ctx, "tcp", d.host)
+	} else {
+		conn, err = d.forward.Dial("tcp", d.host)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	tunnel, err := httpConnect(conn, address, d.user, d.password)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{})
+	return tunnel, nil
+}
+
+// Asks an HTTP proxy on conn to tunnel to address with CONNECT
+// Sends basic Proxy-Authorization when user is set
+func httpConnect(conn net.Conn, address, user, password string) (net.Conn, error) {
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: address},
+		Host:   address,
+		Header: make(http.Header),
+	}
+	if user != "" {
+		req.Header.Set("Proxy-Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(user+":"+password)))
+	}
+	if err := req.Write(conn); err != nil {
+		return nil, err
+	}
+
+	r := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(r, req)
+	if err != nil {
+		return nil, fmt.Errorf("no CONNECT response: %w", err)
+	}
+	resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusProxyAuthRequired:
+		return nil, errors.New("proxy requires authentication, check the user and password")
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("cannot connect to %s: %s", address, resp.Status)
+	}
+
+	// The server may speak first, so keep anything read past the response
+	return &bufferedConn{Conn: conn, r: r}, nil
+}
+
+// A connection whose first bytes were already read into r
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(p []byte) (int, error) {
+	return c.r.Read(p)
+}
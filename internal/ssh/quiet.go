@@ -0,0 +1,84 @@
+package ssh
+
+import (
+	"bytes"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/nathanlytang/rolodex/internal/logger"
+)
+
+// How long output has to pause after login before the screen is cleared
+// The MOTD arrives in one burst, so a pause usually means the prompt is waiting
+const quietSettle = 500 * time.Millisecond
+
+// Clears the screen once the login output settles, keeping only its last line,
+// which is normally the shell prompt. Used for hosts with a noisy MOTD
+// Once the screen is cleared, or the user starts typing, output passes straight through
+type quietWriter struct {
+	mu    sync.Mutex
+	w     io.Writer
+	line  []byte // Output since the last newline, redrawn after clearing
+	timer *time.Timer
+	done  bool
+}
+
+func newQuietWriter(w io.Writer) *quietWriter {
+	q := &quietWriter{w: w}
+	q.timer = time.AfterFunc(quietSettle, q.clear)
+	q.timer.Stop() // Started by the first output
+	return q
+}
+
+func (q *quietWriter) Write(p []byte) (int, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if !q.done {
+		if i := bytes.LastIndexByte(p, '\n'); i >= 0 {
+			q.line = append(q.line[:0], p[i+1:]...)
+		} else {
+			q.line = append(q.line, p...)
+		}
+		q.timer.Reset(quietSettle)
+	}
+	return q.w.Write(p)
+}
+
+// Clears the screen and redraws the prompt, unless output already passes straight through
+func (q *quietWriter) clear() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.done {
+		return
+	}
+	q.done = true
+	logger.Debugf("Clearing login output")
+	// Move home and erase the screen, then put the prompt back
+	q.w.Write(append([]byte("\x1b[H\x1b[2J"), q.line...))
+	q.line = nil
+}
+
+// Leaves the screen as it is from now on
+func (q *quietWriter) stop() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.done = true
+	q.timer.Stop()
+	q.line = nil
+}
+
+// Calls onRead before the first read that returns data
+type firstReadReader struct {
+	io.Reader
+	once   sync.Once
+	onRead func()
+}
+
+func (r *firstReadReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	if n > 0 {
+		r.once.Do(r.onRead)
+	}
+	return n, err
+}
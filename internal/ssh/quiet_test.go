@@ -0,0 +1,107 @@
+package ssh
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// A bytes.Buffer safe to write from the quiet timer's goroutine
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+const clearScreen = "\x1b[H\x1b[2J"
+
+func TestQuietWriterKeepsPrompt(t *testing.T) {
+	tests := []struct {
+		name   string
+		writes []string
+		prompt string
+	}{
+		{"prompt after MOTD", []string{"Welcome to web\r\n", "Last login: today\r\n", "alice@web:~$ "}, "alice@web:~$ "},
+		{"prompt split across writes", []string{"Welcome\r\nalice@", "web:~$ "}, "alice@web:~$ "},
+		{"output ending in a newline", []string{"Welcome\r\n"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out syncBuffer
+			q := newQuietWriter(&out)
+			defer q.stop()
+			for _, w := range tt.writes {
+				q.Write([]byte(w))
+			}
+			q.clear()
+
+			want := strings.Join(tt.writes, "") + clearScreen + tt.prompt
+			if got := out.String(); got != want {
+				t.Errorf("output = %q, want %q", got, want)
+			}
+
+			// Later output passes straight through without clearing again
+			q.Write([]byte("ls\r\n"))
+			q.clear()
+			if got := out.String(); got != want+"ls\r\n" {
+				t.Errorf("output after clearing = %q, want %q", got, want+"ls\r\n")
+			}
+		})
+	}
+}
+
+func TestQuietWriterClearsWhenOutputSettles(t *testing.T) {
+	var out syncBuffer
+	q := newQuietWriter(&out)
+	defer q.stop()
+	q.Write([]byte("Welcome\r\n$ "))
+
+	deadline := time.Now().Add(10 * quietSettle)
+	for !strings.Contains(out.String(), clearScreen) {
+		if time.Now().After(deadline) {
+			t.Fatalf("screen not cleared %v after output stopped, output = %q", 10*quietSettle, out.String())
+		}
+		time.Sleep(quietSettle / 10)
+	}
+	if got, want := out.String(), "Welcome\r\n$ "+clearScreen+"$ "; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
+
+func TestQuietWriterStop(t *testing.T) {
+	var out syncBuffer
+	q := newQuietWriter(&out)
+	q.Write([]byte("Welcome\r\n$ "))
+	// Typing stops the clear, so what the user typed isn't wiped
+	q.stop()
+	q.clear()
+	q.Write([]byte("ls"))
+	if got, want := out.String(), "Welcome\r\n$ ls"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
+
+func TestFirstReadReader(t *testing.T) {
+	calls := 0
+	r := &firstReadReader{Reader: strings.NewReader("ab"), onRead: func() { calls++ }}
+	buf := make([]byte, 1)
+	for range 3 {
+		r.Read(buf)
+	}
+	if calls != 1 {
+		t.Errorf("onRead called %d times, want 1", calls)
+	}
+}
@@ -0,0 +1,29 @@
+package ssh
+
+import (
+	"github.com/nathanlytang/rolodex/internal/logger"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/term"
+)
+
+// Tracks the last terminal size sent to the remote PTY
+type windowSize struct {
+	width  int
+	height int
+}
+
+// Sends a window-change request if the local terminal size has changed
+func (w *windowSize) update(session *ssh.Session, fd int) {
+	width, height, err := term.GetSize(fd)
+	if err != nil || (width == w.width && height == w.height) {
+		return
+	}
+
+	if err := session.WindowChange(height, width); err != nil {
+		logger.Errorf("Failed to send window change: %v", err)
+		return
+	}
+
+	logger.Debugf("Remote window resized to %d x %d", width, height)
+	w.width, w.height = width, height
+}
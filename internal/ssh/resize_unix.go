@@ -0,0 +1,43 @@
+//go:build !windows
+
+package ssh
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Propagates local terminal resizes (SIGWINCH) to the remote PTY
+// Returns a function that stops watching and waits for the goroutine to exit
+func watchWindowSize(session *ssh.Session, fd, width, height int) (stop func()) {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGWINCH)
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		size := windowSize{width: width, height: height}
+		for {
+			select {
+			case <-done:
+				return
+			case <-sigs:
+				size.update(session, fd)
+			}
+		}
+	}()
+
+	return func() {
+		signal.Stop(sigs)
+		close(done)
+		wg.Wait()
+	}
+}
@@ -0,0 +1,43 @@
+//go:build windows
+
+package ssh
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Windows has no SIGWINCH, so the terminal size is polled instead
+const windowSizePollInterval = time.Second
+
+// Propagates local terminal resizes to the remote PTY by polling
+// Returns a function that stops watching and waits for the goroutine to exit
+func watchWindowSize(session *ssh.Session, fd, width, height int) (stop func()) {
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		ticker := time.NewTicker(windowSizePollInterval)
+		defer ticker.Stop()
+
+		size := windowSize{width: width, height: height}
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				size.update(session, fd)
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		wg.Wait()
+	}
+}
@@ -1,96 +1,519 @@
 package ssh
 
 import (
+	"errors"
+	"fmt"
+	"io"
+	"maps"
 	"net"
 	"os"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/muesli/cancelreader"
 	"github.com/nathanlytang/rolodex/internal/logger"
 	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
 	"golang.org/x/term"
 )
 
 // Authentication configuration options
 type AuthConfig struct {
-	SSHAgent           bool
-	IdentityFile       string
-	IdentityPassphrase string
-	KeyringService     string
-	KeyringAccount     string
-	Password           string
+	SSHAgent             bool
+	AgentKey             string // Fingerprint of the only agent key to offer, empty offers all
+	IdentityFile         string
+	IdentityPassphrase   string
+	CertificateFile      string        // Certificate for the identity file, empty looks for <identity file>-cert.pub
+	AddKeyToAgent        bool          // Load the identity file into the agent on first use
+	AgentKeyLifetime     time.Duration // How long keys added to the agent are kept, 0 until the agent exits
+	KeyringService       string
+	KeyringAccount       string
+	Password             string
+	PasswordCommand      string // Shell command that prints the password, tried after the keyring
+	KnownHostsFile       string
+	HostKeyPolicy        string                      // One of the HostKeyPolicy constants, empty means tofu
+	StrictKeyPermissions bool                        // Refuse identity files readable by others instead of warning
+	InteractiveAuth      bool                        // Always ask the user to answer keyboard-interactive questions
+	SingleAuthMethod     bool                        // Offer only the first method that could be built, for servers with a low MaxAuthTries
+	Prompter             KeyboardInteractivePrompter // Asks the user keyboard-interactive questions
+}
+
+// Formats the config for logging with the password and passphrase redacted
+func (c AuthConfig) String() string {
+	return fmt.Sprintf("{SSHAgent:%t AgentKey:%s IdentityFile:%s IdentityPassphrase:%s CertificateFile:%s AddKeyToAgent:%t KeyringService:%s KeyringAccount:%s Password:%s PasswordCommand:%s KnownHostsFile:%s HostKeyPolicy:%s InteractiveAuth:%t SingleAuthMethod:%t}",
+		c.SSHAgent, c.AgentKey, c.IdentityFile, redact(c.IdentityPassphrase), c.CertificateFile, c.AddKeyToAgent, c.KeyringService, c.KeyringAccount,
+		redact(c.Password), c.PasswordCommand, c.KnownHostsFile, c.HostKeyPolicy, c.InteractiveAuth, c.SingleAuthMethod)
+}
+
+// Hides a secret in log output while still showing whether it was set
+func redact(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return "[redacted]"
+}
+
+// Default timeouts used when SessionConfig.ConnectTimeout is unset
+const (
+	DefaultDialTimeout      = 10 * time.Second
+	DefaultHandshakeTimeout = 30 * time.Second
+)
+
+// Returned when an established session ends because the connection dropped
+// rather than the remote shell exiting
+type DisconnectError struct {
+	Address string
+	Err     error
+}
+
+func (e *DisconnectError) Error() string {
+	return fmt.Sprintf("connection to %s lost: %v", e.Address, e.Err)
+}
+
+func (e *DisconnectError) Unwrap() error {
+	return e.Err
+}
+
+// Session behaviour options
+type SessionConfig struct {
+	ConnectTimeout    time.Duration             // TCP dial and SSH handshake timeout, 0 uses the defaults
+	FallbackHosts     []string                  // Other addresses of the host, tried in order when it can't be reached
+	KeepAliveInterval time.Duration             // 0 disables keepalives
+	LocalForwards     []string                  // [bind_address:]port:host:hostport, like ssh -L
+	RemoteForwards    []string                  // [bind_address:]port:host:hostport, like ssh -R
+	HostKeyAlgorithms []string                  // Accepted host key algorithms in preference order, empty uses the library defaults
+	Ciphers           []string                  // Accepted ciphers in preference order, empty uses the library defaults
+	ForwardAgent      bool                      // Forward the local SSH agent to the remote host, like ssh -A
+	ForwardX11        bool                      // Forward remote X11 clients to the local $DISPLAY, like ssh -X
+	Options           map[string]string         // Raw ssh_config options, see sessionOptions for the supported keys
+	Env               map[string]string         // Environment variables set on the remote session
+	OnBanner          func(banner string) error // Called with the server's pre-auth banner before the shell starts, an error disconnects
+	JumpHosts         []JumpHost                // Hosts to connect through, outermost first, like ssh -J
+	Proxy             string                    // socks5:// or http:// proxy URL to dial through, empty dials directly
+	Quiet             bool                      // Clear the screen once login output settles, hiding the MOTD
+	ForwardEnv        []string                  // Local environment variables sent to the session when set, like ssh's SendEnv
 }
 
 // Creates authentication methods in priority order
-// Returns array of auth methods
-func buildAuthMethods(config AuthConfig) []ssh.AuthMethod {
+// Each method notes in tracker when it answers the server. agentErr is why agentClient is nil, if it is
+// Returns array of auth methods, and an *AgentUnreachableError if the agent was wanted but left out
+func buildAuthMethods(config AuthConfig, agentClient *agentConn, agentErr error, tracker *authTracker) ([]ssh.AuthMethod, error) {
+	logger.Debugf("Building authentication methods for %v", config)
 	var authMethods []ssh.AuthMethod
+	var names []string
+	add := func(name string, method ssh.AuthMethod) {
+		authMethods = append(authMethods, method)
+		names = append(names, name)
+	}
+	// With SingleAuthMethod building stops at the first method, so later ones
+	// (password commands, agent enrolment) don't run for nothing
+	single := func() bool {
+		if config.SingleAuthMethod && len(authMethods) > 0 {
+			logger.Printf("Single auth method: offering only %s", names[0])
+			return true
+		}
+		return false
+	}
 
+	// Nil interfaces stay nil so the agent is skipped when it couldn't be reached
+	var trackedAgent agent.Agent
+	if agentClient != nil {
+		trackedAgent = tracker.agent(agentClient)
+	}
+
+	var agentFailure error
 	if config.SSHAgent {
-		if agentAuth := TrySSHAgent(); agentAuth != nil {
-			authMethods = append(authMethods, agentAuth)
+		if trackedAgent == nil {
+			if agentErr == nil {
+				agentErr = ErrNoAgent
+			}
+			agentFailure = &AgentUnreachableError{Err: agentErr}
+		} else if method, err := TrySSHAgent(trackedAgent, config.AgentKey); err != nil {
+			agentFailure = err
+		} else {
+			add("SSH agent", method)
+		}
+		if agentFailure != nil {
+			logger.Errorf("Not using the SSH agent: %v", agentFailure)
 		}
 	}
+	if single() {
+		return authMethods, agentFailure
+	}
 
 	if config.IdentityFile != "" {
-		if keyAuth := TryIdentityFile(config.IdentityFile, config.IdentityPassphrase); keyAuth != nil {
-			authMethods = append(authMethods, keyAuth)
+		var keyAuth ssh.AuthMethod
+		// Keys readable by others are never enrolled under strict permissions
+		if config.AddKeyToAgent && trackedAgent != nil && !(config.StrictKeyPermissions && CheckKeyPermissions(config.IdentityFile) != nil) {
+			var err error
+			keyAuth, err = AddIdentityToAgent(trackedAgent, config.IdentityFile, config.IdentityPassphrase, config.AgentKeyLifetime, config.Prompter)
+			if err != nil {
+				logger.Errorf("Not adding identity file to the SSH agent: %v", err)
+			}
+		}
+		if keyAuth == nil {
+			if signer := loadIdentityFile(config.IdentityFile, config.IdentityPassphrase, config.StrictKeyPermissions); signer != nil {
+				// The certificate goes first, the plain key is still offered if the server doesn't trust its CA
+				if certSigner := loadCertificate(signer, config.IdentityFile, config.CertificateFile); certSigner != nil {
+					keyAuth = ssh.PublicKeys(
+						tracker.signer("certificate for key "+config.IdentityFile, certSigner),
+						tracker.signer("key "+config.IdentityFile, signer),
+					)
+				} else {
+					keyAuth = tracker.publicKey("key "+config.IdentityFile, signer)
+				}
+			}
 		}
+		if keyAuth != nil {
+			add("key "+config.IdentityFile, keyAuth)
+		}
+	}
+	if single() {
+		return authMethods, agentFailure
 	}
 
+	// Password is tried first, keyboard-interactive as fallback for PAM
+	var password string
 	if config.KeyringService != "" && config.KeyringAccount != "" {
-		password, err := GetPasswordFromKeyring(config.KeyringService, config.KeyringAccount)
-		if err == nil && password != "" {
-			authMethods = append(authMethods, TryPasswordAuth(password)...)
+		keyringPassword, err := GetPasswordFromKeyring(config.KeyringService, config.KeyringAccount)
+		if err == nil && keyringPassword != "" {
+			password = keyringPassword
+			add("keyring password", tracker.password("keyring password", keyringPassword))
 		}
 	}
+	if single() {
+		return authMethods, agentFailure
+	}
+
+	if config.PasswordCommand != "" {
+		commandPassword, err := RunPasswordCommand(config.PasswordCommand)
+		if err != nil {
+			logger.Errorf("%v", err)
+		} else {
+			if password == "" {
+				password = commandPassword
+			}
+			add("password command", tracker.password("password command", commandPassword))
+		}
+	}
+	if single() {
+		return authMethods, agentFailure
+	}
 
 	if config.Password != "" {
-		authMethods = append(authMethods, TryPasswordAuth(config.Password)...)
+		if password == "" {
+			password = config.Password
+		}
+		add("password", tracker.password("password", config.Password))
+	}
+	if single() {
+		return authMethods, agentFailure
 	}
 
-	logger.Printf("Total authentication methods configured: %d", len(authMethods))
-	return authMethods
+	if password != "" || config.InteractiveAuth {
+		logger.Debugf("Adding keyboard-interactive authentication method (auto-answer: %t)", !config.InteractiveAuth && password != "")
+		challenge := keyboardInteractiveChallenge(password, !config.InteractiveAuth, config.Prompter)
+		add("keyboard-interactive", tracker.keyboardInteractive("keyboard-interactive", challenge))
+	}
+	if single() {
+		return authMethods, agentFailure
+	}
+
+	logger.Debugf("Total authentication methods configured: %d", len(authMethods))
+	return authMethods, agentFailure
+}
+
+// Returns host:port for dialing, bracketing IPv6 literals like [2001:db8::1]:22
+// Hosts that are already bracketed are accepted as well
+func Address(host string, port int) string {
+	if strings.HasPrefix(host, "[") && strings.HasSuffix(host, "]") {
+		host = host[1 : len(host)-1]
+	}
+	return net.JoinHostPort(host, strconv.Itoa(port))
+}
+
+// Opens and immediately closes a TCP connection to address
+// Returns error if the host can't be resolved or the port doesn't accept connections
+func CheckReachable(address string, timeout time.Duration) error {
+	conn, err := net.DialTimeout("tcp", address, timeout)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// Returns the address of the first host whose port accepts a TCP connection
+// Each host gets the full timeout, so an unreachable one doesn't eat into the next one's time
+// Returns the error for every host, joined, if none can be reached
+func firstReachable(hosts []string, port int, timeout time.Duration) (string, error) {
+	var errs []error
+	for _, host := range hosts {
+		address := Address(host, port)
+		logger.Debugf("Testing TCP connection to %s...", address)
+		err := CheckReachable(address, timeout)
+		if err == nil {
+			if len(hosts) > 1 {
+				logger.Printf("Using address %s", address)
+			}
+			return address, nil
+		}
+		if len(hosts) > 1 {
+			logger.Printf("Cannot reach %s: %v", address, err)
+		}
+		errs = append(errs, err)
+	}
+	return "", errors.Join(errs...)
+}
+
+// An authenticated connection shared by shell and SFTP sessions
+type Client struct {
+	*ssh.Client
+	Address string
+	Banner  string // Pre-authentication banner sent by the server, empty if none
+	// Authentication method the server accepted, e.g. "key ~/.ssh/id_ed25519"
+	// Empty if the server let us in without asking, as with "none" authentication
+	AuthMethod string
+	agent      *agentConn    // Kept open for agent forwarding, nil without an agent
+	jumps      []*ssh.Client // Jump host connections the client is tunnelled through, outermost first
+}
+
+// Closes the connection, then the jump host and agent connections, if any
+func (c *Client) Close() error {
+	if c.agent != nil {
+		c.agent.Close()
+	}
+	err := c.Client.Close()
+	closeJumpHosts(c.jumps)
+	return err
 }
 
 // Connects to an SSH server using multiple authentication methods with priority
 // Returns error if connection fails
-func StartSession(host string, port int, user string, authConfig AuthConfig, termWidth, termHeight int) error {
+func StartSession(host string, port int, user string, authConfig AuthConfig, sessionConfig SessionConfig, termWidth, termHeight int) error {
+	client, err := Connect(host, port, user, authConfig, sessionConfig)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	return client.Shell(sessionConfig, termWidth, termHeight)
+}
+
+// Connects and authenticates to an SSH server, then disconnects without opening a session
+// Errors are Connect's, so ClassifyError and *UnknownHostKeyError work on them
+func Probe(host string, port int, user string, authConfig AuthConfig, sessionConfig SessionConfig) error {
+	client, err := Connect(host, port, user, authConfig, sessionConfig)
+	if err != nil {
+		return err
+	}
+	return client.Close()
+}
+
+// Shows the server banner, then runs an interactive shell in the current terminal until it exits
+// Lets callers connect first, e.g. while showing progress, and hand over the terminal afterwards
+func (c *Client) Shell(sessionConfig SessionConfig, termWidth, termHeight int) error {
+	if c.Banner != "" && sessionConfig.OnBanner != nil {
+		if err := sessionConfig.OnBanner(c.Banner); err != nil {
+			return err
+		}
+	}
+
+	return runShell(c, sessionConfig, termWidth, termHeight)
+}
+
+// Dials and authenticates to an SSH server without opening a session
+// Unknown host keys are returned as *UnknownHostKeyError so the caller can ask to trust them
+func Connect(host string, port int, user string, authConfig AuthConfig, sessionConfig SessionConfig) (*Client, error) {
 	logger.Printf("Attempting connection to %s@%s:%d", user, host, port)
 
-	address := host + ":" + strconv.Itoa(port)
-	logger.Printf("Testing TCP connection to %s...", address)
-	conn, err := net.DialTimeout("tcp", address, 10*time.Second)
+	dialTimeout, handshakeTimeout := DefaultDialTimeout, DefaultHandshakeTimeout
+	if sessionConfig.ConnectTimeout < 0 {
+		return nil, logger.Fatalf("Invalid connect timeout %v: must be positive", sessionConfig.ConnectTimeout)
+	} else if sessionConfig.ConnectTimeout > 0 {
+		dialTimeout, handshakeTimeout = sessionConfig.ConnectTimeout, sessionConfig.ConnectTimeout
+	}
+	logger.Debugf("Using dial timeout %v and handshake timeout %v", dialTimeout, handshakeTimeout)
+
+	if err := ValidateAlgorithms(sessionConfig.HostKeyAlgorithms, sessionConfig.Ciphers); err != nil {
+		return nil, logger.Fatalf("Invalid algorithm configuration: %v", err)
+	}
+	if hasInsecureAlgorithms(sessionConfig.HostKeyAlgorithms, sessionConfig.Ciphers) {
+		logger.Printf("Warning: configured algorithms include ones with known weaknesses")
+	}
+
+	// One agent connection serves both authentication and forwarding
+	var agentClient *agentConn
+	var agentErr error
+	if authConfig.SSHAgent || authConfig.AddKeyToAgent || sessionConfig.ForwardAgent || jumpHostsUseAgent(sessionConfig.JumpHosts) {
+		agentClient, agentErr = dialAgent()
+		if agentErr != nil {
+			logger.Printf("%v", agentErr)
+		}
+	}
+	closeAgent := func() {
+		if agentClient != nil {
+			agentClient.Close()
+		}
+	}
+
+	var tracker authTracker
+	var banner strings.Builder
+	config, err := clientConfig(user, authConfig, sessionConfig, agentClient, agentErr, &tracker, &banner, handshakeTimeout)
+	if err != nil {
+		closeAgent()
+		return nil, err
+	}
+
+	var client *ssh.Client
+	var jumps []*ssh.Client
+	var address string
+	if len(sessionConfig.JumpHosts) > 0 {
+		jumps, err = dialJumpHosts(sessionConfig.JumpHosts, authConfig.Prompter, agentClient, agentErr, sessionConfig, dialTimeout, handshakeTimeout)
+		if err != nil {
+			closeAgent()
+			return nil, err
+		}
+		client, address, err = dialThroughJumpHost(jumps[len(jumps)-1], append([]string{host}, sessionConfig.FallbackHosts...), port, config)
+		if err != nil {
+			closeJumpHosts(jumps)
+		}
+	} else if sessionConfig.Proxy != "" {
+		client, address, err = dialThroughProxy(sessionConfig.Proxy, append([]string{host}, sessionConfig.FallbackHosts...), port, config, dialTimeout)
+	} else {
+		address, err = firstReachable(append([]string{host}, sessionConfig.FallbackHosts...), port, dialTimeout)
+		if err != nil {
+			closeAgent()
+			connectErr := newConnectError(Address(host, port), err)
+			// Reaching the port failed, so anything unrecognised is still an unreachable host
+			if connectErr.Kind == ErrorUnknown {
+				connectErr.Kind = ErrorUnreachable
+			}
+			return nil, connectErr
+		}
+		logger.Debugf("TCP connection successful, attempting SSH handshake...")
+		client, err = ssh.Dial("tcp", address, config)
+	}
 	if err != nil {
-		return logger.Fatalf("Cannot reach %s - TCP connection failed: %v\nCheck firewall, DNS, and network connectivity", address, err)
+		closeAgent()
+		// Unknown hosts are returned as-is so the TUI can ask for confirmation
+		var unknownErr *UnknownHostKeyError
+		if errors.As(err, &unknownErr) {
+			return nil, unknownErr
+		}
+		if address == "" {
+			address = Address(host, port)
+		}
+		connectErr := newConnectError(address, err)
+		if connectErr.Kind == ErrorAuth {
+			logger.Printf("Authentication methods we tried: %d methods", len(config.Auth))
+		}
+		return nil, connectErr
 	}
-	conn.Close()
-	logger.Printf("TCP connection successful, attempting SSH handshake...")
 
-	authMethods := buildAuthMethods(authConfig)
+	logger.Printf("SSH connection established successfully!")
+	authMethod := tracker.method()
+	if authMethod != "" {
+		logger.Printf("Authenticated via: %s", authMethod)
+	}
+	if banner.Len() > 0 {
+		logger.Printf("Server banner from %s:\n%s", address, strings.TrimRight(banner.String(), "\r\n"))
+	}
+	return &Client{Client: client, Address: address, Banner: banner.String(), AuthMethod: authMethod, agent: agentClient, jumps: jumps}, nil
+}
 
+// Builds the client config for logging in as user, with auth methods that note in tracker
+// which one the server accepted and banners written to banner
+func clientConfig(user string, authConfig AuthConfig, sessionConfig SessionConfig, agentClient *agentConn, agentErr error, tracker *authTracker, banner *strings.Builder, timeout time.Duration) (*ssh.ClientConfig, error) {
+	authMethods, agentFailure := buildAuthMethods(authConfig, agentClient, agentErr, tracker)
 	if len(authMethods) == 0 {
-		return logger.Fatal("No authentication method available. Configure at least one: ssh_agent, identity_file, keyring, or password.")
+		// An unreachable agent explains why there's nothing to offer better than the generic error
+		if agentFailure != nil {
+			logger.Fatal(agentFailure.Error())
+			return nil, agentFailure
+		}
+		return nil, logger.Fatal("No authentication method available. Configure at least one: ssh_agent, identity_file, keyring, password_command, or password.")
+	}
+
+	hostKeyCallback, err := buildHostKeyCallback(authConfig.HostKeyPolicy, authConfig.KnownHostsFile)
+	if err != nil {
+		return nil, logger.Fatalf("Host key verification unavailable: %v", err)
 	}
 
+	// Servers may send several banner messages, so they're joined
 	config := &ssh.ClientConfig{
-		User:            user,
-		Auth:            authMethods,
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
-		Timeout:         30 * time.Second,
+		User: user,
+		Auth: authMethods,
+		HostKeyCallback: func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			logger.Debugf("Server offered %s host key", key.Type())
+			return hostKeyCallback(hostname, remote, key)
+		},
+		BannerCallback: func(message string) error {
+			banner.WriteString(message)
+			return nil
+		},
+		HostKeyAlgorithms: sessionConfig.HostKeyAlgorithms,
+		Timeout:           timeout,
+	}
+	config.Ciphers = sessionConfig.Ciphers
+	if len(config.Ciphers) > 0 {
+		logger.Debugf("Using ciphers: %v", config.Ciphers)
 	}
+	return config, nil
+}
 
-	client, err := ssh.Dial("tcp", address, config)
-	if err != nil {
-		if authErr, ok := err.(*ssh.ServerAuthError); ok {
-			logger.Printf("Authentication methods we tried: %d methods", len(authMethods))
-			return logger.Fatalf("SSH authentication failed!\nErrors from server: %v\nFull error: %v", authErr.Errors, err)
+// Returns the environment variables to send to a session, in order, and the names of the forwarded local ones
+// Forwarded local variables go first so a host's own env, and then its SetEnv option, overrides them
+func sessionEnv(sessionConfig SessionConfig, opts sessionOptions, lookup func(string) (string, bool)) (vars [][2]string, forwarded []string) {
+	vars = make([][2]string, 0, len(sessionConfig.ForwardEnv)+len(sessionConfig.Env)+len(opts.env))
+	for _, name := range sessionConfig.ForwardEnv {
+		if value, ok := lookup(name); ok {
+			vars = append(vars, [2]string{name, value})
+			forwarded = append(forwarded, name)
 		}
-		return logger.Fatalf("SSH connection failed: %v", err)
 	}
-	defer client.Close()
+	for _, name := range slices.Sorted(maps.Keys(sessionConfig.Env)) {
+		vars = append(vars, [2]string{name, sessionConfig.Env[name]})
+	}
+	return append(vars, opts.env...), forwarded
+}
 
-	logger.Printf("SSH connection established successfully!")
+// Copies terminal input from in to the session until the returned stop function is called
+// Copying in directly would leave a goroutine blocked reading it after the session ends,
+// which swallows the first key pressed once the host list is back, so the read is cancelled instead
+// onInput, if set, is called when the first input arrives
+func forwardInput(session *ssh.Session, in *os.File, onInput func()) (stop func(), err error) {
+	stdin, err := session.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open session input: %w", err)
+	}
+	input, err := cancelreader.NewReader(in)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read terminal input: %w", err)
+	}
+
+	var r io.Reader = input
+	if onInput != nil {
+		r = &firstReadReader{Reader: input, onRead: onInput}
+	}
+	go func() {
+		io.Copy(stdin, r)
+		stdin.Close()
+	}()
+	return func() {
+		input.Cancel()
+		input.Close()
+	}, nil
+}
+
+// Runs an interactive shell over client in the current terminal until it exits
+func runShell(client *Client, sessionConfig SessionConfig, termWidth, termHeight int) error {
+	address := client.Address
+	opts := parseOptions(sessionConfig.Options)
+
+	stopForwards := startForwards(client.Client, sessionConfig.LocalForwards, sessionConfig.RemoteForwards)
+	defer stopForwards()
 
 	session, err := client.NewSession()
 	if err != nil {
@@ -128,14 +551,74 @@ func StartSession(host string, port int, user string, authConfig AuthConfig, ter
 		return logger.Fatalf("Request for pseudo terminal failed: %v", err)
 	}
 
-	session.Stdin = os.Stdin
 	session.Stdout = os.Stdout
 	session.Stderr = os.Stderr
+	var onInput func()
+	if sessionConfig.Quiet {
+		quiet := newQuietWriter(os.Stdout)
+		defer quiet.stop()
+		// Clearing after the user starts typing would wipe what they typed
+		onInput = quiet.stop
+		session.Stdout = quiet
+	}
+
+	stopInput, err := forwardInput(session, os.Stdin, onInput)
+	if err != nil {
+		return logger.Fatalf("Failed to forward terminal input: %v", err)
+	}
+	defer stopInput()
+
+	// Servers reject variables not listed in their AcceptEnv, which isn't fatal
+	vars, forwarded := sessionEnv(sessionConfig, opts, os.LookupEnv)
+	if len(forwarded) > 0 {
+		logger.Printf("Forwarding local environment variables: %s", strings.Join(forwarded, ", "))
+	}
+	for _, env := range vars {
+		if err := session.Setenv(env[0], env[1]); err != nil {
+			logger.Printf("Warning: server rejected environment variable %s: %v", env[0], err)
+		}
+	}
+
+	if sessionConfig.ForwardAgent {
+		requestAgentForwarding(client.Client, session, client.agent)
+	}
+	if sessionConfig.ForwardX11 {
+		requestX11Forwarding(client.Client, session)
+	}
 
 	if err := session.Shell(); err != nil {
 		return logger.Fatalf("Failed to start shell: %v", err)
 	}
-	session.Wait()
+
+	keepAliveInterval := sessionConfig.KeepAliveInterval
+	if opts.keepAlive != nil {
+		keepAliveInterval = *opts.keepAlive
+	}
+	stopKeepAlive := func() error { return nil }
+	if keepAliveInterval > 0 {
+		stopKeepAlive = startKeepAlive(client.Client, keepAliveInterval)
+	}
+
+	stopWatchingSize := watchWindowSize(session, fd, width, height)
+
+	waitErr := session.Wait()
+
+	stopWatchingSize()
+	if err := stopKeepAlive(); err != nil {
+		logger.Printf("Connection to %s lost: %v", address, err)
+		return &DisconnectError{Address: address, Err: err}
+	}
+
+	if waitErr != nil {
+		// A non-zero exit status is still a clean logout
+		var exitErr *ssh.ExitError
+		if errors.As(waitErr, &exitErr) {
+			logger.Printf("Remote shell exited with status %d", exitErr.ExitStatus())
+			return nil
+		}
+		logger.Printf("Connection to %s lost: %v", address, waitErr)
+		return &DisconnectError{Address: address, Err: waitErr}
+	}
 
 	return nil
 }
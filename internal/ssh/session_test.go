@@ -0,0 +1,214 @@
+package ssh
+
+import (
+	"crypto/ed25519"
+	"encoding/pem"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+func TestAddress(t *testing.T) {
+	tests := []struct {
+		host string
+		port int
+		want string
+	}{
+		{"web.example.com", 22, "web.example.com:22"},
+		{"10.0.0.1", 2222, "10.0.0.1:2222"},
+		{"::1", 22, "[::1]:22"},
+		{"[::1]", 22, "[::1]:22"},
+		{"fe80::1", 2222, "[fe80::1]:2222"},
+		{"[fe80::1]", 2222, "[fe80::1]:2222"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := Address(tt.host, tt.port); got != tt.want {
+				t.Errorf("Address(%q, %d) = %q, want %q", tt.host, tt.port, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSessionEnv(t *testing.T) {
+	local := map[string]string{"LANG": "en_US.UTF-8", "COLORTERM": "truecolor", "EDITOR": "vim"}
+	lookup := func(name string) (string, bool) {
+		value, ok := local[name]
+		return value, ok
+	}
+
+	tests := []struct {
+		name          string
+		config        SessionConfig
+		opts          sessionOptions
+		want          [][2]string
+		wantForwarded []string
+	}{
+		{
+			name: "nothing set",
+		},
+		{
+			name:          "forwarded in listed order",
+			config:        SessionConfig{ForwardEnv: []string{"LANG", "COLORTERM"}},
+			want:          [][2]string{{"LANG", "en_US.UTF-8"}, {"COLORTERM", "truecolor"}},
+			wantForwarded: []string{"LANG", "COLORTERM"},
+		},
+		{
+			name:          "unset local variables are skipped",
+			config:        SessionConfig{ForwardEnv: []string{"LANG", "TERM_PROGRAM"}},
+			want:          [][2]string{{"LANG", "en_US.UTF-8"}},
+			wantForwarded: []string{"LANG"},
+		},
+		{
+			name:   "host env sorted by name",
+			config: SessionConfig{Env: map[string]string{"TZ": "UTC", "APP_ENV": "prod"}},
+			want:   [][2]string{{"APP_ENV", "prod"}, {"TZ", "UTC"}},
+		},
+		{
+			name: "forwarded then host env then SetEnv",
+			config: SessionConfig{
+				ForwardEnv: []string{"LANG", "EDITOR"},
+				Env:        map[string]string{"LANG": "C", "TZ": "UTC"},
+			},
+			opts: sessionOptions{env: [][2]string{{"EDITOR", "nano"}, {"TZ", "Europe/Paris"}}},
+			want: [][2]string{
+				{"LANG", "en_US.UTF-8"}, {"EDITOR", "vim"},
+				{"LANG", "C"}, {"TZ", "UTC"},
+				{"EDITOR", "nano"}, {"TZ", "Europe/Paris"},
+			},
+			wantForwarded: []string{"LANG", "EDITOR"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, forwarded := sessionEnv(tt.config, tt.opts, lookup)
+			if len(got) != 0 || len(tt.want) != 0 {
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("vars = %v, want %v", got, tt.want)
+				}
+			}
+			if !reflect.DeepEqual(forwarded, tt.wantForwarded) {
+				t.Errorf("forwarded = %v, want %v", forwarded, tt.wantForwarded)
+			}
+		})
+	}
+}
+
+// Writes a new unencrypted ed25519 private key to a temporary file and returns its path
+func writeTestKey(t *testing.T) string {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := ssh.MarshalPrivateKey(priv, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "id_ed25519")
+	if err := os.WriteFile(path, pem.EncodeToMemory(block), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// An agent whose socket accepts connections but doesn't answer
+type deadAgent struct {
+	agent.ExtendedAgent
+}
+
+func (deadAgent) List() ([]*agent.Key, error) {
+	return nil, io.EOF
+}
+
+func TestBuildAuthMethods(t *testing.T) {
+	key := writeTestKey(t)
+	liveAgent := &agentConn{ExtendedAgent: agent.NewKeyring().(agent.ExtendedAgent)}
+	unreachable := errors.New("connection refused")
+
+	tests := []struct {
+		name     string
+		config   AuthConfig
+		agent    *agentConn
+		agentErr error
+		want     int   // Number of methods offered
+		wantErr  error // Wrapped by the returned *AgentUnreachableError, nil for no error
+	}{
+		{
+			name:   "every method",
+			config: AuthConfig{SSHAgent: true, IdentityFile: key, Password: "secret"},
+			agent:  liveAgent,
+			want:   4, // Agent, key, password and keyboard-interactive
+		},
+		{
+			name:   "single method stops at the agent",
+			config: AuthConfig{SSHAgent: true, IdentityFile: key, Password: "secret", SingleAuthMethod: true},
+			agent:  liveAgent,
+			want:   1,
+		},
+		{
+			name:   "single method stops at the key",
+			config: AuthConfig{IdentityFile: key, Password: "secret", SingleAuthMethod: true},
+			want:   1,
+		},
+		{
+			name:   "single method with only a password",
+			config: AuthConfig{Password: "secret", SingleAuthMethod: true},
+			want:   1,
+		},
+		{
+			name:   "interactive auth without a password",
+			config: AuthConfig{InteractiveAuth: true},
+			want:   1,
+		},
+		{
+			name:     "agent unreachable",
+			config:   AuthConfig{SSHAgent: true, Password: "secret"},
+			agentErr: unreachable,
+			want:     2,
+			wantErr:  unreachable,
+		},
+		{
+			name:    "agent not configured",
+			config:  AuthConfig{SSHAgent: true},
+			wantErr: ErrNoAgent,
+		},
+		{
+			name:    "agent not answering",
+			config:  AuthConfig{SSHAgent: true, IdentityFile: key},
+			agent:   &agentConn{ExtendedAgent: deadAgent{}},
+			want:    1,
+			wantErr: io.EOF,
+		},
+		{
+			name:     "agent not wanted",
+			config:   AuthConfig{IdentityFile: key},
+			agentErr: unreachable,
+			want:     1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			methods, err := buildAuthMethods(tt.config, tt.agent, tt.agentErr, &authTracker{})
+			if len(methods) != tt.want {
+				t.Errorf("buildAuthMethods() returned %d methods, want %d", len(methods), tt.want)
+			}
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Errorf("buildAuthMethods() error = %v, want nil", err)
+				}
+				return
+			}
+			var agentErr *AgentUnreachableError
+			if !errors.As(err, &agentErr) || !errors.Is(err, tt.wantErr) {
+				t.Errorf("buildAuthMethods() error = %v, want an *AgentUnreachableError wrapping %v", err, tt.wantErr)
+			}
+		})
+	}
+}
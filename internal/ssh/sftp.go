@@ -0,0 +1,101 @@
+package ssh
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/nathanlytang/rolodex/internal/logger"
+	"github.com/pkg/sftp"
+)
+
+// Opens an SFTP session over the connection
+func (c *Client) SFTP() (*sftp.Client, error) {
+	sc, err := sftp.NewClient(c.Client)
+	if err != nil {
+		return nil, logger.Fatalf("Failed to start SFTP on %s: %v", c.Address, err)
+	}
+	logger.Printf("SFTP session started on %s", c.Address)
+	return sc, nil
+}
+
+// Reports bytes copied so far out of the total size
+type ProgressFunc func(done, total int64)
+
+// Writer that reports progress as data passes through it
+type progressWriter struct {
+	w        io.Writer
+	done     int64
+	total    int64
+	progress ProgressFunc
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	n, err := p.w.Write(b)
+	p.done += int64(n)
+	p.progress(p.done, p.total)
+	return n, err
+}
+
+// Copies a remote file to localPath
+func Download(sc *sftp.Client, remotePath, localPath string, progress ProgressFunc) error {
+	src, err := sc.Open(remotePath)
+	if err != nil {
+		return fmt.Errorf("failed to open remote file: %w", err)
+	}
+	defer src.Close()
+
+	info, err := src.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat remote file: %w", err)
+	}
+
+	dst, err := os.OpenFile(localPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode().Perm()|0600)
+	if err != nil {
+		return fmt.Errorf("failed to create local file: %w", err)
+	}
+
+	_, err = io.Copy(&progressWriter{w: dst, total: info.Size(), progress: progress}, src)
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("download failed: %w", err)
+	}
+
+	logger.Printf("Downloaded %s to %s (%d bytes)", remotePath, localPath, info.Size())
+	return nil
+}
+
+// Copies a local file to remotePath
+func Upload(sc *sftp.Client, localPath, remotePath string, progress ProgressFunc) error {
+	src, err := os.Open(localPath)
+	if err != nil {
+		return fmt.Errorf("failed to open local file: %w", err)
+	}
+	defer src.Close()
+
+	info, err := src.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat local file: %w", err)
+	}
+
+	dst, err := sc.OpenFile(remotePath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC)
+	if err != nil {
+		return fmt.Errorf("failed to create remote file: %w", err)
+	}
+
+	_, err = io.Copy(&progressWriter{w: dst, total: info.Size(), progress: progress}, src)
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("upload failed: %w", err)
+	}
+	if err := sc.Chmod(remotePath, info.Mode().Perm()); err != nil {
+		logger.Errorf("Failed to set permissions on %s: %v", remotePath, err)
+	}
+
+	logger.Printf("Uploaded %s to %s (%d bytes)", localPath, remotePath, info.Size())
+	return nil
+}
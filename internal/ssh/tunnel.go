@@ -0,0 +1,86 @@
+package ssh
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+
+	"github.com/nathanlytang/rolodex/internal/logger"
+)
+
+// Port forwards kept running over a connection without a shell
+type Tunnel struct {
+	client        *Client
+	stopForwards  func()
+	stopKeepAlive func() error
+	done          chan struct{}
+	err           error
+	closing       atomic.Bool
+	closeOnce     sync.Once
+}
+
+// Starts the forwards in sessionConfig over client and runs them until Close or the connection drops
+// The tunnel owns client from then on
+// Returns error if sessionConfig has no forwards
+func StartTunnel(client *Client, sessionConfig SessionConfig) (*Tunnel, error) {
+	if len(sessionConfig.LocalForwards) == 0 && len(sessionConfig.RemoteForwards) == 0 {
+		client.Close()
+		return nil, errors.New("no port forwards configured for a tunnel")
+	}
+
+	t := &Tunnel{
+		client:        client,
+		stopForwards:  startForwards(client.Client, sessionConfig.LocalForwards, sessionConfig.RemoteForwards),
+		stopKeepAlive: func() error { return nil },
+		done:          make(chan struct{}),
+	}
+	if sessionConfig.KeepAliveInterval > 0 {
+		t.stopKeepAlive = startKeepAlive(client.Client, sessionConfig.KeepAliveInterval)
+	}
+
+	go func() {
+		waitErr := client.Wait()
+		t.stopForwards()
+		keepAliveErr := t.stopKeepAlive()
+		if !t.closing.Load() {
+			err := keepAliveErr
+			if err == nil {
+				err = waitErr
+			}
+			if err == nil {
+				err = errors.New("connection closed by server")
+			}
+			logger.Printf("Tunnel to %s lost: %v", client.Address, err)
+			t.err = &DisconnectError{Address: client.Address, Err: err}
+		}
+		close(t.done)
+	}()
+
+	logger.Printf("Tunnel to %s started", client.Address)
+	return t, nil
+}
+
+// Returns a channel that is closed once the tunnel has stopped
+func (t *Tunnel) Done() <-chan struct{} {
+	return t.done
+}
+
+// Returns why the tunnel stopped, nil while running or after Close
+func (t *Tunnel) Err() error {
+	select {
+	case <-t.done:
+		return t.err
+	default:
+		return nil
+	}
+}
+
+// Stops the forwards and closes the connection
+func (t *Tunnel) Close() {
+	t.closeOnce.Do(func() {
+		t.closing.Store(true)
+		t.client.Close()
+		<-t.done
+		logger.Printf("Tunnel to %s closed", t.client.Address)
+	})
+}
@@ -0,0 +1,180 @@
+package ssh
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/nathanlytang/rolodex/internal/logger"
+	"golang.org/x/crypto/ssh"
+)
+
+// Authentication protocol X servers use for cookies
+const x11AuthProtocol = "MIT-MAGIC-COOKIE-1"
+
+// Payload of an x11-req channel request, RFC 4254 section 6.3.1
+type x11Request struct {
+	SingleConnection bool
+	AuthProtocol     string
+	AuthCookie       string
+	ScreenNumber     uint32
+}
+
+// Extra data of an x11 channel open, naming the remote client that connected
+type x11ChannelData struct {
+	OriginatorAddress string
+	OriginatorPort    uint32
+}
+
+// Forwards X11 connections on the remote host to the local display, like ssh -X
+// Failures are logged rather than aborting the session
+func requestX11Forwarding(client *ssh.Client, session *ssh.Session) {
+	display := os.Getenv("DISPLAY")
+	if display == "" {
+		logger.Printf("X11 forwarding requested but DISPLAY is not set")
+		return
+	}
+	network, address, screen, err := parseDisplay(display)
+	if err != nil {
+		logger.Errorf("X11 forwarding disabled: %v", err)
+		return
+	}
+
+	cookie, err := x11Cookie(display)
+	if err != nil {
+		logger.Errorf("X11 forwarding disabled: %v", err)
+		return
+	}
+
+	channels := client.HandleChannelOpen("x11")
+	if channels == nil {
+		logger.Errorf("X11 forwarding disabled: x11 channels are already handled on this connection")
+		return
+	}
+	go acceptX11Channels(channels, network, address)
+
+	ok, err := session.SendRequest("x11-req", true, ssh.Marshal(&x11Request{
+		AuthProtocol: x11AuthProtocol,
+		AuthCookie:   cookie,
+		ScreenNumber: uint32(screen),
+	}))
+	if err != nil {
+		logger.Errorf("Failed to request X11 forwarding: %v", err)
+		return
+	}
+	if !ok {
+		logger.Printf("Server refused X11 forwarding, check X11Forwarding in its sshd_config")
+		return
+	}
+	logger.Printf("X11 forwarding to %s requested successfully", display)
+}
+
+// Proxies each x11 channel the server opens to the local display until the connection closes
+func acceptX11Channels(channels <-chan ssh.NewChannel, network, address string) {
+	for ch := range channels {
+		var data x11ChannelData
+		if err := ssh.Unmarshal(ch.ExtraData(), &data); err == nil {
+			logger.Printf("X11 channel opened by %s:%d", data.OriginatorAddress, data.OriginatorPort)
+		} else {
+			logger.Printf("X11 channel opened")
+		}
+
+		local, err := net.Dial(network, address)
+		if err != nil {
+			logger.Errorf("Failed to connect to the local X display at %s: %v", address, err)
+			ch.Reject(ssh.ConnectionFailed, "cannot connect to the X display")
+			continue
+		}
+		channel, requests, err := ch.Accept()
+		if err != nil {
+			logger.Errorf("Failed to accept X11 channel: %v", err)
+			local.Close()
+			continue
+		}
+		go ssh.DiscardRequests(requests)
+		go proxyX11(channel, local)
+	}
+}
+
+// Copies between an X11 channel and the local display until either side closes
+func proxyX11(channel ssh.Channel, local net.Conn) {
+	done := make(chan struct{}, 2)
+	go func() {
+		io.Copy(local, channel)
+		done <- struct{}{}
+	}()
+	go func() {
+		io.Copy(channel, local)
+		channel.CloseWrite()
+		done <- struct{}{}
+	}()
+	<-done
+	channel.Close()
+	local.Close()
+	logger.Debugf("X11 channel closed")
+}
+
+// Works out where the X server for display listens
+// ":0" and "unix:0" use the local socket, "host:0" TCP port 6000 on host, and
+// "/path/to/socket:0", as XQuartz sets, the socket at that path. The screen defaults to 0
+func parseDisplay(display string) (network, address string, screen int, err error) {
+	colon := strings.LastIndex(display, ":")
+	if colon < 0 {
+		return "", "", 0, fmt.Errorf("invalid DISPLAY %q", display)
+	}
+	host, rest := display[:colon], display[colon+1:]
+	number, screenPart, _ := strings.Cut(rest, ".")
+	n, err := strconv.Atoi(number)
+	if err != nil || n < 0 {
+		return "", "", 0, fmt.Errorf("invalid display number in DISPLAY %q", display)
+	}
+	if screenPart != "" {
+		if screen, err = strconv.Atoi(screenPart); err != nil || screen < 0 {
+			return "", "", 0, fmt.Errorf("invalid screen number in DISPLAY %q", display)
+		}
+	}
+
+	switch {
+	case strings.HasPrefix(host, "/"):
+		return "unix", display, screen, nil
+	case host == "" || host == "unix":
+		return "unix", "/tmp/.X11-unix/X" + number, screen, nil
+	}
+	return "tcp", Address(host, 6000+n), screen, nil
+}
+
+// Returns the hex cookie to send the server for display
+// The display's real cookie comes from xauth so remote clients are accepted by the local
+// X server. Without xauth a random cookie is sent, which works for servers that don't check
+func x11Cookie(display string) (string, error) {
+	var stdout bytes.Buffer
+	cmd := exec.Command("xauth", "list", display)
+	cmd.Stdout = &stdout
+	if err := cmd.Run(); err == nil {
+		scanner := bufio.NewScanner(&stdout)
+		for scanner.Scan() {
+			// Lines look like "host/unix:0  MIT-MAGIC-COOKIE-1  0123abcd..."
+			fields := strings.Fields(scanner.Text())
+			if len(fields) == 3 && fields[1] == x11AuthProtocol {
+				return fields[2], nil
+			}
+		}
+		logger.Printf("xauth has no %s for %s, sending a random cookie", x11AuthProtocol, display)
+	} else {
+		logger.Printf("Could not read the X11 cookie with xauth (%v), sending a random cookie", err)
+	}
+
+	cookie := make([]byte, 16)
+	if _, err := rand.Read(cookie); err != nil {
+		return "", fmt.Errorf("failed to generate X11 cookie: %w", err)
+	}
+	return hex.EncodeToString(cookie), nil
+}
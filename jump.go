@@ -0,0 +1,54 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Handles gg to jump to the top and type-to-jump on letters without another binding
+// Returns false when the key should be handled as usual
+func (m *Model) updateJump(msg tea.KeyMsg) bool {
+	pendingG := m.pendingG
+	m.pendingG = false
+
+	// gg jumps to the top, G is handled by the list
+	if msg.String() == "g" {
+		if pendingG {
+			m.list.Select(0)
+		} else {
+			m.pendingG = true
+		}
+		return true
+	}
+
+	if msg.Type != tea.KeyRunes || len(msg.Runes) != 1 || !unicode.IsLetter(msg.Runes[0]) || m.isBoundKey(msg) {
+		return false
+	}
+	m.jumpToLetter(msg.Runes[0])
+	return true
+}
+
+// Reports whether the key is used by a list command or list navigation
+func (m Model) isBoundKey(msg tea.KeyMsg) bool {
+	km := m.list.KeyMap
+	return key.Matches(msg, enter, addHost, deleteHost, editHost, connectAsUser, connectWithKey, connectMarked, importPutty, duplicateHost, undoChange, copyCommand, copyFingerprint, toggleDisabled, showRecent, moveHostUp, moveHostDown,
+		cycleSort, cycleTag, toggleFolder, openSettings, browseFiles, startTunnelKey, showTunnels, showStats, showLogs, showDetail, manageKeyring, exportConfig,
+		km.CursorUp, km.CursorDown, km.PrevPage, km.NextPage, km.GoToStart, km.GoToEnd,
+		km.Filter, km.ClearFilter, km.ShowFullHelp, km.CloseFullHelp, km.Quit, km.ForceQuit)
+}
+
+// Selects the next host after the cursor whose name starts with letter, wrapping around
+func (m *Model) jumpToLetter(letter rune) {
+	items := m.list.VisibleItems()
+	prefix := strings.ToLower(string(letter))
+	for n := 1; n <= len(items); n++ {
+		i := (m.list.Index() + n) % len(items)
+		if it, ok := items[i].(Item); ok && strings.HasPrefix(strings.ToLower(it.host.Name), prefix) {
+			m.list.Select(i)
+			return
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+)
+
+var quitList = key.NewBinding(key.WithKeys("q"), key.WithHelp("q", "quit"))
+var cursorUp = key.NewBinding(key.WithKeys("up", "k"), key.WithHelp("↑/k", "up"))
+var cursorDown = key.NewBinding(key.WithKeys("down", "j"), key.WithHelp("↓/j", "down"))
+
+// Host list bindings that can be remapped in the keybindings section of config.json, by action name
+var keyActions = map[string]*key.Binding{
+	"connect":          &enter,
+	"add":              &addHost,
+	"delete":           &deleteHost,
+	"edit":             &editHost,
+	"duplicate":        &duplicateHost,
+	"copy_command":     &copyCommand,
+	"sort":             &cycleSort,
+	"tag":              &cycleTag,
+	"toggle":           &toggleFolder,
+	"settings":         &openSettings,
+	"browse":           &browseFiles,
+	"stats":            &showStats,
+	"logs":             &showLogs,
+	"info":             &showDetail,
+	"keyring":          &manageKeyring,
+	"export":           &exportConfig,
+	"start_tunnel":     &startTunnelKey,
+	"tunnels":          &showTunnels,
+	"undo":             &undoChange,
+	"connect_marked":   &connectMarked,
+	"connect_as":       &connectAsUser,
+	"connect_with_key": &connectWithKey,
+	"import_putty":     &importPutty,
+	"move_up":          &moveHostUp,
+	"move_down":        &moveHostDown,
+	"copy_fingerprint": &copyFingerprint,
+	"toggle_disabled":  &toggleDisabled,
+	"recent":           &showRecent,
+	"quit":             &quitList,
+	"up":               &cursorUp,
+	"down":             &cursorDown,
+}
+
+// Keys that always keep their meaning and can't be given to an action
+var reservedKeys = []string{"ctrl+c"}
+
+// Returns the names of the actions that can be remapped, sorted
+func keyActionNames() []string {
+	names := make([]string, 0, len(keyActions))
+	for name := range keyActions {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Replaces the keys of the named actions, leaving the others at their defaults
+// Nothing is changed unless every override is valid: the action exists, it has at least one key,
+// no reserved key is used, and no key ends up on two actions
+func applyKeyBindings(overrides map[string][]string) error {
+	if len(overrides) == 0 {
+		return nil
+	}
+
+	keys := make(map[string][]string, len(keyActions))
+	for name, b := range keyActions {
+		keys[name] = b.Keys()
+	}
+	for name, k := range overrides {
+		if _, ok := keyActions[name]; !ok {
+			return fmt.Errorf("unknown key binding action %q, expected one of %s", name, strings.Join(keyActionNames(), ", "))
+		}
+		if len(k) == 0 {
+			return fmt.Errorf("key binding for %q has no keys", name)
+		}
+		for _, reserved := range reservedKeys {
+			if slices.Contains(k, reserved) {
+				return fmt.Errorf("key binding for %q can't use %s, it is reserved", name, reserved)
+			}
+		}
+		keys[name] = k
+	}
+
+	owner := make(map[string]string)
+	for _, name := range keyActionNames() {
+		for _, k := range keys[name] {
+			if other, ok := owner[k]; ok {
+				return fmt.Errorf("key %q is bound to both %q and %q", k, other, name)
+			}
+			owner[k] = name
+		}
+	}
+
+	for name, k := range overrides {
+		b := keyActions[name]
+		b.SetKeys(k...)
+		b.SetHelp(strings.Join(k, "/"), b.Help().Desc)
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/bubbles/key"
+)
+
+// Restores every remappable binding when the test ends, since applyKeyBindings changes them in place
+func restoreKeyBindings(t *testing.T) {
+	t.Helper()
+	saved := make(map[string]key.Binding, len(keyActions))
+	for name, b := range keyActions {
+		saved[name] = *b
+	}
+	t.Cleanup(func() {
+		for name, b := range saved {
+			*keyActions[name] = b
+		}
+	})
+}
+
+func TestApplyKeyBindings(t *testing.T) {
+	tests := []struct {
+		name      string
+		overrides map[string][]string
+		wantErr   string
+		wantKeys  map[string][]string
+	}{
+		{
+			name:     "no overrides",
+			wantKeys: map[string][]string{"add": addHost.Keys()},
+		},
+		{
+			name:      "remap one action",
+			overrides: map[string][]string{"add": {"n"}},
+			wantKeys:  map[string][]string{"add": {"n"}, "delete": deleteHost.Keys()},
+		},
+		{
+			name:      "swap two actions",
+			overrides: map[string][]string{"up": {"j"}, "down": {"k"}},
+			wantKeys:  map[string][]string{"up": {"j"}, "down": {"k"}},
+		},
+		{
+			name:      "unknown action",
+			overrides: map[string][]string{"launch": {"l"}},
+			wantErr:   `unknown key binding action "launch"`,
+		},
+		{
+			name:      "no keys",
+			overrides: map[string][]string{"add": {}},
+			wantErr:   `key binding for "add" has no keys`,
+		},
+		{
+			name:      "reserved key",
+			overrides: map[string][]string{"quit": {"ctrl+c"}},
+			wantErr:   "reserved",
+		},
+		{
+			name:      "key taken by a default",
+			overrides: map[string][]string{"add": {"q"}},
+			wantErr:   `key "q" is bound to both`,
+		},
+		{
+			name:      "key given to two overrides",
+			overrides: map[string][]string{"add": {"n"}, "edit": {"n"}},
+			wantErr:   `key "n" is bound to both "add" and "edit"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			restoreKeyBindings(t)
+			before := addHost.Keys()
+
+			err := applyKeyBindings(tt.overrides)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("applyKeyBindings() error = %v, want %q", err, tt.wantErr)
+				}
+				// A rejected set of overrides changes nothing
+				if !slices.Equal(addHost.Keys(), before) {
+					t.Errorf("add keys = %q after error, want %q", addHost.Keys(), before)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("applyKeyBindings(): %v", err)
+			}
+			for name, want := range tt.wantKeys {
+				b := keyActions[name]
+				if !slices.Equal(b.Keys(), want) {
+					t.Errorf("%s keys = %q, want %q", name, b.Keys(), want)
+				}
+				if _, ok := tt.overrides[name]; ok && b.Help().Key != strings.Join(want, "/") {
+					t.Errorf("%s help key = %q, want %q", name, b.Help().Key, strings.Join(want, "/"))
+				}
+			}
+		})
+	}
+}
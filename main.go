@@ -2,21 +2,24 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
+	"slices"
 	"strings"
 	"time"
 
+	"github.com/atotto/clipboard"
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	lg "github.com/charmbracelet/lipgloss"
-	"github.com/micmonay/keybd_event"
 	"github.com/nathanlytang/rolodex/internal/logger"
 	"github.com/nathanlytang/rolodex/internal/ssh"
-	"golang.org/x/term"
 )
 
 type viewState int
@@ -25,38 +28,177 @@ const (
 	listView viewState = iota
 	formView
 	deleteConfirmView
+	hostKeyConfirmView
+	passwordPromptView
+	reconnectView
+	connectConfirmView
+	settingsView
+	statsView
+	exportView
+	tunnelsView
+	userPromptView
+	connectingView
+	keyPermView
+	detailView
+	keyringView
+	identityPromptView
+	importView
+	logsView
+	configIssuesView
 )
 
 type Model struct {
-	list              list.Model
-	hosts             []Host
-	err               error
-	showErr           bool
-	view              viewState
-	form              formModel
-	configPath        string
-	hostToDelete      *Host
-	hostToDeleteIndex int
-	width             int
-	height            int
-	connectHost       *Host
+	list               list.Model
+	hosts              []Host
+	folders            []Folder
+	collapsed          map[string]bool // Folder names that are collapsed in the list
+	filterFields       []string        // Host fields matched by the list filter
+	sortMode           sortMode
+	filterMode         filterMode
+	tagFilter          string   // Only hosts with this tag are listed when set
+	recentOnly         bool     // Only the hosts connected to most recently are listed
+	confirmTags        []string // Hosts with any of these tags need confirmation before connecting
+	confirmDelete      bool     // Ask before deleting hosts, otherwise d deletes at once and u undoes it
+	hostToConfirm      *Host
+	err                error
+	showErr            bool
+	view               viewState
+	form               formModel
+	configPath         string
+	hostToDelete       *Host
+	hostToDeleteLoc    hostLocation
+	deleteScrollOffset int                   // First line shown in the delete view when it doesn't fit
+	marked             map[hostLocation]bool // Hosts selected with space for deleting together
+	hostsToDelete      []hostLocation        // Marked hosts awaiting delete confirmation
+	undo               *configSnapshot       // Hosts before the last add, edit, or delete, nil if there's nothing to undo
+	width              int
+	height             int
+	connectHost        *Host
+	connectGroup       []Host // Marked hosts to connect to one after another in the terminal
+	// Connection made while the TUI showed a spinner, handed to main to run the shell
+	connectClient        *ssh.Client
+	connectSessionConfig ssh.SessionConfig
+	connectingHost       *Host
+	connectID            int // Bumped per attempt so a cancelled attempt's result is ignored
+	spinner              spinner.Model
+	// Set when the last connection hit an unknown host key
+	unknownHostKey     *ssh.UnknownHostKeyError
+	unknownHostKeyHost *Host
+	// Connect-time password entry for hosts with prompt_password
+	passwordPrompt  textinput.Model
+	promptHost      *Host
+	connectPassword []byte
+	// Connect-time user override for a single connection
+	userPrompt     textinput.Model
+	userPromptHost *Host
+	// Connect-time identity file override for a single connection
+	identityPrompt     textinput.Model
+	identityPromptHost *Host
+	identityCandidates []string // Path completions shown below the prompt
+	importer           importModel
+	logs               logViewerModel
+	configIssues       []error   // Problems found in config.json at startup
+	configModTime      time.Time // Modification time of config.json when last loaded
+	configIssuesOffset int
+	configWatcher      *configWatcher  // Shared by every program, so changes made during a session aren't missed
+	reloadPending      bool            // config.json changed while the list was busy, reloaded once it isn't
+	skippedHosts       map[string]bool // Hosts with config problems hidden from the list, by name
+	// Identity file readable by others, shown before connecting to keyPermHost
+	keyPermHost          *Host
+	keyPermErr           *ssh.KeyPermissionError
+	strictKeyPermissions bool // Identity files readable by others aren't used
+	// Set when the last session dropped unexpectedly
+	disconnectErr   *ssh.DisconnectError
+	disconnectHost  *Host
+	reconnect       bool             // Retry with backoff when connecting to connectHost
+	browse          bool             // Open the file browser instead of a shell on connectHost
+	pendingG        bool             // First g of gg was pressed
+	statsOffset     int              // First host row shown in the stats view
+	detailHost      *Host            // Host shown in the detail view
+	detailOffset    int              // First line shown in the detail view
+	detailPing      *detailPingState // Reachability check shown in the detail view, nil if not run
+	keyring         keyringModel
+	exportPath      textinput.Model
+	exportErr       error // Shown in the export view when writing fails
+	tunnels         *tunnelSet
+	tunnelMode      bool // Start a background tunnel instead of a shell
+	tunnelCursor    int
+	done            <-chan struct{} // Closed once the program running this model exits, ending waits it started
+	settings        settingsModel
+	defaultPort     int    // Port filled in when adding a host, 0 leaves it blank
+	defaultUser     string // User filled in when adding a host
+	defaultIdentity string // Identity file filled in when adding a host
+	mouse           bool   // Mouse support is enabled
+	compactList     bool   // One line per host instead of two
+	defaultJumpHost string
+	defaultProxy    string
+	lastClickIndex  int // List item clicked last, for detecting double-clicks
+	lastClick       time.Time
+	// Background reachability checks, disabled when the interval is 0
+	healthCheckInterval time.Duration
+	health              map[string]healthStatus // Keyed by host name
+	healthGen           int                     // Bumped when the check loop restarts
 }
 
 type Item struct {
-	host Host
+	host         Host
+	loc          hostLocation
+	filterFields []string     // Host fields matched by the list filter
+	health       healthStatus // Result of the last background reachability check
+	marked       bool         // Selected for deleting several hosts at once
+}
+
+// Non-connectable list entry that groups the hosts of a folder
+type FolderItem struct {
+	folder    Folder
+	index     int
+	collapsed bool
 }
 
 type Host struct {
-	Name               string `json:"name"`
-	Host               string `json:"host"`
-	Port               int    `json:"port"`
-	User               string `json:"user"`
-	SSHAgent           bool   `json:"ssh_agent,omitempty"`
-	IdentityFile       string `json:"identity_file,omitempty"`
-	IdentityPassphrase string `json:"identity_passphrase,omitempty"`
-	KeyringService     string `json:"keyring_service,omitempty"`
-	KeyringAccount     string `json:"keyring_account,omitempty"`
-	Password           string `json:"password,omitempty"`
+	Name               string            `json:"name"`
+	Host               string            `json:"host"`
+	Addresses          []string          `json:"addresses,omitempty"` // Fallbacks tried in order when Host can't be reached
+	Port               int               `json:"port"`
+	User               string            `json:"user"`
+	SSHAgent           bool              `json:"ssh_agent,omitempty"`
+	AgentKey           string            `json:"agent_key,omitempty"`
+	IdentityFile       string            `json:"identity_file,omitempty"`
+	IdentityPassphrase string            `json:"identity_passphrase,omitempty"`
+	CertificateFile    string            `json:"certificate_file,omitempty"` // Defaults to <identity_file>-cert.pub when that exists
+	AddKeysToAgent     bool              `json:"add_keys_to_agent,omitempty"`
+	KeyringService     string            `json:"keyring_service,omitempty"`
+	KeyringAccount     string            `json:"keyring_account,omitempty"`
+	Password           string            `json:"password,omitempty"`
+	PasswordCommand    string            `json:"password_command,omitempty"`
+	PromptPassword     bool              `json:"prompt_password,omitempty"`
+	ConnectTimeout     int               `json:"connect_timeout,omitempty"`
+	KeepAliveInterval  *int              `json:"keepalive_interval,omitempty"`
+	LocalForwards      []string          `json:"local_forwards,omitempty"`
+	RemoteForwards     []string          `json:"remote_forwards,omitempty"`
+	LastConnected      time.Time         `json:"last_connected,omitzero"`
+	ConnectCount       int               `json:"connect_count,omitempty"`
+	TotalDuration      int               `json:"total_duration,omitempty"` // Seconds connected across all sessions
+	Tags               []string          `json:"tags,omitempty"`
+	Notes              string            `json:"notes,omitempty"`
+	Confirm            bool              `json:"confirm,omitempty"`
+	InteractiveAuth    bool              `json:"interactive_auth,omitempty"`
+	SingleAuthMethod   bool              `json:"single_auth_method,omitempty"` // Offer only the highest-priority auth method
+	HostKeyAlgorithms  []string          `json:"host_key_algorithms,omitempty"`
+	Ciphers            []string          `json:"ciphers,omitempty"`
+	ForwardAgent       bool              `json:"forward_agent,omitempty"`
+	ForwardX11         bool              `json:"forward_x11,omitempty"`
+	Count              int               `json:"count,omitempty"` // Targets a %d host template expands into
+	Options            map[string]string `json:"options,omitempty"`
+	Env                map[string]string `json:"env,omitempty"`
+	AcknowledgeBanner  bool              `json:"acknowledge_banner,omitempty"`
+	Quiet              bool              `json:"quiet,omitempty"`         // Clear the MOTD once the shell is ready
+	JumpHostRef        string            `json:"jump_host_ref,omitempty"` // Name of a saved host to connect through
+	NoJumpHost         bool              `json:"no_jump_host,omitempty"`  // Connect directly even when default_jump_host is set
+	Disabled           bool              `json:"disabled,omitempty"`      // Kept in the list but can't be connected to
+	Proxy              string            `json:"proxy,omitempty"`         // socks5:// or http:// proxy URL, "none" ignores the global proxy
+
+	templateName string // Name of the template this host was expanded from, empty otherwise
 }
 
 type Folder struct {
@@ -67,9 +209,46 @@ type Folder struct {
 type Configuration struct {
 	Folders []Folder `json:"folders"`
 	Hosts   []Host   `json:"hosts"`
+	Settings
 }
 
-type resetListMsg struct{}
+// Global options, stored at the top level of config.json
+type Settings struct {
+	KnownHostsFile       string              `json:"known_hosts_file,omitempty"`
+	HostKeyPolicy        string              `json:"host_key_policy,omitempty"`
+	StrictKeyPermissions bool                `json:"strict_key_permissions,omitempty"`
+	AgentKeyLifetime     int                 `json:"agent_key_lifetime,omitempty"` // Seconds keys added to the agent are kept, 0 until the agent exits
+	FilterFields         []string            `json:"filter_fields,omitempty"`
+	ConnectTimeout       int                 `json:"connect_timeout,omitempty"`
+	MaxReconnectAttempts int                 `json:"max_reconnect_attempts,omitempty"`
+	SortMode             string              `json:"sort_mode,omitempty"`
+	FilterMode           string              `json:"filter_mode,omitempty"` // fuzzy (default) or substring
+	ConfirmTags          []string            `json:"confirm_tags,omitempty"`
+	HostKeyAlgorithms    []string            `json:"host_key_algorithms,omitempty"`
+	Ciphers              []string            `json:"ciphers,omitempty"`
+	DefaultPort          int                 `json:"default_port,omitempty"`
+	DefaultUser          string              `json:"default_user,omitempty"`          // User filled in when adding a host
+	DefaultIdentityFile  string              `json:"default_identity_file,omitempty"` // Identity file filled in when adding a host
+	LogLevel             string              `json:"log_level,omitempty"`
+	MaxLogAge            int                 `json:"max_log_age,omitempty"`
+	MaxLogFiles          int                 `json:"max_log_files,omitempty"`
+	Mouse                bool                `json:"mouse,omitempty"`
+	CompactList          bool                `json:"compact_list,omitempty"` // One line per host instead of two
+	HealthCheckInterval  int                 `json:"health_check_interval,omitempty"`
+	Theme                *Theme              `json:"theme,omitempty"`
+	KeyBindings          map[string][]string `json:"keybindings,omitempty"` // Keys for host list actions, by action name
+	AcknowledgeBanners   bool                `json:"acknowledge_banners,omitempty"`
+	LastSelected         string              `json:"last_selected,omitempty"`     // Host selected when rolodex last left the list
+	DefaultJumpHost      string              `json:"default_jump_host,omitempty"` // Saved host that hosts without jump_host_ref connect through
+	Proxy                string              `json:"proxy,omitempty"`             // socks5:// or http:// proxy URL hosts without their own connect through
+	ConfirmDelete        *bool               `json:"confirm_delete,omitempty"`    // Ask before deleting hosts, nil means true
+	ForwardEnv           []string            `json:"forward_env,omitempty"`       // Local environment variables sent to every session
+}
+
+// Reports whether deleting hosts asks first, which it does unless confirm_delete is false
+func (s Settings) confirmsDelete() bool {
+	return s.ConfirmDelete == nil || *s.ConfirmDelete
+}
 
 type errorMsg struct {
 	err error
@@ -79,41 +258,349 @@ var docStyle = lg.NewStyle().Margin(1, 2)
 var enter = key.NewBinding(key.WithKeys("enter"), key.WithHelp("⏎", "connect"))
 var addHost = key.NewBinding(key.WithKeys("a"), key.WithHelp("a", "add host"))
 var deleteHost = key.NewBinding(key.WithKeys("d"), key.WithHelp("d", "delete host"))
+var editHost = key.NewBinding(key.WithKeys("e"), key.WithHelp("e", "edit host"))
+var duplicateHost = key.NewBinding(key.WithKeys("c"), key.WithHelp("c", "duplicate host"))
+var copyCommand = key.NewBinding(key.WithKeys("y"), key.WithHelp("y", "copy ssh command"))
+var cycleSort = key.NewBinding(key.WithKeys("s"), key.WithHelp("s", "cycle sort"))
+var cycleTag = key.NewBinding(key.WithKeys("t"), key.WithHelp("t", "filter by tag"))
+var toggleFolder = key.NewBinding(key.WithKeys(" "), key.WithHelp("space", "expand folder/mark host"))
+var openSettings = key.NewBinding(key.WithKeys(","), key.WithHelp(",", "settings"))
+var browseFiles = key.NewBinding(key.WithKeys("f"), key.WithHelp("f", "browse files"))
+var showStats = key.NewBinding(key.WithKeys("S"), key.WithHelp("S", "connection stats"))
+var manageKeyring = key.NewBinding(key.WithKeys("K"), key.WithHelp("K", "manage keyring password"))
+var showDetail = key.NewBinding(key.WithKeys("i"), key.WithHelp("i", "host info"))
+var exportConfig = key.NewBinding(key.WithKeys("x"), key.WithHelp("x", "export ssh_config"))
+var startTunnelKey = key.NewBinding(key.WithKeys("p"), key.WithHelp("p", "start tunnel"))
+var showTunnels = key.NewBinding(key.WithKeys("o"), key.WithHelp("o", "tunnels"))
+var undoChange = key.NewBinding(key.WithKeys("u"), key.WithHelp("u", "undo last change"))
+var connectMarked = key.NewBinding(key.WithKeys("C"), key.WithHelp("C", "connect to marked hosts in turn"))
+var connectWithKey = key.NewBinding(key.WithKeys("ctrl+k"), key.WithHelp("ctrl+k", "connect with another key"))
+var moveHostUp = key.NewBinding(key.WithKeys("shift+up"), key.WithHelp("shift+↑", "move host up"))
+var moveHostDown = key.NewBinding(key.WithKeys("shift+down"), key.WithHelp("shift+↓", "move host down"))
+var importPutty = key.NewBinding(key.WithKeys("I"), key.WithHelp("I", "import PuTTY sessions"))
+var showLogs = key.NewBinding(key.WithKeys("L"), key.WithHelp("L", "view logs"))
+var connectAsUser = key.NewBinding(key.WithKeys("U"), key.WithHelp("U", "connect as another user"))
+var toggleDisabled = key.NewBinding(key.WithKeys("ctrl+d"), key.WithHelp("ctrl+d", "disable/enable host"))
+var showRecent = key.NewBinding(key.WithKeys("r"), key.WithHelp("r", "recent hosts"))
+var copyFingerprint = key.NewBinding(key.WithKeys("ctrl+f"), key.WithHelp("ctrl+f", "copy host key fingerprint"))
+
+func (i Item) Title() string {
+	return i.indent() + i.markPrefix() + i.host.Name + i.healthMarker() + i.disabledMarker()
+}
+func (i Item) Description() string {
+	desc := i.indent() + i.host.Host
+	if tags := i.tagsText(); tags != "" {
+		desc += " · " + tags
+	}
+	if i.host.Notes != "" {
+		desc += " · " + truncateNote(i.host.Notes, 30)
+	}
+	return desc + i.lastConnected()
+}
+func (i Item) FilterValue() string { return i.filterValue() }
+
+// Returns the host's tags formatted for display, e.g. "#prod #web"
+func (i Item) tagsText() string {
+	tags := make([]string, len(i.host.Tags))
+	for t, tag := range i.host.Tags {
+		tags[t] = "#" + tag
+	}
+	return strings.Join(tags, " ")
+}
+
+// Returns the first line of a note, cut to at most n characters with an ellipsis
+func truncateNote(note string, n int) string {
+	line, _, more := strings.Cut(note, "\n")
+	runes := []rune(strings.TrimSpace(line))
+	if len(runes) > n {
+		return string(runes[:n-1]) + "…"
+	}
+	if more {
+		return string(runes) + "…"
+	}
+	return string(runes)
+}
+
+// Returns when the host was last connected to, for the description line
+func (i Item) lastConnected() string {
+	if i.host.LastConnected.IsZero() {
+		return ""
+	}
+	return " · " + relativeTime(i.host.LastConnected)
+}
 
-func (i Item) Title() string       { return i.host.Name }
-func (i Item) Description() string { return i.host.Host }
-func (i Item) FilterValue() string { return i.host.Name }
+// Formats a past time relative to now, e.g. "2h ago"
+func relativeTime(t time.Time) string {
+	d := time.Since(t)
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(d.Hours()))
+	case d < 30*24*time.Hour:
+		return fmt.Sprintf("%dd ago", int(d.Hours()/24))
+	}
+	return t.Format("2006-01-02")
+}
+
+// Returns the prefix used to nest hosts under their folder header
+func (i Item) indent() string {
+	if i.loc.folder >= 0 {
+		return "  "
+	}
+	return ""
+}
+
+func (f FolderItem) Title() string {
+	if f.collapsed {
+		return "▸ " + f.folder.Name
+	}
+	return "▾ " + f.folder.Name
+}
+
+func (f FolderItem) Description() string {
+	if len(f.folder.Hosts) == 1 {
+		return "1 host"
+	}
+	return fmt.Sprintf("%d hosts", len(f.folder.Hosts))
+}
 
-func buildList(hosts []Host) list.Model {
+func (f FolderItem) FilterValue() string { return f.folder.Name }
+
+// Flattens folders and top-level hosts into list items
+// Folders come first, each followed by its hosts unless collapsed
+func (m Model) buildItems() []list.Item {
+	if m.recentOnly {
+		return m.recentItems()
+	}
 	items := []list.Item{}
-	for _, h := range hosts {
-		it := Item{host: h}
-		items = append(items, it)
+	for _, fi := range m.sortMode.folderOrder(m.folders) {
+		f := m.folders[fi]
+		hostItems := m.hostItems(f.Hosts, fi)
+		if m.tagFilter != "" && len(hostItems) == 0 {
+			continue // Hide folders with no hosts carrying the tag
+		}
+		isCollapsed := m.collapsed[f.Name]
+		items = append(items, FolderItem{folder: f, index: fi, collapsed: isCollapsed})
+		if isCollapsed {
+			continue
+		}
+		items = append(items, hostItems...)
+	}
+	return append(items, m.hostItems(m.hosts, -1)...)
+}
+
+// Creates sorted list items for the hosts of one folder (-1 for top-level hosts)
+func (m Model) hostItems(hosts []Host, folder int) []list.Item {
+	var hostItems []Item
+	for hi, template := range hosts {
+		if m.tagFilter != "" && !slices.Contains(template.Tags, m.tagFilter) {
+			continue
+		}
+		if m.skippedHosts[template.Name] {
+			continue
+		}
+		// Templated hosts share the template's location so edits apply to all of them
+		for _, h := range expandHost(template) {
+			loc := hostLocation{folder: folder, index: hi}
+			hostItems = append(hostItems, Item{host: h, loc: loc, filterFields: m.filterFields, health: m.health[h.Name], marked: m.marked[loc]})
+		}
 	}
-	hostList := list.New(items, list.NewDefaultDelegate(), 0, 0)
-	hostList.Title = "Rolodex"
+	m.sortMode.sortItems(hostItems)
+
+	items := make([]list.Item, len(hostItems))
+	for i, it := range hostItems {
+		items[i] = it
+	}
+	return items
+}
+
+// Creates the host list laid out for the last known window size
+func (m Model) buildList() list.Model {
+	hostList := list.New(m.buildItems(), m.listDelegate(), 0, 0)
+	setListSize(&hostList, m.width, m.height)
+	applyListTheme(&hostList)
+	hostList.Title = m.listTitle()
+	hostList.StatusMessageLifetime = 3 * time.Second
+	hostList.Filter = m.filterMode.filterFunc()
+	hostList.KeyMap.GoToStart.SetHelp("gg/home", "go to start")
+	hostList.KeyMap.PrevPage.SetKeys("left", "h", "pgup", "b") // u is undo
+	hostList.KeyMap.CursorUp = cursorUp
+	hostList.KeyMap.CursorDown = cursorDown
+	hostList.KeyMap.Quit = key.NewBinding(key.WithKeys(append(quitList.Keys(), "esc")...), key.WithHelp(quitList.Help().Key, "quit"))
 	hostList.AdditionalShortHelpKeys = func() []key.Binding {
-		return []key.Binding{enter, addHost, deleteHost}
+		return []key.Binding{enter, addHost, editHost, deleteHost}
+	}
+	hostList.AdditionalFullHelpKeys = func() []key.Binding {
+		return []key.Binding{showDetail, manageKeyring, connectAsUser, connectWithKey, connectMarked, duplicateHost, undoChange, copyCommand, copyFingerprint, moveHostUp, moveHostDown, cycleSort, cycleTag, toggleFolder, openSettings, browseFiles, startTunnelKey, showTunnels, showStats, showLogs, exportConfig, importPutty, toggleDisabled, showRecent}
 	}
 	return hostList
 }
 
-func initialModel(hosts []Host, configPath string) Model {
-	return Model{
-		list:       buildList(hosts),
-		hosts:      hosts,
-		view:       listView,
-		configPath: configPath,
+// Returns the delegate for the list layout chosen in settings
+func (m Model) listDelegate() list.ItemDelegate {
+	if m.compactList {
+		return newCompactDelegate()
+	}
+	return newHostDelegate()
+}
+
+// Fits the list inside the document margins of a window, ignoring sizes that aren't known yet
+func setListSize(l *list.Model, width, height int) {
+	h, v := docStyle.GetFrameSize()
+	l.SetSize(max(width-h, 0), max(height-v, 0))
+}
+
+// Moves the list cursor to the host at the given config location
+func selectHost(l *list.Model, loc hostLocation) {
+	for i, item := range l.Items() {
+		if it, ok := item.(Item); ok && it.loc == loc {
+			l.Select(i)
+			return
+		}
+	}
+}
+
+// Moves the list cursor to the named host, leaving it where it is if the host is gone
+func selectHostNamed(l *list.Model, name string) {
+	if name == "" {
+		return
+	}
+	for i, item := range l.Items() {
+		if it, ok := item.(Item); ok && it.host.Name == name {
+			l.Select(i)
+			return
+		}
+	}
+}
+
+// Parts of the host list kept when the TUI restarts after a session
+// The sort mode is saved to config.json, so it's kept already
+type listState struct {
+	filter    string          // Text typed after /, empty if the list isn't filtered
+	tagFilter string          // Tag cycled to with t
+	recent    bool            // Only recent hosts are shown, toggled with r
+	collapsed map[string]bool // Folder names that are collapsed
+	selected  string          // Name of the selected host
+}
+
+// Captures the host list's filters, collapsed folders and selection
+func (m Model) listState() listState {
+	s := listState{tagFilter: m.tagFilter, recent: m.recentOnly, collapsed: m.collapsed}
+	if m.list.FilterState() != list.Unfiltered {
+		s.filter = m.list.FilterValue()
 	}
+	if it, ok := m.list.SelectedItem().(Item); ok {
+		s.selected = it.host.Name
+	}
+	return s
+}
+
+// Reapplies a listState to a freshly built model, rebuilding its items
+// A selected host that's gone or no longer matches leaves the cursor where it was
+func (m *Model) restoreListState(s listState) {
+	m.tagFilter = s.tagFilter
+	m.recentOnly = s.recent
+	if s.collapsed != nil {
+		m.collapsed = s.collapsed
+	}
+	m.list.SetItems(m.buildItems())
+	if s.filter != "" {
+		m.list.SetFilterText(s.filter)
+	}
+	if s.selected == "" {
+		return
+	}
+	for i, item := range m.list.VisibleItems() {
+		if it, ok := item.(Item); ok && it.host.Name == s.selected {
+			m.list.Select(i)
+			return
+		}
+	}
+}
+
+// Moves the list cursor to the header of the given folder
+func selectFolder(l *list.Model, folder int) {
+	for i, item := range l.Items() {
+		if f, ok := item.(FolderItem); ok && f.index == folder {
+			l.Select(i)
+			return
+		}
+	}
+}
+
+// Returns the name of a folder by index, or "" for top-level hosts
+func (m Model) folderName(folder int) string {
+	if folder < 0 || folder >= len(m.folders) {
+		return ""
+	}
+	return m.folders[folder].Name
+}
+
+// Returns the host as saved in the config at loc, before any template expansion
+func (m Model) hostAt(loc hostLocation) Host {
+	if loc.folder >= 0 {
+		return m.folders[loc.folder].Hosts[loc.index]
+	}
+	return m.hosts[loc.index]
+}
+
+// Expands or collapses a folder and keeps its header selected
+func (m Model) toggleFolderCollapsed(folder int) (tea.Model, tea.Cmd) {
+	name := m.folderName(folder)
+	m.collapsed[name] = !m.collapsed[name]
+	cmd := m.list.SetItems(m.buildItems())
+	selectFolder(&m.list, folder)
+	return m, cmd
+}
+
+func initialModel(configuration *Configuration, configPath string) Model {
+	theme = loadTheme(configuration.Theme)
+	m := Model{
+		hosts:                configuration.Hosts,
+		folders:              configuration.Folders,
+		collapsed:            make(map[string]bool),
+		filterFields:         configuration.FilterFields,
+		sortMode:             parseSortMode(configuration.SortMode),
+		filterMode:           parseFilterMode(configuration.FilterMode),
+		confirmTags:          configuration.ConfirmTags,
+		confirmDelete:        configuration.confirmsDelete(),
+		defaultPort:          configuration.DefaultPort,
+		defaultUser:          configuration.DefaultUser,
+		defaultIdentity:      configuration.DefaultIdentityFile,
+		mouse:                configuration.Mouse,
+		compactList:          configuration.CompactList,
+		defaultJumpHost:      configuration.DefaultJumpHost,
+		defaultProxy:         configuration.Proxy,
+		strictKeyPermissions: configuration.StrictKeyPermissions,
+		healthCheckInterval:  time.Duration(configuration.HealthCheckInterval) * time.Second,
+		view:                 listView,
+		configPath:           configPath,
+		configModTime:        configModTime(configPath),
+	}
+	if len(m.filterFields) == 0 {
+		m.filterFields = defaultFilterFields
+	}
+	m.list = m.buildList()
+	selectHostNamed(&m.list, configuration.LastSelected)
+	return m
 }
 
 func (m Model) Init() tea.Cmd {
-	return nil
+	cmds := []tea.Cmd{waitForConfigChange(m.configWatcher, m.done)}
+	if m.healthCheckInterval > 0 {
+		cmds = append(cmds, checkHealth(m.healthGen, expandHosts(m.allHosts())))
+	}
+	// Watch tunnels started before the TUI was last restarted
+	for _, at := range m.tunnels.list() {
+		cmds = append(cmds, waitForTunnel(at, m.done))
+	}
+	return tea.Batch(cmds...)
 }
 
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
-	kb, _ := keybd_event.NewKeyBonding()
-	kb.SetKeys(keybd_event.VK_SPACE)
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
 		// Global quit
@@ -127,33 +614,103 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m.updateForm(msg)
 		case deleteConfirmView:
 			return m.updateDeleteConfirm(msg)
+		case hostKeyConfirmView:
+			return m.updateHostKeyConfirm(msg)
+		case passwordPromptView:
+			return m.updatePasswordPrompt(msg)
+		case reconnectView:
+			return m.updateReconnect(msg)
+		case connectConfirmView:
+			return m.updateConnectConfirm(msg)
+		case userPromptView:
+			return m.updateUserPrompt(msg)
+		case connectingView:
+			return m.updateConnecting(msg)
+		case keyPermView:
+			return m.updateKeyPerm(msg)
+		case detailView:
+			return m.updateDetail(msg)
+		case keyringView:
+			return m.updateKeyring(msg)
+		case identityPromptView:
+			return m.updateIdentityPrompt(msg)
+		case importView:
+			return m.updateImport(msg)
+		case logsView:
+			return m.updateLogs(msg)
+		case configIssuesView:
+			return m.updateConfigIssues(msg)
+		case settingsView:
+			return m.updateSettings(msg)
+		case statsView:
+			return m.updateStats(msg)
+		case exportView:
+			return m.updateExport(msg)
+		case tunnelsView:
+			return m.updateTunnels(msg)
 		}
 		return m.updateList(msg)
 
+	case tea.MouseMsg:
+		if m.mouse {
+			return m.updateMouse(msg)
+		}
+		return m, nil
+
+	case probeResultMsg:
+		return m.finishProbe(msg)
+
+	case keyInfoTickMsg:
+		return m.inspectIdentityFile(msg)
+
+	case keyInfoMsg:
+		return m.finishKeyInfo(msg)
+
+	case configChangedMsg:
+		return m.configChanged(msg)
+
+	case detailPingMsg:
+		return m.finishDetailPing(msg)
+
+	case healthCheckMsg:
+		return m.finishHealthCheck(msg)
+
+	case healthTickMsg:
+		return m.nextHealthCheck(msg)
+
+	case tunnelStartedMsg:
+		return m.finishTunnelStart(msg)
+
+	case tunnelStoppedMsg:
+		return m.finishTunnelStop(msg)
+
+	case fingerprintMsg:
+		return m.finishFingerprint(msg)
+
+	case connectedMsg:
+		return m.finishConnect(msg)
+
+	case spinner.TickMsg:
+		if m.view != connectingView {
+			return m, nil
+		}
+		var cmd tea.Cmd
+		m.spinner, cmd = m.spinner.Update(msg)
+		return m, cmd
+
 	case errorMsg:
 		m.err = msg.err
 		m.showErr = true
 		m.view = listView
 		return m, nil
 
-	case resetListMsg:
-		return m, func() tea.Msg {
-			w, h, _ := term.GetSize(int(os.Stdout.Fd()))
-			return tea.WindowSizeMsg{Width: w, Height: h}
-		}
-
 	case tea.WindowSizeMsg:
-		logger.Printf("Window size: %d x %d", msg.Width, msg.Height)
-		h, v := docStyle.GetFrameSize()
-		m.list.SetSize(msg.Width-h, msg.Height-v)
+		// Resizing only recomputes the layout, views render from the stored size
+		logger.Debugf("Window size: %d x %d", msg.Width, msg.Height)
 		m.width = msg.Width
 		m.height = msg.Height
-
-		// HACK: Keyboard event so that arrow keys work immediately
-		// TODO: Figure out why an extra initial key press is needed
-		kb.Press()
-		time.Sleep(10 * time.Millisecond)
-		kb.Release()
+		setListSize(&m.list, m.width, m.height)
+		return m, nil
 	}
 
 	// Pass other messages to the list if in list view
@@ -168,35 +725,229 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 func (m Model) updateList(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	// If showing error, any key dismisses it (except quit)
 	if m.showErr {
-		if msg.String() == "q" {
+		if key.Matches(msg, quitList) {
 			return Quit(m)
 		}
 
 		m.showErr = false
 		m.err = nil
+		if key.Matches(msg, showLogs) {
+			return m.openLogs(listView)
+		}
 		return m, nil
 	}
 
-	if msg.String() == "q" {
+	if key.Matches(msg, quitList) {
 		return Quit(m)
 	}
 
 	// Only key commands when NOT in filtering mode
 	if !m.list.SettingFilter() {
+		// Handle gg and type-to-jump
+		if m.updateJump(msg) {
+			return m, nil
+		}
+
 		// Handle 'a' key to add new host
 		if key.Matches(msg, addHost) {
 			m.view = formView
-			m.form = newFormModel()
-			return m, textinput.Blink
+			m.form = newAddFormModel(m.defaultUser, m.defaultPort, m.defaultIdentity)
+
+			// Default to the folder of the selected item
+			switch it := m.list.SelectedItem().(type) {
+			case Item:
+				m.form.inputs[folderInput].SetValue(m.folderName(it.loc.folder))
+			case FolderItem:
+				m.form.inputs[folderInput].SetValue(it.folder.Name)
+			}
+			if m.defaultIdentity == "" {
+				return m, textinput.Blink
+			}
+			keyInfoCmd := m.form.identityFileChanged()
+			return m, tea.Batch(textinput.Blink, keyInfoCmd)
+		}
+
+		// Handle ',' key to open the settings view
+		if key.Matches(msg, openSettings) {
+			return m.openSettings()
+		}
+
+		// Handle 'o' key to show running tunnels
+		if key.Matches(msg, showTunnels) {
+			m.tunnelCursor = 0
+			m.view = tunnelsView
+			return m, nil
+		}
+
+		// Handle 'x' key to export hosts to an ssh_config file
+		if key.Matches(msg, exportConfig) {
+			return m.openExport()
+		}
+
+		// Handle 'i' key to show the selected host's details
+		if key.Matches(msg, showDetail) {
+			if it, ok := m.list.SelectedItem().(Item); ok {
+				return m.openDetail(it.host)
+			}
+		}
+
+		// Handle 'K' key to manage the selected host's keyring password
+		if key.Matches(msg, manageKeyring) {
+			if it, ok := m.list.SelectedItem().(Item); ok {
+				return m.openKeyring(it.host)
+			}
+		}
+
+		// Handle 'L' key to show the log file
+		if key.Matches(msg, showLogs) {
+			return m.openLogs(listView)
+		}
+
+		// Handle 'S' key to show connection stats
+		if key.Matches(msg, showStats) {
+			m.view = statsView
+			return m, nil
+		}
+
+		// Handle shift+up/down to move the selected host
+		if key.Matches(msg, moveHostUp) {
+			return m.moveSelectedHost(-1)
+		}
+		if key.Matches(msg, moveHostDown) {
+			return m.moveSelectedHost(1)
+		}
+
+		// Handle 's' key to cycle the sort order
+		if key.Matches(msg, cycleSort) {
+			return m.cycleSortMode()
+		}
+
+		// Handle 't' key to cycle the tag filter
+		if key.Matches(msg, cycleTag) {
+			return m.cycleTagFilter()
+		}
+
+		// Handle 'r' key to show only the hosts connected to most recently
+		if key.Matches(msg, showRecent) {
+			return m.toggleRecentHosts()
+		}
+
+		// Handle space to expand/collapse the selected folder or mark the selected host
+		if key.Matches(msg, toggleFolder) {
+			switch it := m.list.SelectedItem().(type) {
+			case FolderItem:
+				return m.toggleFolderCollapsed(it.index)
+			case Item:
+				return m.toggleMark(it)
+			}
+		}
+
+		// Handle 'e' key to edit the selected host
+		if key.Matches(msg, editHost) {
+			selected := m.list.SelectedItem()
+			if selected != nil {
+				if it, ok := selected.(Item); ok {
+					m.view = formView
+					m.form = newEditFormModel(m.hostAt(it.loc), it.loc, m.folderName(it.loc.folder))
+					keyInfoCmd := m.form.identityFileChanged()
+					return m, tea.Batch(textinput.Blink, keyInfoCmd)
+				}
+			}
+		}
+
+		// Handle 'c' key to duplicate the selected host
+		if key.Matches(msg, duplicateHost) {
+			if it, ok := m.list.SelectedItem().(Item); ok {
+				m.view = formView
+				m.form = newDuplicateFormModel(m.hostAt(it.loc), m.folderName(it.loc.folder), hostNames(m.hosts, m.folders))
+				keyInfoCmd := m.form.identityFileChanged()
+				return m, tea.Batch(textinput.Blink, keyInfoCmd)
+			}
+		}
+
+		// Handle 'ctrl+d' to disable or enable the selected host
+		if key.Matches(msg, toggleDisabled) {
+			return m.toggleHostDisabled()
+		}
+
+		// Handle 'y' key to copy the equivalent ssh command
+		if key.Matches(msg, copyCommand) {
+			if it, ok := m.list.SelectedItem().(Item); ok {
+				config, err := readConfig(m.configPath)
+				if err != nil && !errors.Is(err, os.ErrNotExist) {
+					return m, m.list.NewStatusMessage("Failed to read config: " + err.Error())
+				}
+				command, err := sshCommand(it.host, &config)
+				if err != nil {
+					return m, m.list.NewStatusMessage(err.Error())
+				}
+				if err := clipboard.WriteAll(command); err != nil {
+					logger.Errorf("Failed to copy to clipboard: %v", err)
+					return m, m.list.NewStatusMessage("Clipboard unavailable: " + err.Error())
+				}
+				return m, m.list.NewStatusMessage("Copied: " + command)
+			}
+		}
+
+		// Handle 'U' key to connect to the selected host as another user
+		if key.Matches(msg, connectAsUser) {
+			if it, ok := m.list.SelectedItem().(Item); ok {
+				return m.openUserPrompt(it.host)
+			}
+		}
+
+		// Handle 'C' key to connect to each marked host in turn
+		if key.Matches(msg, connectMarked) {
+			if len(m.marked) == 0 {
+				return m, m.list.NewStatusMessage("Mark hosts with space first")
+			}
+			return m.connectMarkedHosts()
+		}
+
+		// Handle 'I' key to import PuTTY sessions
+		if key.Matches(msg, importPutty) {
+			return m.openImport()
+		}
+
+		// Handle ctrl+k to connect to the selected host with a one-off identity file
+		if key.Matches(msg, connectWithKey) {
+			if it, ok := m.list.SelectedItem().(Item); ok {
+				return m.openIdentityPrompt(it.host)
+			}
+		}
+
+		// Handle 'u' key to undo the last add, edit, or delete
+		if key.Matches(msg, undoChange) {
+			return m.undoLastChange()
 		}
 
-		// Handle 'd' key to delete host
+		// Handle ctrl+f to copy the selected host's key fingerprint
+		if key.Matches(msg, copyFingerprint) {
+			if it, ok := m.list.SelectedItem().(Item); ok {
+				h := it.host
+				return m, tea.Batch(
+					fetchFingerprint(&h, m.configPath),
+					m.list.NewStatusMessage("Fetching host key for "+h.Name+"..."),
+				)
+			}
+		}
+
+		// Handle 'd' key to delete the marked hosts, or the selected host if none are marked
+		if key.Matches(msg, deleteHost) && !m.confirmDelete {
+			return m.deleteWithoutConfirm()
+		}
 		if key.Matches(msg, deleteHost) {
+			if len(m.marked) > 0 {
+				m.hostsToDelete = m.markedLocations()
+				m.view = deleteConfirmView
+				return m, nil
+			}
 			selected := m.list.SelectedItem()
 			if selected != nil {
 				if it, ok := selected.(Item); ok {
-					m.hostToDelete = &it.host
-					m.hostToDeleteIndex = m.list.Index()
+					h := m.hostAt(it.loc)
+					m.hostToDelete = &h
+					m.hostToDeleteLoc = it.loc
 					m.view = deleteConfirmView
 					return m, nil
 				}
@@ -204,14 +955,35 @@ func (m Model) updateList(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		}
 	}
 
-	// Handle enter to connect
+	// Handle 'f' key to browse the selected host's files over SFTP,
+	// or 'p' to run its port forwards in the background
+	if !m.list.SettingFilter() && key.Matches(msg, browseFiles, startTunnelKey) {
+		if it, ok := m.list.SelectedItem().(Item); ok {
+			m.browse = key.Matches(msg, browseFiles)
+			m.tunnelMode = key.Matches(msg, startTunnelKey)
+			if m.needsConnectConfirm(it.host) {
+				m.hostToConfirm = &it.host
+				m.view = connectConfirmView
+				return m, nil
+			}
+			return m.connect(&it.host)
+		}
+	}
+
+	// Handle enter to connect, or expand/collapse a folder header
 	if key.Matches(msg, enter) {
-		selected := m.list.SelectedItem()
-		if selected != nil {
-			if it, ok := selected.(Item); ok {
-				m.connectHost = &it.host
-				return Quit(m)
+		switch it := m.list.SelectedItem().(type) {
+		case Item:
+			m.browse = false
+			m.tunnelMode = false
+			if m.needsConnectConfirm(it.host) {
+				m.hostToConfirm = &it.host
+				m.view = connectConfirmView
+				return m, nil
 			}
+			return m.connect(&it.host)
+		case FolderItem:
+			return m.toggleFolderCollapsed(it.index)
 		}
 	}
 
@@ -225,21 +997,26 @@ func (m Model) View() string {
 	if m.showErr && m.err != nil {
 		errorStyle := lg.NewStyle().
 			Bold(true).
-			Foreground(lg.Color("#EE0000")).
+			Foreground(theme.Alert).
 			Padding(1, 2)
 
 		headerStyle := lg.NewStyle().
 			Bold(true).
-			Foreground(lg.Color("#FFFF00")).
+			Foreground(theme.Warning).
 			Padding(0, 2)
 
 		footerStyle := lg.NewStyle().
-			Foreground(lg.Color("#888888")).
+			Foreground(theme.Muted).
 			Padding(1, 2)
 
-		header := headerStyle.Render("⚠  Connection Error")
-		errMsg := errorStyle.Render(m.err.Error() + "\n\nCheck the logs for more details.")
-		footer := footerStyle.Render("Press 'q' to quit or any other key to return to the list.")
+		kind := ssh.ClassifyError(m.err)
+		header := headerStyle.Render("⚠  " + kind.String())
+		msg := m.err.Error()
+		if advice := kind.Advice(); advice != "" {
+			msg += "\n\n" + advice + "."
+		}
+		errMsg := errorStyle.Render(msg + "\n\nCheck the logs in " + logger.Dir() + " for more details.")
+		footer := footerStyle.Render("Press '" + showLogs.Help().Key + "' to view the logs, 'q' to quit or any other key to return to the list.")
 
 		return docStyle.Render(header + "\n" + errMsg + "\n" + footer)
 	}
@@ -252,21 +1029,125 @@ func (m Model) View() string {
 		return m.renderDeleteConfirm()
 	}
 
+	if m.view == hostKeyConfirmView {
+		return m.renderHostKeyConfirm()
+	}
+
+	if m.view == passwordPromptView {
+		return m.renderPasswordPrompt()
+	}
+
+	if m.view == reconnectView {
+		return m.renderReconnect()
+	}
+
+	if m.view == settingsView {
+		return m.renderSettings()
+	}
+
+	if m.view == statsView {
+		return m.renderStats()
+	}
+
+	if m.view == exportView {
+		return m.renderExport()
+	}
+
+	if m.view == tunnelsView {
+		return m.renderTunnels()
+	}
+
+	if m.view == connectConfirmView {
+		return m.renderConnectConfirm()
+	}
+
+	if m.view == userPromptView {
+		return m.renderUserPrompt()
+	}
+
+	if m.view == connectingView {
+		return m.renderConnecting()
+	}
+
+	if m.view == keyPermView {
+		return m.renderKeyPerm()
+	}
+
+	if m.view == detailView {
+		return m.renderDetail()
+	}
+
+	if m.view == keyringView {
+		return m.renderKeyring()
+	}
+
+	if m.view == identityPromptView {
+		return m.renderIdentityPrompt()
+	}
+
+	if m.view == importView {
+		return m.renderImport()
+	}
+
+	if m.view == logsView {
+		return m.renderLogs()
+	}
+
+	if m.view == configIssuesView {
+		return m.renderConfigIssues()
+	}
+
+	if len(m.hosts) == 0 && len(m.folders) == 0 {
+		return m.renderEmptyList()
+	}
+
 	return docStyle.Render(m.list.View())
 }
 
+// Renders the onboarding message shown when there are no hosts yet
+func (m Model) renderEmptyList() string {
+	messageStyle := lg.NewStyle().
+		Foreground(theme.Text).
+		Padding(1, 2)
+
+	hintStyle := lg.NewStyle().
+		Foreground(theme.Muted).
+		Padding(0, 2)
+
+	title := m.list.Styles.TitleBar.Render(m.list.Styles.Title.Render(m.list.Title))
+	message := messageStyle.Render("No hosts yet, press 'a' to add one.")
+	hint := hintStyle.Render("Hosts are saved to " + m.configPath)
+	help := m.list.Styles.HelpStyle.Render(m.list.Help.View(m.list))
+
+	return docStyle.Render(lg.JoinVertical(lg.Left, title, message, hint, help))
+}
+
 func Quit(m Model) (tea.Model, tea.Cmd) {
 	return m, tea.Quit
 }
 
+// Applies the configured log level, keeping the default if it isn't recognised
+func setLogLevel(name string) {
+	level, err := logger.ParseLevel(name)
+	if err != nil {
+		logger.Errorf("%v", err)
+	}
+	logger.SetLevel(level)
+}
+
 func clearScreen() {
 	fmt.Print("\033[H\033[2J")
 }
 
-// Returns the directory containing the config file
-// If running via 'go run', uses current working directory
-// Otherwise, uses the directory containing the executable
-func getConfigDir() (string, error) {
+// Returns the path of the config file
+// ROLODEX_CONFIG overrides the location, and 'go run' uses the current working directory
+// Otherwise, uses rolodex/config.json in the user config directory, copying over a
+// config.json left beside the executable by older versions
+func getConfigPath() (string, error) {
+	if path := os.Getenv("ROLODEX_CONFIG"); path != "" {
+		return path, nil
+	}
+
 	exePath, err := os.Executable()
 	if err != nil {
 		return "", err
@@ -279,10 +1160,197 @@ func getConfigDir() (string, error) {
 		if err != nil {
 			return "", err
 		}
-		return cwd, nil
+		return filepath.Join(cwd, "config.json"), nil
 	}
 
-	return exeDir, nil
+	configDir, err := userConfigDir()
+	if err != nil {
+		return "", err
+	}
+	configDir = filepath.Join(configDir, "rolodex")
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	configPath := filepath.Join(configDir, "config.json")
+	if err := migrateConfig(filepath.Join(exeDir, "config.json"), configPath); err != nil {
+		return "", err
+	}
+	return configPath, nil
+}
+
+// Returns $XDG_CONFIG_HOME (~/.config if unset), or %APPDATA% on Windows
+func userConfigDir() (string, error) {
+	if runtime.GOOS == "windows" {
+		return os.UserConfigDir()
+	}
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return dir, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".config"), nil
+}
+
+// Copies the config beside the executable to the new location if there isn't one there yet
+// The old file is left in place
+func migrateConfig(oldPath, newPath string) error {
+	if _, err := os.Stat(newPath); !errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	data, err := os.ReadFile(oldPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to read old config for migration: %w", err)
+	}
+
+	if err := os.WriteFile(newPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to migrate config: %w", err)
+	}
+	logger.Printf("Copied config from %s to %s", oldPath, newPath)
+	return nil
+}
+
+// Builds the SSH options for connecting to h, applying global settings the host doesn't override
+// A non-nil password (entered at connect time) replaces the stored one
+// Returns an error if h's jump host can't be resolved
+func connectionConfig(h *Host, configuration *Configuration, password []byte) (ssh.AuthConfig, ssh.SessionConfig, error) {
+	authConfig := hostAuthConfig(h, configuration)
+	if password != nil {
+		authConfig.Password = string(password)
+	}
+
+	jumpHosts, err := resolveJumpHosts(h, configuration)
+	if err != nil {
+		return authConfig, ssh.SessionConfig{}, err
+	}
+
+	sessionConfig := ssh.SessionConfig{
+		KeepAliveInterval: ssh.DefaultKeepAliveInterval,
+		FallbackHosts:     h.Addresses,
+		LocalForwards:     h.LocalForwards,
+		RemoteForwards:    h.RemoteForwards,
+		HostKeyAlgorithms: configuration.HostKeyAlgorithms,
+		Ciphers:           configuration.Ciphers,
+		ForwardAgent:      h.ForwardAgent,
+		ForwardX11:        h.ForwardX11,
+		Options:           h.Options,
+		Env:               h.Env,
+		OnBanner:          bannerHandler(h, configuration),
+		JumpHosts:         jumpHosts,
+		Proxy:             h.proxyURL(configuration.Proxy),
+		Quiet:             h.Quiet,
+		ForwardEnv:        configuration.ForwardEnv,
+	}
+	if len(h.HostKeyAlgorithms) > 0 {
+		sessionConfig.HostKeyAlgorithms = h.HostKeyAlgorithms
+	}
+	if len(h.Ciphers) > 0 {
+		sessionConfig.Ciphers = h.Ciphers
+	}
+	connectTimeout := configuration.ConnectTimeout
+	if h.ConnectTimeout != 0 {
+		connectTimeout = h.ConnectTimeout
+	}
+	sessionConfig.ConnectTimeout = time.Duration(connectTimeout) * time.Second
+	if h.KeepAliveInterval != nil {
+		sessionConfig.KeepAliveInterval = time.Duration(*h.KeepAliveInterval) * time.Second
+	}
+	return authConfig, sessionConfig, nil
+}
+
+// Builds the authentication options for logging in to h with its stored credentials
+func hostAuthConfig(h *Host, configuration *Configuration) ssh.AuthConfig {
+	return ssh.AuthConfig{
+		SSHAgent:             h.SSHAgent,
+		AgentKey:             h.AgentKey,
+		IdentityFile:         h.IdentityFile,
+		IdentityPassphrase:   h.IdentityPassphrase,
+		CertificateFile:      h.CertificateFile,
+		AddKeyToAgent:        h.AddKeysToAgent,
+		AgentKeyLifetime:     time.Duration(configuration.AgentKeyLifetime) * time.Second,
+		KeyringService:       h.KeyringService,
+		KeyringAccount:       h.KeyringAccount,
+		Password:             h.Password,
+		PasswordCommand:      h.PasswordCommand,
+		KnownHostsFile:       configuration.KnownHostsFile,
+		HostKeyPolicy:        configuration.HostKeyPolicy,
+		StrictKeyPermissions: configuration.StrictKeyPermissions,
+		InteractiveAuth:      h.InteractiveAuth,
+		SingleAuthMethod:     h.SingleAuthMethod,
+		Prompter:             promptKeyboardInteractive,
+	}
+}
+
+// Returns the name of the host h connects through, empty to connect directly
+// That's its jump_host_ref if set, otherwise defaultJumpHost unless h opts out with
+// no_jump_host or is the default jump host itself
+func (h Host) jumpHost(defaultJumpHost string) string {
+	switch {
+	case h.JumpHostRef != "":
+		return h.JumpHostRef
+	case h.NoJumpHost || h.Name == defaultJumpHost:
+		return ""
+	}
+	return defaultJumpHost
+}
+
+// Returns the proxy URL h connects through, empty to connect directly
+// That's its own proxy if set, otherwise defaultProxy unless h's proxy is "none"
+func (h Host) proxyURL(defaultProxy string) string {
+	switch h.Proxy {
+	case "":
+		return defaultProxy
+	case "none":
+		return ""
+	}
+	return h.Proxy
+}
+
+// Returns proxy with any password in it hidden, for display
+func redactProxy(proxy string) string {
+	if u, err := ssh.ParseProxy(proxy); err == nil {
+		return u.Redacted()
+	}
+	return proxy
+}
+
+// Resolves the jump host h connects through, and those that host connects through, into
+// the hosts to connect through, outermost first. Jump hosts use their stored credentials since
+// there's no chance to prompt for theirs. Returns an error if a name isn't found or the references loop
+func resolveJumpHosts(h *Host, configuration *Configuration) ([]ssh.JumpHost, error) {
+	ref := h.jumpHost(configuration.DefaultJumpHost)
+	if ref == "" {
+		return nil, nil
+	}
+
+	targets := allTargets(configuration)
+	path := []string{h.Name}
+	var hops []ssh.JumpHost
+	for ref != "" {
+		if slices.Contains(path, ref) {
+			return nil, fmt.Errorf("jump host references form a cycle: %s", strings.Join(append(path, ref), " -> "))
+		}
+		i := slices.IndexFunc(targets, func(t Host) bool { return t.Name == ref })
+		if i < 0 {
+			return nil, fmt.Errorf("jump host %q of %s not found", ref, path[len(path)-1])
+		}
+		jump := &targets[i]
+		hops = slices.Insert(hops, 0, ssh.JumpHost{
+			Name: jump.Name,
+			Host: jump.Host,
+			Port: jump.Port,
+			User: jump.User,
+			Auth: hostAuthConfig(jump, configuration),
+		})
+		path = append(path, ref)
+		ref = jump.jumpHost(configuration.DefaultJumpHost)
+	}
+	return hops, nil
 }
 
 func main() {
@@ -292,36 +1360,89 @@ func main() {
 	}
 	defer logger.Close()
 
-	// Get the directory where the config file is located
-	configDir, err := getConfigDir()
+	// Find config.json
+	configPath, err := getConfigPath()
 	if err != nil {
-		logger.Fatalf("Failed to get config directory: %v", err)
-		fmt.Fprintf(os.Stderr, "Error: Failed to get config directory: %v\n", err)
+		logger.Fatalf("Failed to get config path: %v", err)
+		fmt.Fprintf(os.Stderr, "Error: Failed to get config path: %v\n", err)
 		os.Exit(1)
 	}
+	logger.Printf("Using config %s", configPath)
 
-	// Look for config.json in the config directory
-	configPath := filepath.Join(configDir, "config.json")
+	configuration := &Configuration{}
 	data, err := os.ReadFile(configPath)
-	if err != nil {
+	if errors.Is(err, os.ErrNotExist) {
+		// First run, start empty and create the file when the first host is added
+		logger.Printf("No config.json found at %s, starting with no hosts", configPath)
+	} else if err != nil {
 		logger.Fatalf("Failed to read config.json from %s: %v", configPath, err)
 		fmt.Fprintf(os.Stderr, "Error: Failed to read config.json from %s: %v\n", configPath, err)
 		os.Exit(1)
-	}
-
-	configuration := &Configuration{}
-	if err := json.Unmarshal(data, &configuration); err != nil {
+	} else if err := json.Unmarshal(data, &configuration); err != nil {
 		logger.Fatalf("Failed to parse config.json: %v", err)
 		fmt.Fprintf(os.Stderr, "Error: Failed to parse config.json: %v\n", err)
 		os.Exit(1)
 	}
 
-	logger.Printf("Loaded configuration with %d hosts", len(configuration.Hosts))
+	logger.Printf("Loaded configuration with %d hosts and %d folders", len(configuration.Hosts), len(configuration.Folders))
+	if err := applyKeyBindings(configuration.KeyBindings); err != nil {
+		logger.Fatalf("Invalid keybindings in config.json: %v", err)
+		fmt.Fprintf(os.Stderr, "Error: Invalid keybindings in config.json: %v\n", err)
+		os.Exit(1)
+	}
+	setLogLevel(configuration.LogLevel)
+	logger.Prune(configuration.MaxLogAge, configuration.MaxLogFiles)
+
+	// Query the terminal before Bubble Tea starts reading input
+	if len(os.Args) == 1 && (configuration.Theme == nil || configuration.Theme.Name == "") {
+		detectTheme()
+	}
+
+	// Subcommands run without the TUI
+	if len(os.Args) > 1 {
+		code := runCommand(os.Args[1:], configuration, configPath)
+		logger.Close()
+		os.Exit(code)
+	}
+
+	// Background tunnels keep running while connected elsewhere and across TUI restarts
+	tunnels := &tunnelSet{}
+	watcher := watchConfig(configPath)
+
+	model := initialModel(configuration, configPath)
+
+	// Report config mistakes now rather than one failed connection at a time
+	if issues := validateConfiguration(configuration); len(issues) > 0 {
+		for _, issue := range issues {
+			logger.Errorf("Config problem: %v", issue)
+		}
+		model.configIssues = issues
+		model.view = configIssuesView
+	}
+	// Hosts skipped at startup stay hidden for the rest of the run
+	var skippedHosts map[string]bool
+	// Filters and folders stay as they were when returning from a session
+	var state listState
 
-	model := initialModel(configuration.Hosts, configPath)
 	for {
-		p := tea.NewProgram(model, tea.WithAltScreen())
+		model.tunnels = tunnels
+		model.configWatcher = watcher
+		if skippedHosts != nil {
+			model.skippedHosts = skippedHosts
+		}
+		model.restoreListState(state)
+		model.list.Title = model.listTitle()
+
+		opts := []tea.ProgramOption{tea.WithAltScreen()}
+		if model.mouse {
+			opts = append(opts, tea.WithMouseCellMotion())
+		}
+		// Each session restarts the program, so stop the old one's background waits with it
+		done := make(chan struct{})
+		model.done = done
+		p := tea.NewProgram(model, opts...)
 		finalModel, err := p.Run()
+		close(done)
 		if err != nil {
 			logger.Fatalf("Application error: %v", err)
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
@@ -334,33 +1455,116 @@ func main() {
 			fmt.Fprintln(os.Stderr, "Error: Unexpected model type returned from Bubble Tea")
 			os.Exit(1)
 		}
+		skippedHosts = m.skippedHosts
+		state = m.listState()
+
+		// Start on the same host next time, even after quitting
+		if it, ok := m.list.SelectedItem().(Item); ok && it.host.Name != configuration.LastSelected {
+			if err := saveLastSelected(configPath, it.host.Name); err != nil {
+				logger.Errorf("Failed to save last selected host: %v", err)
+			} else {
+				configuration.LastSelected = it.host.Name
+			}
+		}
+
+		if len(m.connectGroup) > 0 {
+			clearScreen()
+			err := connectSequentially(m.connectGroup, configuration, configPath)
+			if reloaded, readErr := readConfig(configPath); readErr == nil {
+				configuration = &reloaded
+			}
+			model = initialModel(configuration, configPath)
+			if err != nil {
+				model.err = err
+				model.showErr = true
+			}
+			continue
+		}
 
 		if m.connectHost == nil {
+			tunnels.closeAll()
 			logger.Printf("Application exited normally")
 			os.Exit(0)
 		}
 
 		clearScreen()
 
+		// Use settings changed in the TUI for this connection
+		if reloaded, readErr := readConfig(configPath); readErr == nil {
+			configuration = &reloaded
+			setLogLevel(configuration.LogLevel)
+		}
+
 		// Run SSH session in the main terminal buffer
 		h := m.connectHost
-		authConfig := ssh.AuthConfig{
-			SSHAgent:           h.SSHAgent,
-			IdentityFile:       h.IdentityFile,
-			IdentityPassphrase: h.IdentityPassphrase,
-			KeyringService:     h.KeyringService,
-			KeyringAccount:     h.KeyringAccount,
-			Password:           h.Password,
-		}
-		err = ssh.StartSession(h.Host, h.Port, h.User, authConfig, m.width, m.height)
-		if err != nil {
+		authConfig, sessionConfig, configErr := connectionConfig(h, configuration, m.connectPassword)
+		connect := func() error {
+			if configErr != nil {
+				return configErr
+			}
+			if m.browse {
+				return browseHostFiles(h, authConfig, sessionConfig)
+			}
+			return ssh.StartSession(h.Host, h.Port, h.User, authConfig, sessionConfig, m.width, m.height)
+		}
+		if client := m.connectClient; client != nil {
+			// Already connected while the TUI showed progress
+			connect = func() error {
+				defer client.Close()
+				return client.Shell(m.connectSessionConfig, m.width, m.height)
+			}
+		}
+		start := time.Now()
+		if m.reconnect {
+			maxAttempts := configuration.MaxReconnectAttempts
+			if maxAttempts <= 0 {
+				maxAttempts = defaultMaxReconnectAttempts
+			}
+			err = reconnectWithBackoff(maxAttempts, connect)
+		} else {
+			err = connect()
+		}
+
+		// Don't keep prompted passwords around longer than the session
+		clear(m.connectPassword)
+		authConfig.Password = ""
+
+		var disconnectErr *ssh.DisconnectError
+		if err == nil || errors.As(err, &disconnectErr) {
+			if touchErr := recordConnection(configPath, h.configName(), time.Now(), time.Since(start)); touchErr != nil {
+				logger.Errorf("Failed to record connection for %s: %v", h.Name, touchErr)
+			}
+		}
+
+		// Pick up changes made in the TUI or by other instances
+		if reloaded, readErr := readConfig(configPath); readErr == nil {
+			configuration = &reloaded
+		} else if !errors.Is(readErr, os.ErrNotExist) {
+			logger.Errorf("Failed to reload config: %v", readErr)
+		}
+
+		var unknownErr *ssh.UnknownHostKeyError
+		if errors.As(err, &unknownErr) {
+			// Ask the user to confirm the host key when we return to the TUI
+			model = initialModel(configuration, configPath)
+			model.unknownHostKey = unknownErr
+			model.unknownHostKeyHost = h
+			model.browse = m.browse
+			model.view = hostKeyConfirmView
+		} else if errors.As(err, &disconnectErr) {
+			// Offer to reconnect when the connection dropped unexpectedly
+			model = initialModel(configuration, configPath)
+			model.disconnectErr = disconnectErr
+			model.disconnectHost = h
+			model.view = reconnectView
+		} else if err != nil {
 			// Show error when we return to the TUI
-			model = initialModel(configuration.Hosts, configPath)
+			model = initialModel(configuration, configPath)
 			model.err = err
 			model.showErr = true
 		} else {
 			// Reset the TUI after a successful session
-			model = initialModel(configuration.Hosts, configPath)
+			model = initialModel(configuration, configPath)
 		}
 	}
 }
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestResolveJumpHosts(t *testing.T) {
+	tests := []struct {
+		name        string
+		hosts       []Host
+		folders     []Folder
+		defaultJump string
+		target      string
+		want        []string // Hop names, outermost first
+		wantErr     string
+	}{
+		{
+			name:   "direct",
+			hosts:  []Host{{Name: "web"}},
+			target: "web",
+		},
+		{
+			name:   "one hop",
+			hosts:  []Host{{Name: "web", JumpHostRef: "bastion"}, {Name: "bastion"}},
+			target: "web",
+			want:   []string{"bastion"},
+		},
+		{
+			name: "chain outermost first",
+			hosts: []Host{
+				{Name: "db", JumpHostRef: "inner"},
+				{Name: "inner", JumpHostRef: "outer"},
+				{Name: "outer"},
+			},
+			target: "db",
+			want:   []string{"outer", "inner"},
+		},
+		{
+			name:    "jump host in a folder",
+			hosts:   []Host{{Name: "web", JumpHostRef: "bastion"}},
+			folders: []Folder{{Name: "infra", Hosts: []Host{{Name: "bastion"}}}},
+			target:  "web",
+			want:    []string{"bastion"},
+		},
+		{
+			name:        "default jump host",
+			hosts:       []Host{{Name: "web"}, {Name: "bastion"}},
+			defaultJump: "bastion",
+			target:      "web",
+			want:        []string{"bastion"},
+		},
+		{
+			name:        "default jump host connects directly",
+			hosts:       []Host{{Name: "bastion"}},
+			defaultJump: "bastion",
+			target:      "bastion",
+		},
+		{
+			name:        "opted out of the default",
+			hosts:       []Host{{Name: "web", NoJumpHost: true}, {Name: "bastion"}},
+			defaultJump: "bastion",
+			target:      "web",
+		},
+		{
+			name:    "not found",
+			hosts:   []Host{{Name: "web", JumpHostRef: "inner"}, {Name: "inner", JumpHostRef: "gone"}},
+			target:  "web",
+			wantErr: `jump host "gone" of inner not found`,
+		},
+		{
+			name:    "references itself",
+			hosts:   []Host{{Name: "web", JumpHostRef: "web"}},
+			target:  "web",
+			wantErr: "cycle: web -> web",
+		},
+		{
+			name: "cycle",
+			hosts: []Host{
+				{Name: "web", JumpHostRef: "a"},
+				{Name: "a", JumpHostRef: "b"},
+				{Name: "b", JumpHostRef: "a"},
+			},
+			target:  "web",
+			wantErr: "cycle: web -> a -> b -> a",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configuration := &Configuration{Hosts: tt.hosts, Folders: tt.folders}
+			configuration.DefaultJumpHost = tt.defaultJump
+			i := slices.IndexFunc(tt.hosts, func(h Host) bool { return h.Name == tt.target })
+			hops, err := resolveJumpHosts(&tt.hosts[i], configuration)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("resolveJumpHosts() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveJumpHosts(): %v", err)
+			}
+			var names []string
+			for _, hop := range hops {
+				names = append(names, hop.Name)
+			}
+			if !slices.Equal(names, tt.want) {
+				t.Errorf("hops = %q, want %q", names, tt.want)
+			}
+		})
+	}
+}
+
+func TestListStateSurvivesRebuild(t *testing.T) {
+	configuration := &Configuration{
+		Hosts: []Host{
+			{Name: "web-prod", Host: "web", Port: 22, Tags: []string{"prod"}},
+			{Name: "db-prod", Host: "db", Port: 22, Tags: []string{"prod"}},
+			{Name: "db-dev", Host: "db-dev", Port: 22, Tags: []string{"dev"}},
+		},
+		Folders: []Folder{{Name: "archive", Hosts: []Host{{Name: "db-old", Host: "old", Port: 22, Tags: []string{"prod"}}}}},
+	}
+	configPath := filepath.Join(t.TempDir(), "config.json")
+
+	m := initialModel(configuration, configPath)
+	m.tagFilter = "prod"
+	m.collapsed["archive"] = true
+	m.list.SetItems(m.buildItems())
+	m.list.SetFilterText("db")
+	selectHostNamed(&m.list, "db-prod")
+	state := m.listState()
+
+	// main builds a new model after every session
+	rebuilt := initialModel(configuration, configPath)
+	rebuilt.restoreListState(state)
+
+	if rebuilt.tagFilter != "prod" {
+		t.Errorf("tag filter = %q, want prod", rebuilt.tagFilter)
+	}
+	if !rebuilt.collapsed["archive"] {
+		t.Error("archive folder expanded, want it still collapsed")
+	}
+	if got := rebuilt.list.FilterValue(); got != "db" {
+		t.Errorf("filter = %q, want db", got)
+	}
+	it, ok := rebuilt.list.SelectedItem().(Item)
+	if !ok || it.host.Name != "db-prod" {
+		t.Errorf("selected %v, want db-prod", rebuilt.list.SelectedItem())
+	}
+	for _, item := range rebuilt.list.VisibleItems() {
+		if it, ok := item.(Item); ok && it.host.Name == "db-dev" {
+			t.Error("db-dev is visible, want it hidden by the prod tag filter")
+		}
+	}
+}
+
+func TestListStateSelectedHostGone(t *testing.T) {
+	configuration := &Configuration{Hosts: []Host{{Name: "web", Host: "web", Port: 22}, {Name: "db", Host: "db", Port: 22}}}
+	m := initialModel(configuration, filepath.Join(t.TempDir(), "config.json"))
+	m.restoreListState(listState{selected: "removed"})
+	if it, ok := m.list.SelectedItem().(Item); !ok || it.host.Name != "web" {
+		t.Errorf("selected %v, want the cursor left on web", m.list.SelectedItem())
+	}
+}
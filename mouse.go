@@ -0,0 +1,118 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	lg "github.com/charmbracelet/lipgloss"
+)
+
+// Two clicks on the same host within this long count as a double-click
+const doubleClickInterval = 500 * time.Millisecond
+
+// Handles mouse clicks and the scroll wheel when mouse support is enabled
+func (m Model) updateMouse(msg tea.MouseMsg) (tea.Model, tea.Cmd) {
+	if m.showErr {
+		return m, nil
+	}
+
+	switch m.view {
+	case listView:
+		return m.updateListMouse(msg)
+	case formView:
+		return m.updateFormMouse(msg)
+	}
+	return m, nil
+}
+
+// Selects the clicked host, connecting on a double-click
+func (m Model) updateListMouse(msg tea.MouseMsg) (tea.Model, tea.Cmd) {
+	if m.list.SettingFilter() {
+		return m, nil
+	}
+
+	switch {
+	case msg.Button == tea.MouseButtonWheelUp:
+		m.list.CursorUp()
+		return m, nil
+	case msg.Button == tea.MouseButtonWheelDown:
+		m.list.CursorDown()
+		return m, nil
+	case msg.Button != tea.MouseButtonLeft || msg.Action != tea.MouseActionPress:
+		return m, nil
+	}
+
+	index, ok := m.listIndexAt(msg.Y)
+	if !ok {
+		return m, nil
+	}
+
+	doubleClick := index == m.lastClickIndex && time.Since(m.lastClick) < doubleClickInterval
+	m.list.Select(index)
+	m.lastClickIndex = index
+	m.lastClick = time.Now()
+
+	if doubleClick {
+		m.lastClick = time.Time{}
+		return m.updateList(tea.KeyMsg{Type: tea.KeyEnter})
+	}
+	return m, nil
+}
+
+// Returns the index of the visible list item at screen row y
+func (m Model) listIndexAt(y int) (int, bool) {
+	// Items start below the doc margin, title bar and status bar
+	top := docStyle.GetMarginTop()
+	if m.list.ShowTitle() || (m.list.ShowFilter() && m.list.FilteringEnabled()) {
+		top += lg.Height(m.list.Styles.TitleBar.Render(""))
+	}
+	if m.list.ShowStatusBar() {
+		top += lg.Height(m.list.Styles.StatusBar.Render(""))
+	}
+
+	d := m.listDelegate()
+	rowHeight := d.Height() + d.Spacing()
+	if y < top || (y-top)%rowHeight >= d.Height() {
+		return 0, false
+	}
+
+	index := m.list.Paginator.Page*m.list.Paginator.PerPage + (y-top)/rowHeight
+	if index >= len(m.list.VisibleItems()) {
+		return 0, false
+	}
+	return index, true
+}
+
+// Focuses the clicked form input
+func (m Model) updateFormMouse(msg tea.MouseMsg) (tea.Model, tea.Cmd) {
+	if m.form.keyPicker != nil || msg.Button != tea.MouseButtonLeft || msg.Action != tea.MouseActionPress {
+		return m, nil
+	}
+
+	content, inputLines := m.formContent()
+	lines := strings.Split(content, "\n")
+
+	_, availHeight := m.renderFormHelp(formKeys)
+	title := m.formTitle()
+	availHeight -= lg.Height(title)
+
+	// Match the scrolling done by getVisibleFormLines
+	start := 0
+	if len(lines) > availHeight {
+		start = min(max(m.form.scrollOffset, 0), len(lines)-availHeight)
+	}
+
+	line := msg.Y - docStyle.GetMarginTop() - lg.Height(title) + start
+	if line < start || line >= start+availHeight {
+		return m, nil
+	}
+
+	// Each input's label and everything below it up to the next label belong to it
+	for i := len(inputLines) - 1; i >= 0; i-- {
+		if line >= inputLines[i] {
+			return m, m.form.focusInput(i)
+		}
+	}
+	return m, nil
+}
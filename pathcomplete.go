@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// Most completion candidates listed below an input
+const maxPathCandidates = 8
+
+// Completes the last element of a file path against the files on disk
+// A leading ~ is expanded for the lookup but kept in the result. Returns the value extended
+// as far as the matches agree, with a trailing separator for a single directory match,
+// and the matching names when more than one remains
+func completePath(value string) (string, []string) {
+	dir, prefix := filepath.Split(value)
+	lookup := dir
+	if lookup == "" {
+		lookup = "."
+	} else if strings.HasPrefix(lookup, "~") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return value, nil
+		}
+		lookup = filepath.Join(home, lookup[1:])
+	}
+
+	entries, err := os.ReadDir(lookup)
+	if err != nil {
+		return value, nil
+	}
+
+	var names []string
+	for _, e := range entries {
+		name := e.Name()
+		// Hidden files are only offered once a dot is typed
+		if !strings.HasPrefix(name, prefix) || (strings.HasPrefix(name, ".") && !strings.HasPrefix(prefix, ".")) {
+			continue
+		}
+		if e.IsDir() {
+			name += string(filepath.Separator)
+		}
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	switch len(names) {
+	case 0:
+		return value, nil
+	case 1:
+		return dir + names[0], nil
+	}
+	return dir + commonPrefix(names), names
+}
+
+// Returns the longest prefix shared by all names
+func commonPrefix(names []string) string {
+	prefix := names[0]
+	for _, name := range names[1:] {
+		for !strings.HasPrefix(name, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	// Don't end partway through a multi-byte character
+	for !utf8.ValidString(prefix) {
+		prefix = prefix[:len(prefix)-1]
+	}
+	return prefix
+}
+
+// Formats completion candidates for display below an input, e.g. "id_ed25519  id_rsa  +3 more"
+func formatPathCandidates(names []string) string {
+	if len(names) <= maxPathCandidates {
+		return strings.Join(names, "  ")
+	}
+	return strings.Join(names[:maxPathCandidates], "  ") + fmt.Sprintf("  +%d more", len(names)-maxPathCandidates)
+}
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/nathanlytang/rolodex/internal/ssh"
+)
+
+// Where PuTTY keeps saved sessions on Linux and macOS, one file per session
+const defaultPuttySessionsPath = "~/.putty/sessions"
+
+// Registry key PuTTY keeps saved sessions under on Windows
+const puttyRegistryKey = `HKEY_CURRENT_USER\Software\SimonTatham\PuTTY\Sessions`
+
+// Folder imported sessions are saved to
+const puttyImportFolder = "PuTTY"
+
+// Reads PuTTY's saved sessions and converts the SSH ones to hosts, sorted by name
+// path may be an exported .reg file, a PuTTY sessions directory, or a single session file.
+// An empty path reads the registry on Windows and ~/.putty/sessions elsewhere
+func loadPuttySessions(path string) ([]Host, error) {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		if runtime.GOOS == "windows" {
+			data, err := exportPuttyRegistry()
+			if err != nil {
+				return nil, err
+			}
+			return puttyHosts(parsePuttyReg(decodeRegFile(data))), nil
+		}
+		path = defaultPuttySessionsPath
+	}
+
+	path, err := ssh.ResolveIdentityFile(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make(map[string]map[string]string)
+	if info.IsDir() {
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			if e.IsDir() {
+				continue
+			}
+			data, err := os.ReadFile(filepath.Join(path, e.Name()))
+			if err != nil {
+				return nil, err
+			}
+			sessions[e.Name()] = parsePuttySessionFile(string(data))
+		}
+	} else {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		text := decodeRegFile(data)
+		if isRegFile(text) {
+			sessions = parsePuttyReg(text)
+		} else {
+			sessions[filepath.Base(path)] = parsePuttySessionFile(text)
+		}
+	}
+	return puttyHosts(sessions), nil
+}
+
+// Exports the PuTTY sessions registry key with reg.exe, which avoids a registry dependency
+func exportPuttyRegistry() ([]byte, error) {
+	tmp, err := os.CreateTemp("", "rolodex-putty-*.reg")
+	if err != nil {
+		return nil, err
+	}
+	tmpPath := tmp.Name()
+	tmp.Close()
+	defer os.Remove(tmpPath)
+
+	var stderr bytes.Buffer
+	cmd := exec.Command("reg", "export", puttyRegistryKey, tmpPath, "/y")
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("failed to read PuTTY sessions from the registry: %s", msg)
+		}
+		return nil, fmt.Errorf("failed to read PuTTY sessions from the registry: %w", err)
+	}
+	return os.ReadFile(tmpPath)
+}
+
+// Returns the text of a .reg file, which regedit writes as UTF-16LE with a byte order mark
+func decodeRegFile(data []byte) string {
+	if !bytes.HasPrefix(data, []byte{0xff, 0xfe}) {
+		return string(bytes.TrimPrefix(data, []byte{0xef, 0xbb, 0xbf}))
+	}
+	data = data[2:]
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		units[i] = uint16(data[2*i]) | uint16(data[2*i+1])<<8
+	}
+	return string(utf16.Decode(units))
+}
+
+// Reports whether text is a registry export rather than a PuTTY session file
+func isRegFile(text string) bool {
+	return strings.HasPrefix(text, "Windows Registry Editor") || strings.HasPrefix(text, "REGEDIT4")
+}
+
+// Returns the values of each session key in a registry export, keyed by session name
+// Session names are left URL-encoded like PuTTY stores them
+func parsePuttyReg(text string) map[string]map[string]string {
+	sessions := make(map[string]map[string]string)
+	var current map[string]string
+	prefix := strings.ToLower(puttyRegistryKey) + `\`
+
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			key := line[1 : len(line)-1]
+			current = nil
+			if strings.HasPrefix(strings.ToLower(key), prefix) && !strings.Contains(key[len(prefix):], `\`) {
+				current = make(map[string]string)
+				sessions[key[len(prefix):]] = current
+			}
+			continue
+		}
+		if current == nil || !strings.HasPrefix(line, `"`) {
+			continue
+		}
+		name, value, ok := parseRegValue(line)
+		if ok {
+			current[name] = value
+		}
+	}
+	return sessions
+}
+
+// Parses a "Name"="string" or "Name"=dword:0000hex registry value line
+// DWORDs are returned in decimal. Other value types are skipped
+func parseRegValue(line string) (string, string, bool) {
+	name, rest, ok := readRegString(line)
+	if !ok || !strings.HasPrefix(rest, "=") {
+		return "", "", false
+	}
+	rest = rest[1:]
+
+	if hex, ok := strings.CutPrefix(rest, "dword:"); ok {
+		n, err := strconv.ParseUint(hex, 16, 32)
+		if err != nil {
+			return "", "", false
+		}
+		return name, strconv.FormatUint(n, 10), true
+	}
+	value, _, ok := readRegString(rest)
+	return name, value, ok
+}
+
+// Reads a quoted registry string with \\ and \" escapes from the start of s
+// Returns the unescaped string and what follows the closing quote
+func readRegString(s string) (string, string, bool) {
+	if !strings.HasPrefix(s, `"`) {
+		return "", "", false
+	}
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if i+1 < len(s) {
+				i++
+				b.WriteByte(s[i])
+			}
+		case '"':
+			return b.String(), s[i+1:], true
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return "", "", false
+}
+
+// Returns the Key=value settings of a session file from ~/.putty/sessions
+func parsePuttySessionFile(text string) map[string]string {
+	values := make(map[string]string)
+	for line := range strings.Lines(text) {
+		if name, value, ok := strings.Cut(strings.TrimRight(line, "\r\n"), "="); ok {
+			values[name] = value
+		}
+	}
+	return values
+}
+
+// Converts sessions to hosts, skipping PuTTY's defaults and sessions that aren't SSH
+func puttyHosts(sessions map[string]map[string]string) []Host {
+	var hosts []Host
+	for name, values := range sessions {
+		if decoded, err := url.PathUnescape(name); err == nil {
+			name = decoded
+		}
+		if h, ok := puttySessionToHost(name, values); ok {
+			hosts = append(hosts, h)
+		}
+	}
+	slices.SortFunc(hosts, func(a, b Host) int { return strings.Compare(a.Name, b.Name) })
+	return hosts
+}
+
+// Converts a PuTTY session's HostName, PortNumber, UserName and PublicKeyFile to a host
+// Sessions without a key ask for the password, like PuTTY does
+func puttySessionToHost(name string, values map[string]string) (Host, bool) {
+	if name == "Default Settings" {
+		return Host{}, false
+	}
+	if protocol := values["Protocol"]; protocol != "" && protocol != "ssh" {
+		return Host{}, false
+	}
+	hostName := strings.TrimSpace(values["HostName"])
+	if hostName == "" {
+		return Host{}, false
+	}
+
+	h := Host{Name: name, Port: 22, User: values["UserName"]}
+	// PuTTY accepts user@host in the host name
+	if user, host, ok := strings.Cut(hostName, "@"); ok {
+		if h.User == "" {
+			h.User = user
+		}
+		hostName = host
+	}
+	h.Host = hostName
+	if port, err := strconv.Atoi(values["PortNumber"]); err == nil && port > 0 {
+		h.Port = port
+	}
+
+	if keyFile := values["PublicKeyFile"]; keyFile != "" {
+		identityFile, err := convertPuttyKeyPath(keyFile)
+		if err != nil {
+			h.Notes = err.Error()
+		}
+		h.IdentityFile = identityFile
+	}
+	if h.IdentityFile == "" {
+		h.PromptPassword = true
+	}
+	return h, true
+}
+
+// Returns the OpenSSH key to use for a PuTTY key file
+// .ppk keys can't be read, so an OpenSSH copy beside it (key or key.pem) is used when there is one
+func convertPuttyKeyPath(keyFile string) (string, error) {
+	ext := filepath.Ext(keyFile)
+	if !strings.EqualFold(ext, ".ppk") {
+		return keyFile, nil
+	}
+	base := strings.TrimSuffix(keyFile, ext)
+	for _, candidate := range []string{base, base + ".pem"} {
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("PuTTY key %s needs converting with puttygen (Conversions > Export OpenSSH key)", keyFile)
+}
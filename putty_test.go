@@ -0,0 +1,214 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+// Encodes text the way regedit and reg export write .reg files: UTF-16LE with a byte order mark
+func utf16RegFile(text string) []byte {
+	data := []byte{0xff, 0xfe}
+	for _, u := range utf16.Encode([]rune(text)) {
+		data = append(data, byte(u), byte(u>>8))
+	}
+	return data
+}
+
+func TestDecodeRegFile(t *testing.T) {
+	const text = "Windows Registry Editor Version 5.00\r\n\r\n[HKEY_CURRENT_USER\\Software]\r\n\"Name\"=\"café 🚀\"\r\n"
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"UTF-16LE with BOM", utf16RegFile(text)},
+		{"UTF-8 with BOM", append([]byte{0xef, 0xbb, 0xbf}, text...)},
+		{"UTF-8", []byte(text)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := decodeRegFile(tt.data); got != text {
+				t.Errorf("decodeRegFile() = %q, want %q", got, text)
+			}
+		})
+	}
+}
+
+func TestParseRegValue(t *testing.T) {
+	tests := []struct {
+		line      string
+		wantName  string
+		wantValue string
+		wantOK    bool
+	}{
+		{`"HostName"="web.example.com"`, "HostName", "web.example.com", true},
+		{`"PortNumber"=dword:00000016`, "PortNumber", "22", true},
+		{`"PortNumber"=dword:0000ffff`, "PortNumber", "65535", true},
+		{`"PublicKeyFile"="C:\\Users\\alice\\.ssh\\id.ppk"`, "PublicKeyFile", `C:\Users\alice\.ssh\id.ppk`, true},
+		{`"Command"="echo \"hi\""`, "Command", `echo "hi"`, true},
+		{`"Empty"=""`, "Empty", "", true},
+		{`"Escaped\"Name"="x"`, `Escaped"Name`, "x", true},
+		{`"Colours"=hex:01,02`, "", "", false},
+		{`"PortNumber"=dword:zz`, "", "", false},
+		{`"Unterminated"="web`, "", "", false},
+		{`"NoValue"`, "", "", false},
+		{`@="default"`, "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			name, value, ok := parseRegValue(tt.line)
+			// What's returned alongside false isn't used
+			if ok != tt.wantOK || ok && (name != tt.wantName || value != tt.wantValue) {
+				t.Errorf("parseRegValue(%q) = %q, %q, %v, want %q, %q, %v", tt.line, name, value, ok, tt.wantName, tt.wantValue, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestLoadPuttySessionsRegExport(t *testing.T) {
+	dir := t.TempDir()
+	// An OpenSSH copy of the .ppk, as puttygen exports it
+	keyBase := filepath.Join(dir, "deploy")
+	if err := os.WriteFile(keyBase+".pem", []byte("key"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	regPath := func(path string) string { return strings.ReplaceAll(path, `\`, `\\`) }
+
+	// Trimmed from reg export HKCU\Software\SimonTatham\PuTTY\Sessions
+	reg := strings.Join([]string{
+		`Windows Registry Editor Version 5.00`,
+		``,
+		`[HKEY_CURRENT_USER\Software\SimonTatham\PuTTY\Sessions]`,
+		``,
+		`[HKEY_CURRENT_USER\Software\SimonTatham\PuTTY\Sessions\Default%20Settings]`,
+		`"HostName"="default.example.com"`,
+		`"Protocol"="ssh"`,
+		``,
+		`[HKEY_CURRENT_USER\Software\SimonTatham\PuTTY\Sessions\Web%20Prod]`,
+		`"Present"=dword:00000001`,
+		`"HostName"="web.example.com"`,
+		`"PortNumber"=dword:00000816`,
+		`"Protocol"="ssh"`,
+		`"UserName"="deploy"`,
+		`"PublicKeyFile"="` + regPath(keyBase+".ppk") + `"`,
+		``,
+		`[HKEY_CURRENT_USER\Software\SimonTatham\PuTTY\Sessions\Web%20Prod\Extra]`,
+		`"HostName"="nested.example.com"`,
+		``,
+		`[HKEY_CURRENT_USER\Software\SimonTatham\PuTTY\Sessions\db]`,
+		`"HostName"="admin@db.example.com"`,
+		`"PortNumber"=dword:00000016`,
+		`"Protocol"="ssh"`,
+		`"UserName"=""`,
+		`"PublicKeyFile"=""`,
+		``,
+		`[HKEY_CURRENT_USER\Software\SimonTatham\PuTTY\Sessions\switch]`,
+		`"HostName"="switch.example.com"`,
+		`"Protocol"="telnet"`,
+		``,
+		`[HKEY_CURRENT_USER\Software\SimonTatham\PuTTY\SshHostKeys]`,
+		`"ssh-ed25519@22:web.example.com"="0x1234"`,
+		``,
+	}, "\r\n")
+	path := filepath.Join(dir, "putty.reg")
+	if err := os.WriteFile(path, utf16RegFile(reg), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	hosts, err := loadPuttySessions(path)
+	if err != nil {
+		t.Fatalf("loadPuttySessions: %v", err)
+	}
+	want := []Host{
+		{Name: "Web Prod", Host: "web.example.com", Port: 2070, User: "deploy", IdentityFile: keyBase + ".pem"},
+		{Name: "db", Host: "db.example.com", Port: 22, User: "admin", PromptPassword: true},
+	}
+	if !reflect.DeepEqual(hosts, want) {
+		t.Errorf("loadPuttySessions() = %+v, want %+v", hosts, want)
+	}
+}
+
+func TestLoadPuttySessionsDirectory(t *testing.T) {
+	// Laid out like ~/.putty/sessions, one file per session named after it
+	dir := t.TempDir()
+	sessions := map[string]string{
+		"Default%20Settings": "HostName=\nProtocol=ssh\n",
+		"web%20prod":         "HostName=web.example.com\nPortNumber=2222\nProtocol=ssh\nUserName=alice\nPublicKeyFile=\n",
+		"bastion":            "HostName=root@bastion.example.com\r\nPortNumber=22\r\nProtocol=ssh\r\nUserName=ops\r\nPublicKeyFile=/keys/id_ed25519\r\n",
+		"serial":             "HostName=\nProtocol=serial\nSerialLine=/dev/ttyUSB0\n",
+		"no-host":            "PortNumber=22\nProtocol=ssh\n",
+	}
+	for name, text := range sessions {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(text), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Subdirectories aren't sessions
+	if err := os.Mkdir(filepath.Join(dir, "backup"), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	hosts, err := loadPuttySessions(dir)
+	if err != nil {
+		t.Fatalf("loadPuttySessions: %v", err)
+	}
+	want := []Host{
+		// The session's UserName wins over the one in the host name
+		{Name: "bastion", Host: "bastion.example.com", Port: 22, User: "ops", IdentityFile: "/keys/id_ed25519"},
+		{Name: "web prod", Host: "web.example.com", Port: 2222, User: "alice", PromptPassword: true},
+	}
+	if !reflect.DeepEqual(hosts, want) {
+		t.Errorf("loadPuttySessions() = %+v, want %+v", hosts, want)
+	}
+
+	// A single session file is named after the file
+	hosts, err = loadPuttySessions(filepath.Join(dir, "web%20prod"))
+	if err != nil {
+		t.Fatalf("loadPuttySessions: %v", err)
+	}
+	if len(hosts) != 1 || hosts[0].Name != "web prod" {
+		t.Errorf("loadPuttySessions() of one file = %+v, want the web prod session", hosts)
+	}
+}
+
+func TestConvertPuttyKeyPath(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"plain", "exported.pem"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("key"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "folder"), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		keyFile string
+		want    string
+		wantErr bool
+	}{
+		{"OpenSSH key unchanged", filepath.Join(dir, "id_ed25519"), filepath.Join(dir, "id_ed25519"), false},
+		{"copy without extension", filepath.Join(dir, "plain.ppk"), filepath.Join(dir, "plain"), false},
+		{"pem copy", filepath.Join(dir, "exported.PPK"), filepath.Join(dir, "exported.pem"), false},
+		{"directory isn't a key", filepath.Join(dir, "folder.ppk"), "", true},
+		{"no copy", filepath.Join(dir, "missing.ppk"), "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := convertPuttyKeyPath(tt.keyFile)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("convertPuttyKeyPath(%q) error = %v, want error %v", tt.keyFile, err, tt.wantErr)
+			}
+			if err != nil && !strings.Contains(err.Error(), "puttygen") {
+				t.Errorf("convertPuttyKeyPath(%q) error = %q, want a puttygen hint", tt.keyFile, err)
+			}
+			if got != tt.want {
+				t.Errorf("convertPuttyKeyPath(%q) = %q, want %q", tt.keyFile, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Most hosts shown when the list is narrowed to recent connections
+const recentHostLimit = 10
+
+// Returns the hosts connected to most recently, newest first, without folder headers
+// Hosts never connected to are left out, as are those hidden by the tag filter
+func (m Model) recentItems() []list.Item {
+	var recent []Item
+	add := func(items []list.Item) {
+		for _, item := range items {
+			if it, ok := item.(Item); ok && !it.host.LastConnected.IsZero() {
+				recent = append(recent, it)
+			}
+		}
+	}
+	for fi, f := range m.folders {
+		add(m.hostItems(f.Hosts, fi))
+	}
+	add(m.hostItems(m.hosts, -1))
+	sortRecent.sortItems(recent)
+
+	items := make([]list.Item, 0, min(len(recent), recentHostLimit))
+	for _, it := range recent[:min(len(recent), recentHostLimit)] {
+		items = append(items, it)
+	}
+	return items
+}
+
+// Reports whether any host has been connected to
+func (m Model) hasConnectionHistory() bool {
+	hasHistory := func(hosts []Host) bool {
+		return slices.ContainsFunc(hosts, func(h Host) bool { return !h.LastConnected.IsZero() })
+	}
+	if hasHistory(m.hosts) {
+		return true
+	}
+	return slices.ContainsFunc(m.folders, func(f Folder) bool { return hasHistory(f.Hosts) })
+}
+
+// Narrows the list to the hosts connected to most recently, or goes back to all hosts
+func (m Model) toggleRecentHosts() (tea.Model, tea.Cmd) {
+	if !m.recentOnly && !m.hasConnectionHistory() {
+		return m, m.list.NewStatusMessage("No connections yet, hosts you connect to will be listed here")
+	}
+	selected := m.list.SelectedItem()
+
+	m.recentOnly = !m.recentOnly
+	m.list.Title = m.listTitle()
+	cmds := []tea.Cmd{m.list.SetItems(m.buildItems())}
+	if it, ok := selected.(Item); ok {
+		selectHostNamed(&m.list, it.host.Name)
+	}
+
+	if m.recentOnly {
+		cmds = append(cmds, m.list.NewStatusMessage(fmt.Sprintf("Showing the last %d hosts connected to", recentHostLimit)))
+	} else {
+		cmds = append(cmds, m.list.NewStatusMessage("Showing all hosts"))
+	}
+	return m, tea.Batch(cmds...)
+}
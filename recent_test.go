@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+)
+
+// Returns the names of the hosts among items, in order
+func itemNames(items []Item) []string {
+	names := make([]string, len(items))
+	for i, it := range items {
+		names[i] = it.host.Name
+	}
+	return names
+}
+
+func TestRecentItems(t *testing.T) {
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	ago := func(hours int) time.Time { return base.Add(-time.Duration(hours) * time.Hour) }
+
+	tests := []struct {
+		name      string
+		hosts     []Host
+		folders   []Folder
+		tagFilter string
+		want      []string
+	}{
+		{
+			name:  "never connected",
+			hosts: []Host{{Name: "web"}, {Name: "db"}},
+		},
+		{
+			name: "newest first across folders",
+			hosts: []Host{
+				{Name: "web", LastConnected: ago(3)},
+				{Name: "unused"},
+			},
+			folders: []Folder{{Name: "prod", Hosts: []Host{
+				{Name: "db", LastConnected: ago(1)},
+				{Name: "cache", LastConnected: ago(2)},
+			}}},
+			want: []string{"db", "cache", "web"},
+		},
+		{
+			name: "tag filter applies",
+			hosts: []Host{
+				{Name: "web", Tags: []string{"prod"}, LastConnected: ago(2)},
+				{Name: "dev", Tags: []string{"dev"}, LastConnected: ago(1)},
+			},
+			tagFilter: "prod",
+			want:      []string{"web"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := Model{hosts: tt.hosts, folders: tt.folders, tagFilter: tt.tagFilter}
+			var items []Item
+			for _, item := range m.recentItems() {
+				items = append(items, item.(Item))
+			}
+			if got := itemNames(items); !slices.Equal(got, tt.want) {
+				t.Errorf("recentItems() = %q, want %q", got, tt.want)
+			}
+			if got := m.hasConnectionHistory(); got != (len(tt.want) > 0) {
+				t.Errorf("hasConnectionHistory() = %v, want %v", got, len(tt.want) > 0)
+			}
+		})
+	}
+}
+
+func TestRecentItemsLimit(t *testing.T) {
+	var hosts []Host
+	for i := range recentHostLimit + 5 {
+		hosts = append(hosts, Host{Name: fmt.Sprintf("host%02d", i), LastConnected: time.Unix(int64(i), 0)})
+	}
+	m := Model{hosts: hosts}
+	items := m.recentItems()
+	if len(items) != recentHostLimit {
+		t.Fatalf("recentItems() returned %d hosts, want %d", len(items), recentHostLimit)
+	}
+	if newest := items[0].(Item).host.Name; newest != "host14" {
+		t.Errorf("first recent host = %q, want host14", newest)
+	}
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/fsnotify/fsnotify"
+	"github.com/nathanlytang/rolodex/internal/logger"
+)
+
+// How often config.json is checked for changes when the OS can't report them
+const configPollInterval = 2 * time.Second
+
+// How long a change that arrived while the list was busy waits before trying to reload again
+const configRetryInterval = time.Second
+
+// Modification time left by rolodex's own last write to config.json
+// Changes with this time are ours and don't need reloading
+var ownConfigWrite struct {
+	sync.Mutex
+	modTime time.Time
+}
+
+// Remembers the modification time of a config file rolodex just wrote
+func recordConfigWrite(configPath string) {
+	info, err := os.Stat(configPath)
+	if err != nil {
+		return
+	}
+	ownConfigWrite.Lock()
+	ownConfigWrite.modTime = info.ModTime()
+	ownConfigWrite.Unlock()
+}
+
+// Reports whether a modification time is from rolodex's own last write
+func isOwnConfigWrite(modTime time.Time) bool {
+	ownConfigWrite.Lock()
+	defer ownConfigWrite.Unlock()
+	return modTime.Equal(ownConfigWrite.modTime)
+}
+
+// Returns the config file's modification time, zero if it can't be read
+func configModTime(configPath string) time.Time {
+	info, err := os.Stat(configPath)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+// Reports changes to config.json, rolodex's own writes included
+type configWatcher struct {
+	changes chan struct{} // Receives after config.json may have changed, holding at most one change
+	stop    func()
+}
+
+// Starts watching config.json for changes with the OS's file notifications,
+// falling back to polling its modification time if they can't be set up
+func watchConfig(configPath string) *configWatcher {
+	w, err := notifyConfigChanges(configPath)
+	if err != nil {
+		logger.Printf("Polling config.json for changes: %v", err)
+		return pollConfigChanges(configPath)
+	}
+	return w
+}
+
+// Watches the directory holding config.json with fsnotify
+// Watching the directory rather than the file also catches editors that save by writing
+// a new file and renaming it over config.json, which would leave a file watch on the old one
+func notifyConfigChanges(configPath string) (*configWatcher, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	if err := watcher.Add(filepath.Dir(configPath)); err != nil {
+		watcher.Close()
+		return nil, err
+	}
+
+	w := &configWatcher{changes: make(chan struct{}, 1), stop: sync.OnceFunc(func() { watcher.Close() })}
+	name := filepath.Clean(configPath)
+	go func() {
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				// Written in place, or created by renaming over it
+				if filepath.Clean(event.Name) == name && event.Has(fsnotify.Write|fsnotify.Create) {
+					w.notify()
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				// An overflowed queue may have dropped config.json's event
+				if errors.Is(err, fsnotify.ErrEventOverflow) {
+					w.notify()
+					continue
+				}
+				logger.Errorf("Error watching config.json: %v", err)
+			}
+		}
+	}()
+	return w, nil
+}
+
+// Stops watching
+func (w *configWatcher) Close() {
+	w.stop()
+}
+
+// Records a change, merging it with one not yet received
+func (w *configWatcher) notify() {
+	select {
+	case w.changes <- struct{}{}:
+	default:
+	}
+}
+
+// Checks config.json's modification time every configPollInterval
+func pollConfigChanges(configPath string) *configWatcher {
+	done := make(chan struct{})
+	w := &configWatcher{changes: make(chan struct{}, 1), stop: sync.OnceFunc(func() { close(done) })}
+	go func() {
+		ticker := time.NewTicker(configPollInterval)
+		defer ticker.Stop()
+		last := configModTime(configPath)
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if modTime := configModTime(configPath); !modTime.IsZero() && !modTime.Equal(last) {
+					last = modTime
+					w.notify()
+				}
+			}
+		}
+	}()
+	return w
+}
+
+// Sent when config.json may have changed on disk
+type configChangedMsg struct {
+	retry bool // Sent again for a change that arrived while the list was busy
+}
+
+// Returns a command that waits for config.json to change, or for done to close
+func waitForConfigChange(w *configWatcher, done <-chan struct{}) tea.Cmd {
+	if w == nil {
+		return nil
+	}
+	return func() tea.Msg {
+		select {
+		case <-w.changes:
+			return configChangedMsg{}
+		case <-done:
+			return nil
+		}
+	}
+}
+
+// Tries a postponed reload again after configRetryInterval
+func retryConfigReload() tea.Cmd {
+	return tea.Tick(configRetryInterval, func(time.Time) tea.Msg {
+		return configChangedMsg{retry: true}
+	})
+}
+
+// Reloads the host list and settings when config.json was changed by something other than rolodex
+// Waits until the list is showing and not being filtered, so nothing being edited is lost
+func (m Model) configChanged(msg configChangedMsg) (tea.Model, tea.Cmd) {
+	var next tea.Cmd
+	if !msg.retry {
+		next = waitForConfigChange(m.configWatcher, m.done)
+	} else if !m.reloadPending {
+		// Already reloaded by a later change
+		return m, nil
+	}
+
+	modTime := configModTime(m.configPath)
+	if modTime.IsZero() || modTime.Equal(m.configModTime) {
+		m.reloadPending = false
+		return m, next
+	}
+	if isOwnConfigWrite(modTime) {
+		// Rolodex rereads config.json before writing it, so its writes include any pending change
+		m.configModTime = modTime
+		m.reloadPending = false
+		return m, next
+	}
+	if m.view != listView || m.list.FilterState() == list.Filtering {
+		if m.reloadPending && !msg.retry {
+			return m, next
+		}
+		m.reloadPending = true
+		return m, tea.Batch(next, retryConfigReload())
+	}
+	m.reloadPending = false
+
+	config, err := readConfig(m.configPath)
+	if err != nil {
+		// Often a save still in progress, the next change will be picked up
+		logger.Printf("Not reloading changed config: %v", err)
+		return m, tea.Batch(next, m.list.NewStatusMessage(fmt.Sprintf("config.json not reloaded: %v", err)))
+	}
+	m.configModTime = modTime
+
+	state := m.listState()
+	m.hosts = config.Hosts
+	m.folders = config.Folders
+	m.marked = nil
+	m, cmd := m.applySettings(config.Settings)
+	m.restoreListState(state)
+	logger.Printf("Reloaded %s after it changed on disk", m.configPath)
+	return m, tea.Batch(next, cmd, m.list.NewStatusMessage("Config reloaded"))
+}
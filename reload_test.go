@@ -0,0 +1,183 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatchConfigSeesSaves(t *testing.T) {
+	watchers := []struct {
+		name  string
+		watch func(configPath string) *configWatcher
+	}{
+		{"notify", watchConfig},
+		// Used where the OS can't report changes
+		{"poll", pollConfigChanges},
+	}
+	for _, watcher := range watchers {
+		t.Run(watcher.name, func(t *testing.T) {
+			dir := t.TempDir()
+			configPath := filepath.Join(dir, "config.json")
+			if err := os.WriteFile(configPath, []byte(`{"hosts": []}`), 0600); err != nil {
+				t.Fatal(err)
+			}
+			w := watcher.watch(configPath)
+			defer w.Close()
+
+			saves := []struct {
+				name string
+				save func() error
+			}{
+				{"written in place", func() error {
+					return os.WriteFile(configPath, []byte(`{"hosts": [{"name": "web"}]}`), 0600)
+				}},
+				{"renamed over", func() error {
+					// How editors like vim save by default
+					tmp := filepath.Join(dir, ".config.json.swp")
+					if err := os.WriteFile(tmp, []byte(`{"hosts": [{"name": "db"}]}`), 0600); err != nil {
+						return err
+					}
+					return os.Rename(tmp, configPath)
+				}},
+				{"by rolodex", func() error {
+					return writeConfig(configPath, Configuration{Hosts: []Host{{Name: "cache"}}})
+				}},
+			}
+			for _, s := range saves {
+				// Keep modification times apart so polling sees each save
+				time.Sleep(10 * time.Millisecond)
+				if err := s.save(); err != nil {
+					t.Fatal(err)
+				}
+				select {
+				case <-w.changes:
+				case <-time.After(3 * configPollInterval):
+					t.Fatalf("%s: no change reported within %v", s.name, 3*configPollInterval)
+				}
+			}
+
+			// Settle first, since one save can be reported more than once
+			time.Sleep(50 * time.Millisecond)
+			select {
+			case <-w.changes:
+			default:
+			}
+			if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("hi"), 0600); err != nil {
+				t.Fatal(err)
+			}
+			select {
+			case <-w.changes:
+				t.Error("writing another file in the directory was reported as a change")
+			case <-time.After(200 * time.Millisecond):
+			}
+		})
+	}
+}
+
+func TestWaitForConfigChangeStopsWithProgram(t *testing.T) {
+	w := &configWatcher{changes: make(chan struct{}, 1)}
+	done := make(chan struct{})
+	close(done)
+	if msg := waitForConfigChange(w, done)(); msg != nil {
+		t.Errorf("waitForConfigChange returned %v after the program exited, want nil", msg)
+	}
+}
+
+// Writes config.json as another program would, moving its modification time past the last one
+func editConfigExternally(t *testing.T, configPath string, config Configuration) {
+	t.Helper()
+	data, err := json.Marshal(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	modTime := configModTime(configPath).Add(time.Second)
+	if err := os.WriteFile(configPath, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(configPath, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Returns the names of the model's top-level hosts
+func modelHostNames(m Model) []string {
+	var names []string
+	for _, h := range m.hosts {
+		names = append(names, h.Name)
+	}
+	return names
+}
+
+func TestConfigChanged(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	original := Configuration{Hosts: []Host{{Name: "web", Host: "web", Port: 22}}}
+	if err := writeConfig(configPath, original); err != nil {
+		t.Fatal(err)
+	}
+	edited := Configuration{Hosts: []Host{{Name: "web", Host: "web", Port: 22}, {Name: "db", Host: "db", Port: 22}}}
+
+	update := func(m Model, msg configChangedMsg) Model {
+		t.Helper()
+		model, _ := m.configChanged(msg)
+		return model.(Model)
+	}
+
+	t.Run("external edit reloads", func(t *testing.T) {
+		m := initialModel(&original, configPath)
+		editConfigExternally(t, configPath, edited)
+		m = update(m, configChangedMsg{})
+		if got := modelHostNames(m); len(got) != 2 {
+			t.Errorf("hosts = %q after an external edit, want web and db", got)
+		}
+		if len(m.list.Items()) != 2 {
+			t.Errorf("list shows %d hosts, want 2", len(m.list.Items()))
+		}
+	})
+
+	t.Run("own write ignored", func(t *testing.T) {
+		m := initialModel(&original, configPath)
+		time.Sleep(10 * time.Millisecond)
+		if err := writeConfig(configPath, edited); err != nil {
+			t.Fatal(err)
+		}
+		m = update(m, configChangedMsg{})
+		if got := modelHostNames(m); len(got) != 1 {
+			t.Errorf("hosts = %q after rolodex's own write, want the model left alone", got)
+		}
+		if !m.configModTime.Equal(configModTime(configPath)) {
+			t.Error("own write's modification time not remembered")
+		}
+	})
+
+	t.Run("postponed while busy", func(t *testing.T) {
+		if err := writeConfig(configPath, original); err != nil {
+			t.Fatal(err)
+		}
+		m := initialModel(&original, configPath)
+		m.view = formView
+		editConfigExternally(t, configPath, edited)
+
+		m = update(m, configChangedMsg{})
+		if got := modelHostNames(m); len(got) != 1 || !m.reloadPending {
+			t.Fatalf("hosts = %q, pending %v while editing, want the reload postponed", got, m.reloadPending)
+		}
+		// Still busy when the retry comes round
+		m = update(m, configChangedMsg{retry: true})
+		if !m.reloadPending {
+			t.Fatal("reload no longer pending after a retry while still busy")
+		}
+
+		m.view = listView
+		m = update(m, configChangedMsg{retry: true})
+		if got := modelHostNames(m); len(got) != 2 || m.reloadPending {
+			t.Errorf("hosts = %q, pending %v back on the list, want the change reloaded", got, m.reloadPending)
+		}
+		// A retry left over from an earlier postponement does nothing
+		if _, cmd := m.configChanged(configChangedMsg{retry: true}); cmd != nil {
+			t.Error("stale retry scheduled more work")
+		}
+	})
+}
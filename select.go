@@ -0,0 +1,53 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"slices"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Marks or unmarks a host so several can be deleted at once
+// Hosts expanded from a template share its location, so marking one marks them all
+func (m Model) toggleMark(it Item) (tea.Model, tea.Cmd) {
+	if m.marked == nil {
+		m.marked = make(map[hostLocation]bool)
+	}
+	if m.marked[it.loc] {
+		delete(m.marked, it.loc)
+	} else {
+		m.marked[it.loc] = true
+	}
+
+	m.list.Title = m.listTitle()
+	return m, m.list.SetItems(m.buildItems())
+}
+
+// Returns the marked host locations in config order
+func (m Model) markedLocations() []hostLocation {
+	return slices.SortedFunc(maps.Keys(m.marked), func(a, b hostLocation) int {
+		return cmp.Or(cmp.Compare(a.folder, b.folder), cmp.Compare(a.index, b.index))
+	})
+}
+
+// Returns the list title suffix counting marked hosts
+func (m Model) markedText() string {
+	switch n := len(m.marked); n {
+	case 0:
+		return ""
+	case 1:
+		return " · 1 marked"
+	default:
+		return fmt.Sprintf(" · %d marked", n)
+	}
+}
+
+// Returns the prefix that shows a host is marked
+func (i Item) markPrefix() string {
+	if i.marked {
+		return "✓ "
+	}
+	return ""
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/nathanlytang/rolodex/internal/logger"
+)
+
+// Order in which hosts are listed
+type sortMode string
+
+const (
+	sortConfig sortMode = "config" // Order hosts appear in config.json
+	sortName   sortMode = "name"   // Alphabetical by name
+	sortRecent sortMode = "recent" // Most recently connected first
+)
+
+var sortModes = []sortMode{sortConfig, sortName, sortRecent}
+
+// Returns the sort mode that follows s when cycling
+func (s sortMode) next() sortMode {
+	i := slices.Index(sortModes, s)
+	return sortModes[(i+1)%len(sortModes)]
+}
+
+// Returns a human readable description of the sort mode
+func (s sortMode) String() string {
+	switch s {
+	case sortName:
+		return "name"
+	case sortRecent:
+		return "most recently connected"
+	}
+	return "config order"
+}
+
+// Parses a sort mode from config, falling back to config order
+func parseSortMode(s string) sortMode {
+	for _, mode := range sortModes {
+		if string(mode) == s {
+			return mode
+		}
+	}
+	return sortConfig
+}
+
+// Sorts host items in place according to the sort mode
+// Items keep their config location so edits still target the right host
+func (s sortMode) sortItems(items []Item) {
+	switch s {
+	case sortName:
+		slices.SortStableFunc(items, func(a, b Item) int {
+			return cmp.Compare(strings.ToLower(a.host.Name), strings.ToLower(b.host.Name))
+		})
+	case sortRecent:
+		slices.SortStableFunc(items, func(a, b Item) int {
+			return b.host.LastConnected.Compare(a.host.LastConnected)
+		})
+	}
+}
+
+// Returns folder indices in display order for the sort mode
+// Folders are only reordered when sorting by name
+func (s sortMode) folderOrder(folders []Folder) []int {
+	order := make([]int, len(folders))
+	for i := range order {
+		order[i] = i
+	}
+	if s == sortName {
+		slices.SortStableFunc(order, func(a, b int) int {
+			return cmp.Compare(strings.ToLower(folders[a].Name), strings.ToLower(folders[b].Name))
+		})
+	}
+	return order
+}
+
+// Switches to the next sort mode, keeping the current selection and saving the choice
+func (m Model) cycleSortMode() (tea.Model, tea.Cmd) {
+	selected := m.list.SelectedItem()
+
+	m.sortMode = m.sortMode.next()
+	cmds := []tea.Cmd{m.list.SetItems(m.buildItems())}
+
+	switch it := selected.(type) {
+	case Item:
+		selectHost(&m.list, it.loc)
+	case FolderItem:
+		selectFolder(&m.list, it.index)
+	}
+
+	if err := saveSortMode(m.configPath, m.sortMode); err != nil {
+		logger.Errorf("Failed to save sort mode: %v", err)
+	}
+
+	cmds = append(cmds, m.list.NewStatusMessage("Sorted by "+m.sortMode.String()))
+	return m, tea.Batch(cmds...)
+}
+
+// Moves the selected host above (-1) or below (+1) the host next to it in the list,
+// saving the new order to the config file. Hosts hidden by the tag filter are skipped over
+// Only applies in config order, and hosts can't be moved out of their folder
+func (m Model) moveSelectedHost(delta int) (tea.Model, tea.Cmd) {
+	it, ok := m.list.SelectedItem().(Item)
+	if !ok {
+		return m, nil
+	}
+	if m.sortMode != sortConfig {
+		return m, m.list.NewStatusMessage("Hosts can only be moved when sorted by " + sortConfig.String())
+	}
+	if m.recentOnly {
+		return m, m.list.NewStatusMessage("Hosts can't be moved while only recent hosts are shown")
+	}
+
+	// Template hosts expand into several items that share a location
+	items := m.list.VisibleItems()
+	i := m.list.Index()
+	for i >= 0 && i < len(items) {
+		if other, ok := items[i].(Item); !ok || other.loc != it.loc {
+			break
+		}
+		i += delta
+	}
+	var neighbour Item
+	if i >= 0 && i < len(items) {
+		neighbour, _ = items[i].(Item)
+	}
+	if neighbour.host.Name == "" || neighbour.loc.folder != it.loc.folder {
+		if delta < 0 {
+			return m, m.list.NewStatusMessage(it.host.Name + " is already at the top")
+		}
+		return m, m.list.NewStatusMessage(it.host.Name + " is already at the bottom")
+	}
+
+	snapshot := takeSnapshot(m.configPath, "moving "+it.host.Name)
+	if err := reorderHostInConfig(m.configPath, it.loc.folder, it.loc.index, neighbour.loc.index); err != nil {
+		m.err = fmt.Errorf("failed to move host: %w", err)
+		m.showErr = true
+		return m, nil
+	}
+	m.undo = snapshot
+
+	config, err := readConfig(m.configPath)
+	if err != nil {
+		m.err = fmt.Errorf("failed to reload config: %w", err)
+		m.showErr = true
+		return m, nil
+	}
+	// Moving shifts the locations marks refer to
+	m.hosts = config.Hosts
+	m.folders = config.Folders
+	m.marked = nil
+	cmd := m.list.SetItems(m.buildItems())
+	selectHost(&m.list, hostLocation{folder: it.loc.folder, index: neighbour.loc.index})
+	return m, cmd
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Returns every tag used by any host, sorted alphabetically
+func allTags(hosts []Host, folders []Folder) []string {
+	var tags []string
+	add := func(hosts []Host) {
+		for _, h := range hosts {
+			for _, tag := range h.Tags {
+				if !slices.Contains(tags, tag) {
+					tags = append(tags, tag)
+				}
+			}
+		}
+	}
+
+	add(hosts)
+	for _, f := range folders {
+		add(f.Hosts)
+	}
+	slices.Sort(tags)
+	return tags
+}
+
+// Splits a comma-separated list of tags, dropping blanks, duplicates, and leading #
+func parseTags(value string) []string {
+	var tags []string
+	for _, tag := range strings.Split(value, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "#")
+		if tag != "" && !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// Switches the tag filter to the next tag, wrapping back to showing all hosts
+func (m Model) cycleTagFilter() (tea.Model, tea.Cmd) {
+	tags := allTags(m.hosts, m.folders)
+	if len(tags) == 0 {
+		return m, m.list.NewStatusMessage("No hosts are tagged")
+	}
+
+	// Index is -1 when no filter is set, so the first tag comes next
+	next := slices.Index(tags, m.tagFilter) + 1
+	if next >= len(tags) {
+		m.tagFilter = ""
+	} else {
+		m.tagFilter = tags[next]
+	}
+
+	m.list.Title = m.listTitle()
+	cmds := []tea.Cmd{m.list.SetItems(m.buildItems())}
+	if m.tagFilter == "" {
+		cmds = append(cmds, m.list.NewStatusMessage("Showing all hosts"))
+	} else {
+		cmds = append(cmds, m.list.NewStatusMessage("Showing hosts tagged #"+m.tagFilter))
+	}
+	return m, tea.Batch(cmds...)
+}
+
+// Returns the list title, noting the active tag filter
+func (m Model) listTitle() string {
+	title := "Rolodex"
+	if m.tagFilter != "" {
+		title += " #" + m.tagFilter
+	}
+	if m.recentOnly {
+		title += " · recent"
+	}
+	title += m.markedText()
+	switch n := len(m.tunnels.list()); n {
+	case 0:
+	case 1:
+		title += " · 1 tunnel"
+	default:
+		title += fmt.Sprintf(" · %d tunnels", n)
+	}
+	return title
+}
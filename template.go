@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/nathanlytang/rolodex/internal/logger"
+)
+
+// Most hosts a single template may expand into
+const maxTemplateHosts = 1000
+
+// Host patterns that expand into numbered hosts
+// A printf-style %d verb (with optional zero padding, e.g. %02d) expands from 1 to Count,
+// and a shell-style {1..10} range expands over the range with padding kept from the
+// start, so {01..10} gives 01, 02 ... 10
+var (
+	templateVerb  = regexp.MustCompile(`%(0?[0-9]*)d`)
+	templateRange = regexp.MustCompile(`\{([0-9]+)\.\.([0-9]+)\}`)
+)
+
+// Returns the labels a templated host expands to and the pattern they replace
+// Returns nil for ordinary hosts and for invalid templates, which are logged
+func templateLabels(h Host) (labels []string, pattern *regexp.Regexp) {
+	if m := templateRange.FindStringSubmatch(h.Host); m != nil {
+		start, _ := strconv.Atoi(m[1])
+		end, _ := strconv.Atoi(m[2])
+		if end < start || end-start >= maxTemplateHosts {
+			logger.Errorf("Ignoring range %s in host %s: expected {start..end} with at most %d hosts", m[0], h.Name, maxTemplateHosts)
+			return nil, nil
+		}
+		width := 0
+		if len(m[1]) > 1 && m[1][0] == '0' {
+			width = len(m[1])
+		}
+		for i := start; i <= end; i++ {
+			labels = append(labels, fmt.Sprintf("%0*d", width, i))
+		}
+		return labels, templateRange
+	}
+
+	if m := templateVerb.FindStringSubmatch(h.Host); m != nil && h.Count > 0 {
+		if h.Count > maxTemplateHosts {
+			logger.Errorf("Ignoring count %d in host %s: at most %d hosts are allowed", h.Count, h.Name, maxTemplateHosts)
+			return nil, nil
+		}
+		for i := 1; i <= h.Count; i++ {
+			labels = append(labels, fmt.Sprintf("%"+m[1]+"d", i))
+		}
+		return labels, templateVerb
+	}
+	return nil, nil
+}
+
+// Expands a templated host into one host per target, or returns it unchanged
+// The name gets the same substitution if it contains the pattern, otherwise the number is appended
+func expandHost(h Host) []Host {
+	labels, pattern := templateLabels(h)
+	if labels == nil {
+		return []Host{h}
+	}
+
+	hosts := make([]Host, len(labels))
+	for i, label := range labels {
+		e := h
+		e.templateName = h.Name
+		e.Count = 0
+		e.Host = pattern.ReplaceAllLiteralString(h.Host, label)
+		if pattern.MatchString(h.Name) {
+			e.Name = pattern.ReplaceAllLiteralString(h.Name, label)
+		} else {
+			e.Name = strings.TrimSpace(h.Name) + " " + label
+		}
+		hosts[i] = e
+	}
+	return hosts
+}
+
+// Expands every templated host in hosts
+func expandHosts(hosts []Host) []Host {
+	var expanded []Host
+	for _, h := range hosts {
+		expanded = append(expanded, expandHost(h)...)
+	}
+	return expanded
+}
+
+// Returns the name the host is saved under in the config
+// Hosts expanded from a template are saved under the template's name
+func (h Host) configName() string {
+	if h.templateName != "" {
+		return h.templateName
+	}
+	return h.Name
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"slices"
+	"strconv"
+	"testing"
+)
+
+func TestTemplateLabels(t *testing.T) {
+	thousand := make([]string, maxTemplateHosts)
+	for i := range thousand {
+		thousand[i] = strconv.Itoa(i + 1)
+	}
+
+	tests := []struct {
+		name string
+		host Host
+		want []string
+	}{
+		{"ordinary host", Host{Host: "web.example.com"}, nil},
+		{"range", Host{Host: "web{1..3}.example.com"}, []string{"1", "2", "3"}},
+		{"padded range", Host{Host: "web{08..11}"}, []string{"08", "09", "10", "11"}},
+		{"single zero start", Host{Host: "web{0..2}"}, []string{"0", "1", "2"}},
+		{"single value range", Host{Host: "web{5..5}"}, []string{"5"}},
+		{"backwards range", Host{Host: "web{3..1}"}, nil},
+		{"range too large", Host{Host: "web{1..1001}"}, nil},
+		{"largest range", Host{Host: "web{1..1000}"}, thousand},
+		{"verb", Host{Host: "web%d", Count: 3}, []string{"1", "2", "3"}},
+		{"padded verb", Host{Host: "web%02d", Count: 2}, []string{"01", "02"}},
+		{"verb without count", Host{Host: "web%d"}, nil},
+		{"count too large", Host{Host: "web%d", Count: 1001}, nil},
+		{"count without verb", Host{Host: "web", Count: 3}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			labels, pattern := templateLabels(tt.host)
+			if !slices.Equal(labels, tt.want) {
+				t.Errorf("templateLabels(%q) = %q, want %q", tt.host.Host, labels, tt.want)
+			}
+			if (pattern == nil) != (tt.want == nil) {
+				t.Errorf("templateLabels(%q) pattern = %v, want one only with labels", tt.host.Host, pattern)
+			}
+		})
+	}
+}
+
+func TestExpandHost(t *testing.T) {
+	tests := []struct {
+		name      string
+		host      Host
+		wantNames []string
+		wantHosts []string
+	}{
+		{
+			name:      "ordinary host unchanged",
+			host:      Host{Name: "web", Host: "web.example.com"},
+			wantNames: []string{"web"},
+			wantHosts: []string{"web.example.com"},
+		},
+		{
+			name:      "number appended to name",
+			host:      Host{Name: "web ", Host: "web{1..2}.example.com"},
+			wantNames: []string{"web 1", "web 2"},
+			wantHosts: []string{"web1.example.com", "web2.example.com"},
+		},
+		{
+			name:      "name substituted",
+			host:      Host{Name: "web-{01..02}", Host: "10.0.0.{01..02}"},
+			wantNames: []string{"web-01", "web-02"},
+			wantHosts: []string{"10.0.0.01", "10.0.0.02"},
+		},
+		{
+			name:      "verb in name and host",
+			host:      Host{Name: "node%02d", Host: "node%02d.local", Count: 2},
+			wantNames: []string{"node01", "node02"},
+			wantHosts: []string{"node01.local", "node02.local"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hosts := expandHost(tt.host)
+			var names, addrs []string
+			for _, h := range hosts {
+				names = append(names, h.Name)
+				addrs = append(addrs, h.Host)
+				if len(hosts) > 1 {
+					if h.configName() != tt.host.Name {
+						t.Errorf("%s configName() = %q, want %q", h.Name, h.configName(), tt.host.Name)
+					}
+					if h.Count != 0 {
+						t.Errorf("%s Count = %d, want 0", h.Name, h.Count)
+					}
+				}
+			}
+			if !slices.Equal(names, tt.wantNames) {
+				t.Errorf("names = %q, want %q", names, tt.wantNames)
+			}
+			if !slices.Equal(addrs, tt.wantHosts) {
+				t.Errorf("hosts = %q, want %q", addrs, tt.wantHosts)
+			}
+		})
+	}
+}
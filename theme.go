@@ -0,0 +1,170 @@
+package main
+
+import (
+	"maps"
+	"os"
+	"slices"
+
+	"github.com/charmbracelet/bubbles/list"
+	lg "github.com/charmbracelet/lipgloss"
+	"github.com/nathanlytang/rolodex/internal/logger"
+)
+
+// Named colors used by every view
+// In config.json, name picks a built-in theme and any colors set override it
+type Theme struct {
+	Name            string   `json:"name,omitempty"`
+	TitleText       lg.Color `json:"title_text,omitempty"`       // Text of view titles
+	TitleBackground lg.Color `json:"title_background,omitempty"` // Background of view titles
+	Text            lg.Color `json:"text,omitempty"`             // Labels and values
+	Muted           lg.Color `json:"muted,omitempty"`            // Hints, help and secondary details
+	Accent          lg.Color `json:"accent,omitempty"`           // Input prompts and folders
+	Highlight       lg.Color `json:"highlight,omitempty"`        // Selected items and host names
+	Header          lg.Color `json:"header,omitempty"`           // Section headers
+	Success         lg.Color `json:"success,omitempty"`          // Reachable hosts and passed checks
+	Error           lg.Color `json:"error,omitempty"`            // Validation errors and warnings
+	Warning         lg.Color `json:"warning,omitempty"`          // Error view heading
+	Alert           lg.Color `json:"alert,omitempty"`            // Error view message
+	Border          lg.Color `json:"border,omitempty"`           // Inactive pane borders
+	ActiveBorder    lg.Color `json:"active_border,omitempty"`    // Focused pane borders
+}
+
+// Theme used when none is configured and the terminal background is unknown
+const defaultThemeName = "dark"
+
+// Theme used when none is configured, set from the terminal background by detectTheme
+var autoThemeName = defaultThemeName
+
+// Set when NO_COLOR is, see https://no-color.org
+// lipgloss already drops colors and text attributes, so views that rely on
+// them to show focus or status add plain text markers instead
+var noColor = os.Getenv("NO_COLOR") != ""
+
+// Built-in themes selectable by name
+var themes = map[string]Theme{
+	"dark": {
+		TitleText:       "#DDDDDD",
+		TitleBackground: "62",
+		Text:            "#DDDDDD",
+		Muted:           "#888888",
+		Accent:          "#7D56F4",
+		Highlight:       "#EE6FF8",
+		Header:          "#00FFFF",
+		Success:         "#04B575",
+		Error:           "#ED5679",
+		Warning:         "#FFFF00",
+		Alert:           "#EE0000",
+		Border:          "#444444",
+		ActiveBorder:    "62",
+	},
+	"light": {
+		TitleText:       "#FFFFFF",
+		TitleBackground: "#5A3FD6",
+		Text:            "#1A1A1A",
+		Muted:           "#6B6B6B",
+		Accent:          "#5A3FD6",
+		Highlight:       "#A8179A",
+		Header:          "#006D8F",
+		Success:         "#027A48",
+		Error:           "#C4283C",
+		Warning:         "#8A6100",
+		Alert:           "#B00000",
+		Border:          "#BBBBBB",
+		ActiveBorder:    "#5A3FD6",
+	},
+	"high-contrast": {
+		TitleText:       "#000000",
+		TitleBackground: "#FFFF00",
+		Text:            "#FFFFFF",
+		Muted:           "#D0D0D0",
+		Accent:          "#00FFFF",
+		Highlight:       "#FFFF00",
+		Header:          "#00FFFF",
+		Success:         "#00FF00",
+		Error:           "#FF5555",
+		Warning:         "#FFFF00",
+		Alert:           "#FF0000",
+		Border:          "#FFFFFF",
+		ActiveBorder:    "#FFFF00",
+	},
+}
+
+// Colors of the loaded theme
+var theme = themes[defaultThemeName]
+
+// Picks the light theme for terminals with a light background
+// This queries the terminal, so it must run before Bubble Tea takes over stdin
+func detectTheme() {
+	if noColor {
+		return
+	}
+	if !lg.HasDarkBackground() {
+		autoThemeName = "light"
+	}
+	logger.Debugf("Detected terminal background, defaulting to the %s theme", autoThemeName)
+}
+
+// Returns the built-in theme names, the default first
+func themeNames() []string {
+	names := []string{autoThemeName}
+	for _, name := range slices.Sorted(maps.Keys(themes)) {
+		if name != autoThemeName {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Resolves a configured theme to the named built-in with any colors it sets applied on top
+// Unknown or missing names fall back to the default theme
+func loadTheme(config *Theme) Theme {
+	if config == nil {
+		return themes[autoThemeName]
+	}
+	t, ok := themes[config.Name]
+	if !ok {
+		if config.Name != "" {
+			logger.Errorf("Unknown theme %q, using %s", config.Name, autoThemeName)
+		}
+		t = themes[autoThemeName]
+	}
+	t.Name = config.Name
+
+	for _, c := range []struct {
+		dst *lg.Color
+		src lg.Color
+	}{
+		{&t.TitleText, config.TitleText},
+		{&t.TitleBackground, config.TitleBackground},
+		{&t.Text, config.Text},
+		{&t.Muted, config.Muted},
+		{&t.Accent, config.Accent},
+		{&t.Highlight, config.Highlight},
+		{&t.Header, config.Header},
+		{&t.Success, config.Success},
+		{&t.Error, config.Error},
+		{&t.Warning, config.Warning},
+		{&t.Alert, config.Alert},
+		{&t.Border, config.Border},
+		{&t.ActiveBorder, config.ActiveBorder},
+	} {
+		if c.src != "" {
+			*c.dst = c.src
+		}
+	}
+	return t
+}
+
+// Returns a marker for the focused input when focus can't be shown with color
+func focusMarker(focused bool) string {
+	if noColor && focused {
+		return "» "
+	}
+	return ""
+}
+
+// Applies the theme to the host list's title and items
+func applyListTheme(l *list.Model) {
+	l.Styles.Title = l.Styles.Title.Foreground(theme.TitleText).Background(theme.TitleBackground)
+	l.SetDelegate(newHostDelegate())
+}
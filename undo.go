@@ -0,0 +1,70 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Hosts and folders as they were before the last add, edit, or delete, for a single level of undo
+type configSnapshot struct {
+	hosts   []Host
+	folders []Folder
+	change  string // Shown when the snapshot is restored, e.g. "delete web"
+}
+
+// Returns the current hosts and folders before a change, nil if the config can't be read
+// A missing config is an empty snapshot, so undoing the first host removes it again
+func takeSnapshot(configPath, change string) *configSnapshot {
+	config, err := readConfig(configPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return &configSnapshot{hosts: config.Hosts, folders: config.Folders, change: change}
+}
+
+// Writes the snapshot's hosts and folders back, keeping settings changed since
+func restoreSnapshot(configPath string, s *configSnapshot) error {
+	unlock, err := lockConfig(configPath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	config, err := readConfig(configPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	config.Hosts = s.hosts
+	config.Folders = s.folders
+
+	return writeConfig(configPath, config)
+}
+
+// Restores the hosts and folders from before the last change
+func (m Model) undoLastChange() (tea.Model, tea.Cmd) {
+	if m.undo == nil {
+		return m, m.list.NewStatusMessage("Nothing to undo")
+	}
+	if err := restoreSnapshot(m.configPath, m.undo); err != nil {
+		m.err = fmt.Errorf("failed to undo: %w", err)
+		m.showErr = true
+		return m, nil
+	}
+
+	config, err := readConfig(m.configPath)
+	if err != nil {
+		m.err = fmt.Errorf("failed to reload config: %w", err)
+		m.showErr = true
+		return m, nil
+	}
+	change := m.undo.change
+	m.undo = nil
+	m.hosts = config.Hosts
+	m.folders = config.Folders
+	m.marked = nil
+	m.list = m.buildList()
+	return m, m.list.NewStatusMessage("Restored config from before " + change)
+}